@@ -1,6 +1,7 @@
 package surfnerd
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -12,17 +13,27 @@ import (
 	"time"
 )
 
+// Paths are relative to the NDBC endpoint of the buoys client
 const (
-	baseDataURL          = "http://www.ndbc.noaa.gov/data/realtime2/%s%s"
-	baseSpectraPlotURL   = "http://www.ndbc.noaa.gov/spec_plot.php?station=%s"
-	baseLatestReadingURL = "http://www.ndbc.noaa.gov/data/latest_obs/%s.txt"
-	baseAlphaSpectraURL  = "http://www.ndbc.noaa.gov/data/realtime2/%s.swdir"
-	baseEnergyURL        = "http://www.ndbc.noaa.gov/data/realtime2/%s.data_spec"
-	// Old URL for latest was "http://www.ndbc.noaa.gov/get_observation_as_xml.php?station=%s"
-	standardDataPostfix     = ".txt"
-	detailedWaveDataPostfix = ".spec"
-	latestDateLayout        = "1504 MST 01/02/06"
-	standardDateLayout      = "1504 MST 01/02/2006"
+	baseDataURL          = "/data/realtime2/%s%s"
+	baseSpectraPlotURL   = "/spec_plot.php?station=%s"
+	baseLatestReadingURL = "/data/latest_obs/%s.txt"
+	baseAlphaSpectraURL  = "/data/realtime2/%s.swdir"
+	baseEnergyURL        = "/data/realtime2/%s.data_spec"
+	baseAlpha2SpectraURL = "/data/realtime2/%s.swdir2"
+	baseR1SpectraURL     = "/data/realtime2/%s.swr1"
+	baseR2SpectraURL     = "/data/realtime2/%s.swr2"
+	// Old URL for latest was "/get_observation_as_xml.php?station=%s"
+	standardDataPostfix        = ".txt"
+	detailedWaveDataPostfix    = ".spec"
+	oceanDataPostfix           = ".ocean"
+	continuousWindPostfix      = ".cwind"
+	supplementalDataPostfix    = ".supl"
+	currentDataPostfix         = ".adcp"
+	detailedCurrentDataPostfix = ".adcp2"
+	dartDataPostfix            = ".dart"
+	latestDateLayout           = "1504 MST 01/02/06"
+	standardDateLayout         = "1504 MST 01/02/2006"
 )
 
 // Holds the latest report grabbed from the NOAA data portal for the given station ID. Typically not
@@ -40,16 +51,40 @@ type Buoy struct {
 	WaterQuality string   `xml:"waterquality,attr"`
 	Dart         string   `xml:"dart,attr"`
 	BuoyData     []BuoyDataItem
+
+	// Set from the NDBC realtime file listing when the station list is fetched, as the station list
+	// itself does not say which stations measure waves
+	SpectralData bool `xml:"-"`
+
+	// Filled by the fetchers of the products that are reported separately from the BuoyData
+	OceanData          []OceanDataItem        `json:",omitempty"`
+	ContinuousWindData []ContinuousWindItem   `json:",omitempty"`
+	SupplementalData   []SupplementalDataItem `json:",omitempty"`
+	CurrentProfiles    []CurrentProfile       `json:",omitempty"`
+	DartData           []DartDataItem         `json:",omitempty"`
+
+	// The client used to fetch this buoys data. DefaultClient is used when nil.
+	Client *Client `xml:"-" json:"-"`
 }
 
 // Finds a buoy for a given identification string
 func GetBuoyByID(stationID string) *Buoy {
-	buoy := BuoyStations{}
-	fetchError := buoy.GetAllActiveBuoyStations()
-	if fetchError != nil {
-		return nil
+	buoy, _ := GetBuoyByIDContext(context.Background(), stationID)
+	return buoy
+}
+
+// Finds a buoy for a given identification string using the DefaultClient. The context
+// controls cancellation of the station list download.
+func GetBuoyByIDContext(ctx context.Context, stationID string) (*Buoy, error) {
+	return DefaultClient.GetBuoyByID(ctx, stationID)
+}
+
+// Returns the client this buoy fetches its data with
+func (b Buoy) client() *Client {
+	if b.Client == nil {
+		return DefaultClient
 	}
-	return buoy.FindBuoyByID(stationID)
+	return b.Client
 }
 
 // Returns if the buoy is active. This is functionally a check if the buoy
@@ -93,39 +128,64 @@ func (b Buoy) DoesBuoyHaveDartData() bool {
 	return true
 }
 
+// Returns if the buoy reports wave spectra, which is when NDBC publishes a realtime spectral
+// energy file for it. Only known for buoys from a fetched station list, see SpectralData.
+func (b Buoy) DoesBuoyHaveSpectralData() bool {
+	return b.SpectralData
+}
+
 // Creates and returns the url of the latest buoy buoy reading xml
 func (b Buoy) CreateLatestReadingURL() string {
-	return fmt.Sprintf(baseLatestReadingURL, b.StationID)
+	return b.client().endpoints().ndbcURL(baseLatestReadingURL, b.StationID)
 }
 
 // Creates and returns the url for fetching the buoys standard meterology report.
 // The url returns tab delimited ascii data.
 func (b Buoy) CreateStandardDataURL() string {
-	return fmt.Sprintf(baseDataURL, b.StationID, standardDataPostfix)
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, standardDataPostfix)
 }
 
 // Creates and returns the url for fetching the buoys detailed wave data.
 // The url returns tab delimited ascii data.
 func (b Buoy) CreateDetailedWaveDataURL() string {
-	return fmt.Sprintf(baseDataURL, b.StationID, detailedWaveDataPostfix)
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, detailedWaveDataPostfix)
 }
 
 // Creates and returns the url for fetching the raw directional wave spectra. This is the
 // primary wave direction component and is usually used with the raw energy wave spectra
 func (b Buoy) CreateDirectionalSpectraDataURL() string {
-	return fmt.Sprintf(baseAlphaSpectraURL, b.StationID)
+	return b.client().endpoints().ndbcURL(baseAlphaSpectraURL, b.StationID)
 }
 
 // Creates and returns the url for fetching the raw wave energy spectra. This is the
 // primary wave energy component and is usually used with the raw directional wave spectra
 func (b Buoy) CreateEnergySpectraDataURL() string {
-	return fmt.Sprintf(baseEnergyURL, b.StationID)
+	return b.client().endpoints().ndbcURL(baseEnergyURL, b.StationID)
+}
+
+// Creates and returns the url for fetching the raw secondary directional wave spectra (alpha2).
+// Used with the r1 and r2 coefficients to describe how the wave energy spreads around the
+// primary direction at each frequency
+func (b Buoy) CreateSecondaryDirectionalSpectraDataURL() string {
+	return b.client().endpoints().ndbcURL(baseAlpha2SpectraURL, b.StationID)
+}
+
+// Creates and returns the url for fetching the first normalized polar coordinate (r1) of the
+// Fourier coefficients for each frequency of the wave spectra
+func (b Buoy) CreateR1SpectraDataURL() string {
+	return b.client().endpoints().ndbcURL(baseR1SpectraURL, b.StationID)
+}
+
+// Creates and returns the url for fetching the second normalized polar coordinate (r2) of the
+// Fourier coefficients for each frequency of the wave spectra
+func (b Buoy) CreateR2SpectraDataURL() string {
+	return b.client().endpoints().ndbcURL(baseR2SpectraURL, b.StationID)
 }
 
 // Creates and returns the url of the Buoys latest Spectral Density plot.
 // The url returns a jpeg image.
 func (b Buoy) CreateSpectraPlotURL() string {
-	return fmt.Sprintf(baseSpectraPlotURL, b.StationID)
+	return b.client().endpoints().ndbcURL(baseSpectraPlotURL, b.StationID)
 }
 
 func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
@@ -137,17 +197,15 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 	// Clear out old data if its hanging around
 	b.BuoyData = make([]BuoyDataItem, 1, 1)
 
-	// Make a new buoy data item
-	buoyDataItem := BuoyDataItem{}
+	// Make a new buoy data item, anything not in the report stays missing
+	buoyDataItem := NewBuoyDataItem(English)
 
 	// Get the date
 	rawTime := rawBuoyLineData[4]
 	buoyDataItem.Date, _ = time.Parse(latestDateLayout, rawTime)
 
-	buoyDataItem.Units = English
-	buoyDataItem.WaveSummary.ChangeUnits(English)
-	windWaveComponent := Swell{Units: English}
-	swellWaveComponent := Swell{Units: English}
+	windWaveComponent := NewMissingSwell(English)
+	swellWaveComponent := NewMissingSwell(English)
 	swellPeriodRead := false
 	swellDirectionRead := false
 	for i := 5; i < len(rawBuoyLineData); i++ {
@@ -162,41 +220,45 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 		switch variable {
 		case "Wind":
 			windComponents := strings.Split(comps[1], ",")
-			buoyDataItem.WindDirection = DirectionToDegree(strings.Split(strings.TrimSpace(windComponents[0]), " ")[0])
-			buoyDataItem.WindSpeed, _ = strconv.ParseFloat(strings.Split(strings.TrimSpace(windComponents[1]), " ")[0], 64)
-			buoyDataItem.WindSpeed = KnotsToMilesPerHour(buoyDataItem.WindSpeed)
+			buoyDataItem.WindDirection = compassDirectionToDegree(strings.Split(strings.TrimSpace(windComponents[0]), " ")[0])
+			if len(windComponents) > 1 {
+				buoyDataItem.WindSpeed = parseNDBCValue(strings.Split(strings.TrimSpace(windComponents[1]), " ")[0], noSentinel)
+				buoyDataItem.WindSpeed = KnotsToMilesPerHour(buoyDataItem.WindSpeed)
+			}
 		case "Gust":
-			buoyDataItem.WindGust, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WindGust = parseNDBCValue(rawValue, noSentinel)
 			buoyDataItem.WindGust = KnotsToMilesPerHour(buoyDataItem.WindGust)
 		case "Seas":
-			buoyDataItem.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaveSummary.WaveHeight = parseNDBCValue(rawValue, noSentinel)
 		case "Peak Period":
-			buoyDataItem.WaveSummary.Period, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaveSummary.Period = parseNDBCValue(rawValue, noSentinel)
 		case "Pres":
-			buoyDataItem.Pressure, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.Pressure = parseNDBCValue(rawValue, noSentinel)
 		case "Air Temp":
-			buoyDataItem.AirTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.AirTemperature = parseNDBCValue(rawValue, noSentinel)
 		case "Water Temp":
-			buoyDataItem.WaterTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaterTemperature = parseNDBCValue(rawValue, noSentinel)
 		case "Dew Point":
-			buoyDataItem.DewpointTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.DewpointTemperature = parseNDBCValue(rawValue, noSentinel)
 		case "Swell":
-			swellWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			swellWaveComponent.WaveHeight = parseNDBCValue(rawValue, noSentinel)
 		case "Wind Wave":
-			windWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			windWaveComponent.WaveHeight = parseNDBCValue(rawValue, noSentinel)
 		case "Period":
 			if !swellPeriodRead {
-				swellWaveComponent.Period, _ = strconv.ParseFloat(rawValue, 64)
+				swellWaveComponent.Period = parseNDBCValue(rawValue, noSentinel)
 				swellPeriodRead = true
 			} else {
-				windWaveComponent.Period, _ = strconv.ParseFloat(rawValue, 64)
+				windWaveComponent.Period = parseNDBCValue(rawValue, noSentinel)
 			}
 		case "Direction":
 			if !swellDirectionRead {
 				swellWaveComponent.CompassDirection = rawValue
+				swellWaveComponent.Direction = compassDirectionToDegree(rawValue)
 				swellDirectionRead = true
 			} else {
 				windWaveComponent.CompassDirection = rawValue
+				windWaveComponent.Direction = compassDirectionToDegree(rawValue)
 			}
 		default:
 			// Do Nothing
@@ -211,100 +273,120 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 	return nil
 }
 
-func (b *Buoy) ParseRawStandardData(rawData []string, dataCountLimit int) error {
-	const dataLineLength = 19
-	const headerLines = 2
-	dataLineCount := (len(rawData) / dataLineLength) - headerLines
+// Parses the contents of a NDBC standard meteorological data (.txt) file into a time series of
+// BuoyDataItem objects. Columns are matched by the names in the header line, and any column the
+// station does not report is left missing. Input a negative integer to parse all available data points.
+// A malformed line is reported as a *ParseError and leaves the BuoyData untouched.
+func (b *Buoy) ParseRawStandardData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
 	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
 		dataLineCount = dataCountLimit
 	}
 
-	b.BuoyData = make([]BuoyDataItem, dataLineCount)
+	buoyData := make([]BuoyDataItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
 
-	itemIndex := 0
-	for line := headerLines; line < dataLineCount+headerLines; line++ {
-		lineBeginIndex := line * dataLineLength
-		if lineBeginIndex > len(rawData) {
-			break
+		// Units are metric by default
+		newBuoyData := NewBuoyDataItem(Metric)
+		newBuoyData.Date = values.date()
+		newBuoyData.WindDirection = values.float("WDIR", directionSentinel)
+		newBuoyData.WindSpeed = values.float("WSPD", speedSentinel)
+		newBuoyData.WindGust = values.float("GST", speedSentinel)
+		newBuoyData.WaveSummary.WaveHeight = values.float("WVHT", heightSentinel)
+		newBuoyData.WaveSummary.Period = values.float("DPD", periodSentinel)
+		newBuoyData.AveragePeriod = values.float("APD", periodSentinel)
+		newBuoyData.WaveSummary.Direction = values.float("MWD", directionSentinel)
+		if !IsMissing(newBuoyData.WaveSummary.Direction) {
+			newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
+		}
+		newBuoyData.Pressure = values.float("PRES", pressureSentinel)
+		newBuoyData.AirTemperature = values.float("ATMP", tempSentinel)
+		newBuoyData.WaterTemperature = values.float("WTMP", tempSentinel)
+		newBuoyData.DewpointTemperature = values.float("DEWP", tempSentinel)
+		newBuoyData.Visibility = values.float("VIS", visSentinel)
+		newBuoyData.PressureTendency = values.float("PTDY", speedSentinel)
+		newBuoyData.WaterLevel = values.float("TIDE", tideSentinel)
+		if table.unit("TIDE") != "m" {
+			newBuoyData.WaterLevel = FeetToMeters(newBuoyData.WaterLevel)
 		}
-		newBuoyData := BuoyDataItem{}
 
-		// Units are metric by default
-		newBuoyData.Units = Metric
-		newBuoyData.Units = Metric
-		newBuoyData.WaveSummary.Units = Metric
-
-		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", rawData[lineBeginIndex+3], rawData[lineBeginIndex+4], rawData[lineBeginIndex+1], rawData[lineBeginIndex+2], rawData[lineBeginIndex+0])
-		newBuoyData.Date, _ = time.Parse(standardDateLayout, rawDate)
-		newBuoyData.WindDirection, _ = strconv.ParseFloat(rawData[lineBeginIndex+5], 64)
-		newBuoyData.WindSpeed, _ = strconv.ParseFloat(rawData[lineBeginIndex+6], 64)
-		newBuoyData.WindGust, _ = strconv.ParseFloat(rawData[lineBeginIndex+7], 64)
-		newBuoyData.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+8], 64)
-		newBuoyData.WaveSummary.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+9], 64)
-		newBuoyData.AveragePeriod, _ = strconv.ParseFloat(rawData[lineBeginIndex+10], 64)
-		newBuoyData.WaveSummary.Direction, _ = strconv.ParseFloat(rawData[lineBeginIndex+11], 64)
-		newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
-		newBuoyData.Pressure, _ = strconv.ParseFloat(rawData[lineBeginIndex+12], 64)
-		newBuoyData.AirTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+13], 64)
-		newBuoyData.WaterTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+14], 64)
-		newBuoyData.DewpointTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+15], 64)
-		newBuoyData.Visibility, _ = strconv.ParseFloat(rawData[lineBeginIndex+16], 64)
-		newBuoyData.PressureTendency, _ = strconv.ParseFloat(rawData[lineBeginIndex+17], 64)
-		newBuoyData.WaterLevel, _ = strconv.ParseFloat(rawData[lineBeginIndex+18], 64)
-		newBuoyData.WaterLevel = FeetToMeters(newBuoyData.WaterLevel)
-
-		b.BuoyData[itemIndex] = newBuoyData
-
-		itemIndex++
+		if values.err != nil {
+			return values.err
+		}
+		buoyData[itemIndex] = newBuoyData
 	}
 
+	b.BuoyData = buoyData
 	return nil
 }
 
-func (b *Buoy) ParseRawDetailedWaveData(rawData []string, dataCountLimit int) error {
-	const dataLineLength = 15
-	const headerLines = 2
-	dataLineCount := (len(rawData) / dataLineLength) - headerLines
+// Parses the contents of a NDBC detailed wave data (.spec) file into a time series of BuoyDataItem
+// objects. Columns are matched by the names in the header line, and any column the station does not
+// report is left missing. Input a negative integer to parse all available data points. A malformed
+// line is reported as a *ParseError and leaves the BuoyData untouched.
+func (b *Buoy) ParseRawDetailedWaveData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
 	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
 		dataLineCount = dataCountLimit
 	}
 
-	b.BuoyData = make([]BuoyDataItem, dataLineCount)
-
-	itemIndex := 0
-	for line := headerLines; line < dataLineCount+headerLines; line++ {
-		lineBeginIndex := line * dataLineLength
-		if lineBeginIndex > len(rawData) {
-			break
+	buoyData := make([]BuoyDataItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
+
+		newBuoyData := NewBuoyDataItem(Metric)
+		windWaveComponent := NewMissingSwell(Metric)
+		swellWaveComponent := NewMissingSwell(Metric)
+		newBuoyData.Date = values.date()
+		newBuoyData.WaveSummary.WaveHeight = values.float("WVHT", heightSentinel)
+		swellWaveComponent.WaveHeight = values.float("SwH", heightSentinel)
+		swellWaveComponent.Period = values.float("SwP", periodSentinel)
+		windWaveComponent.WaveHeight = values.float("WWH", heightSentinel)
+		windWaveComponent.Period = values.float("WWP", periodSentinel)
+		swellWaveComponent.Direction = compassDirectionToDegree(values.text("SwD"))
+		if !IsMissing(swellWaveComponent.Direction) {
+			swellWaveComponent.CompassDirection = values.text("SwD")
+		}
+		windWaveComponent.Direction = compassDirectionToDegree(values.text("WWD"))
+		if !IsMissing(windWaveComponent.Direction) {
+			windWaveComponent.CompassDirection = values.text("WWD")
+		}
+		if steepness := values.text("STEEPNESS"); steepness != "MM" && steepness != "N/A" {
+			newBuoyData.Steepness = steepness
+		}
+		newBuoyData.AveragePeriod = values.float("APD", periodSentinel)
+		newBuoyData.WaveSummary.Direction = values.float("MWD", directionSentinel)
+		if !IsMissing(newBuoyData.WaveSummary.Direction) {
+			newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
 		}
 
-		newBuoyData := BuoyDataItem{}
-		windWaveComponent := Swell{Units: Metric}
-		swellWaveComponent := Swell{Units: Metric}
-		newBuoyData.WaveSummary.Units = Metric
-		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", rawData[lineBeginIndex+3], rawData[lineBeginIndex+4], rawData[lineBeginIndex+1], rawData[lineBeginIndex+2], rawData[lineBeginIndex+0])
-		newBuoyData.Date, _ = time.Parse(standardDateLayout, rawDate)
-		newBuoyData.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+5], 64)
-		swellWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+6], 64)
-		swellWaveComponent.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+7], 64)
-		windWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+8], 64)
-		windWaveComponent.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+9], 64)
-		swellWaveComponent.CompassDirection = rawData[lineBeginIndex+10]
-		windWaveComponent.CompassDirection = rawData[lineBeginIndex+11]
-		newBuoyData.Steepness = rawData[lineBeginIndex+12]
-		newBuoyData.AveragePeriod, _ = strconv.ParseFloat(rawData[lineBeginIndex+13], 64)
-		newBuoyData.WaveSummary.Direction, _ = strconv.ParseFloat(rawData[lineBeginIndex+14], 64)
-		newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
+		if values.err != nil {
+			return values.err
+		}
 
 		newBuoyData.SwellComponents = []Swell{swellWaveComponent, windWaveComponent}
 		newBuoyData.InterpolateDominantPeriod()
 		newBuoyData.InterpolateDominantWaveDirection()
 
-		b.BuoyData[itemIndex] = newBuoyData
-
-		itemIndex++
+		buoyData[itemIndex] = newBuoyData
 	}
 
+	b.BuoyData = buoyData
 	return nil
 }
 
@@ -318,7 +400,7 @@ func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dat
 		return errors.New("Swell direction and energy spectra data does not match, could not parse")
 	} else if len(rawAlphaData) < 2 {
 		return errors.New("Insufficient data passed for spectra parsing")
-	} else if dataCountLimit < 1 {
+	} else if dataCountLimit == 0 {
 		return errors.New("Incompatable data count passed to parser")
 	}
 
@@ -345,7 +427,7 @@ func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dat
 		freqCount := (len(rawAlphaLine) - 5) / 2
 
 		// Create the new item
-		buoyItem := BuoyDataItem{}
+		buoyItem := NewBuoyDataItem(Metric)
 		item := BuoySpectraItem{}
 
 		// Start with the date
@@ -362,10 +444,10 @@ func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dat
 			item.Frequencies[freqIndex], _ = strconv.ParseFloat(rawAlphaLine[j+1], 64)
 
 			// Get the angle
-			item.Angles[freqIndex], _ = strconv.ParseFloat(rawAlphaLine[j], 64)
+			item.Angles[freqIndex] = parseNDBCValue(rawAlphaLine[j], directionSentinel)
 
 			// Get the energy
-			item.Energies[freqIndex], _ = strconv.ParseFloat(rawEnergyLine[j+1], 64)
+			item.Energies[freqIndex] = parseNDBCValue(rawEnergyLine[j+1], densitySentinel)
 
 			// Increment the index
 			freqIndex += 1
@@ -387,12 +469,92 @@ func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dat
 	return nil
 }
 
+// Adds the alpha2, r1 and r2 directional coefficients to the wave spectra already in BuoyData,
+// which must be parsed with ParseRawWaveSpectraData first. Lines are matched to the existing items
+// by their observation time, and items without a matching line are left without coefficients.
+func (b *Buoy) ParseRawDirectionalCoefficientData(rawAlpha2Data, rawR1Data, rawR2Data []string) error {
+	alpha2Values, alpha2Err := parseRawSpectralCoefficients(rawAlpha2Data, directionSentinel)
+	if alpha2Err != nil {
+		return alpha2Err
+	}
+	r1Values, r1Err := parseRawSpectralCoefficients(rawR1Data, directionSentinel)
+	if r1Err != nil {
+		return r1Err
+	}
+	r2Values, r2Err := parseRawSpectralCoefficients(rawR2Data, directionSentinel)
+	if r2Err != nil {
+		return r2Err
+	}
+
+	for i, _ := range b.BuoyData {
+		spectra := &b.BuoyData[i].WaveSpectra
+		date := b.BuoyData[i].Date
+
+		alpha2, hasAlpha2 := alpha2Values[date.Unix()]
+		r1, hasR1 := r1Values[date.Unix()]
+		r2, hasR2 := r2Values[date.Unix()]
+		if !hasAlpha2 || !hasR1 || !hasR2 {
+			continue
+		}
+
+		frequencyCount := len(spectra.Frequencies)
+		if len(alpha2) != frequencyCount || len(r1) != frequencyCount || len(r2) != frequencyCount {
+			return fmt.Errorf("Directional coefficients for %s do not match the %d spectra frequencies", date.Format(time.RFC3339), frequencyCount)
+		}
+
+		spectra.Alpha2 = alpha2
+		spectra.R1 = r1
+		spectra.R2 = r2
+	}
+
+	return nil
+}
+
+// Parses the lines of a realtime spectral coefficient file, where each line is the observation time
+// followed by value (frequency) pairs, into a map of the values keyed by the unix time of each
+// observation. Values at or above the sentinel are missing.
+func parseRawSpectralCoefficients(rawData []string, sentinel float64) (map[int64][]float64, error) {
+	const firstValueIndex = 5
+
+	coefficients := map[int64][]float64{}
+	for lineIndex, rawLine := range rawData {
+		rawLine = strings.Replace(rawLine, "(", " ", -1)
+		rawLine = strings.Replace(rawLine, ")", " ", -1)
+		fields := strings.Fields(rawLine)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		} else if len(fields) < firstValueIndex || (len(fields)-firstValueIndex)%2 != 0 {
+			return nil, &ParseError{Line: lineIndex + 1, Column: "spectra", Value: strings.TrimSpace(rawLine), Err: ErrColumnCountMismatch}
+		}
+
+		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", fields[3], fields[4], fields[1], fields[2], fields[0])
+		date, dateErr := time.Parse(standardDateLayout, rawDate)
+		if dateErr != nil {
+			return nil, &ParseError{Line: lineIndex + 1, Column: "date", Value: rawDate, Err: dateErr}
+		}
+
+		values := make([]float64, 0, (len(fields)-firstValueIndex)/2)
+		for j := firstValueIndex; j < len(fields); j += 2 {
+			values = append(values, parseNDBCValue(fields[j], sentinel))
+		}
+		coefficients[date.Unix()] = values
+	}
+
+	return coefficients, nil
+}
+
 // Fetches the latest buoy reading data from the buoy and fills the
 // BuoyData member with the latest value
 func (b *Buoy) FetchLatestBuoyReading() error {
-	rawData, error := fetchRawDataFromURL(b.CreateLatestReadingURL())
+	return b.FetchLatestBuoyReadingContext(context.Background())
+}
+
+// Fetches the latest buoy reading data from the buoy and fills the BuoyData member
+// with the latest value. The context controls cancellation of the download.
+func (b *Buoy) FetchLatestBuoyReadingContext(ctx context.Context) error {
+	rawData, error := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateLatestReadingURL())
 	if error != nil {
-		return error
+		return stationFetchError(error)
 	}
 
 	if rawData == nil {
@@ -407,41 +569,57 @@ func (b *Buoy) FetchLatestBuoyReading() error {
 // wave heights, periods, water temps, and wind. Input a negative integer or zero to download all
 // available data points.
 func (b *Buoy) FetchStandardData(dataCountLimit int) error {
-	rawData, fetchError := fetchSpaceDelimitedString(b.CreateStandardDataURL())
+	return b.FetchStandardDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchStandardData, but the download is bound to the given context
+func (b *Buoy) FetchStandardDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchError := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateStandardDataURL())
 	if fetchError != nil {
-		return fetchError
+		return stationFetchError(fetchError)
 	} else if rawData == nil {
 		return errors.New("No data received from NOAA Buoy")
 	}
 
-	return b.ParseRawStandardData(rawData, dataCountLimit)
+	return b.ParseRawStandardData(string(rawData), dataCountLimit)
 }
 
 // Grabs the latest spectral wave data as a time series of BuoyDataItem objects. This data contains things
 // like the primary and secondary swell components, and significant wave height. Input a negative integer
 // or zero to download all available data points
 func (b *Buoy) FetchDetailedWaveData(dataCountLimit int) error {
-	rawData, fetchError := fetchSpaceDelimitedString(b.CreateDetailedWaveDataURL())
+	return b.FetchDetailedWaveDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchDetailedWaveData, but the download is bound to the given context
+func (b *Buoy) FetchDetailedWaveDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchError := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateDetailedWaveDataURL())
 	if fetchError != nil {
-		return fetchError
+		return stationFetchError(fetchError)
 	} else if rawData == nil {
 		return errors.New("No data received from NOAA Buoy")
 	}
 
-	return b.ParseRawDetailedWaveData(rawData, dataCountLimit)
+	return b.ParseRawDetailedWaveData(string(rawData), dataCountLimit)
 }
 
+// Grabs the raw directional and energy spectra as a time series of BuoyDataItem objects
 func (b *Buoy) FetchRawWaveSpectraData(dataCountLimit int) error {
-	rawAlphaData, rawAlphaError := fetchLineDelimitedString(b.CreateDirectionalSpectraDataURL())
+	return b.FetchRawWaveSpectraDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchRawWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchRawWaveSpectraDataContext(ctx context.Context, dataCountLimit int) error {
+	rawAlphaData, rawAlphaError := fetchLineDelimitedString(ctx, b.client().fetcher(), b.CreateDirectionalSpectraDataURL())
 	if rawAlphaError != nil {
-		return rawAlphaError
+		return stationFetchError(rawAlphaError)
 	} else if rawAlphaData == nil {
 		return errors.New("No directional data recieved for this buoy")
 	}
 
-	rawEnergyData, rawEnergyError := fetchLineDelimitedString(b.CreateEnergySpectraDataURL())
+	rawEnergyData, rawEnergyError := fetchLineDelimitedString(ctx, b.client().fetcher(), b.CreateEnergySpectraDataURL())
 	if rawEnergyError != nil {
-		return rawEnergyError
+		return stationFetchError(rawEnergyError)
 	} else if rawEnergyData == nil {
 		return errors.New("No energy data recieved for this buoy")
 	}
@@ -449,8 +627,91 @@ func (b *Buoy) FetchRawWaveSpectraData(dataCountLimit int) error {
 	return b.ParseRawWaveSpectraData(rawAlphaData, rawEnergyData, dataCountLimit)
 }
 
+// Grabs the raw energy spectra along with all four directional Fourier coefficients (alpha1, alpha2,
+// r1 and r2) as a time series of BuoyDataItem objects. This is needed to know how spread out the
+// energy is at each frequency, not just its mean direction.
+func (b *Buoy) FetchDirectionalWaveSpectraData(dataCountLimit int) error {
+	return b.FetchDirectionalWaveSpectraDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchDirectionalWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchDirectionalWaveSpectraDataContext(ctx context.Context, dataCountLimit int) error {
+	spectraErr := b.FetchRawWaveSpectraDataContext(ctx, dataCountLimit)
+	if spectraErr != nil {
+		return spectraErr
+	}
+
+	coefficientURLs := []string{
+		b.CreateSecondaryDirectionalSpectraDataURL(),
+		b.CreateR1SpectraDataURL(),
+		b.CreateR2SpectraDataURL(),
+	}
+	rawCoefficientData := make([][]string, len(coefficientURLs))
+	for index, url := range coefficientURLs {
+		rawData, fetchErr := fetchLineDelimitedString(ctx, b.client().fetcher(), url)
+		if fetchErr != nil {
+			return stationFetchError(fetchErr)
+		}
+		rawCoefficientData[index] = rawData
+	}
+
+	return b.ParseRawDirectionalCoefficientData(rawCoefficientData[0], rawCoefficientData[1], rawCoefficientData[2])
+}
+
+// A NDBC data product that can be fetched into the BuoyData of a buoy
+type BuoyDataProduct string
+
+const (
+	LatestReadingProduct          BuoyDataProduct = "latest"
+	StandardDataProduct           BuoyDataProduct = "standard"
+	DetailedWaveDataProduct       BuoyDataProduct = "detailed"
+	WaveSpectraProduct            BuoyDataProduct = "spectra"
+	DirectionalWaveSpectraProduct BuoyDataProduct = "directional"
+)
+
+// Grabs several data products and joins them by observation time into one time series of BuoyDataItem
+// objects, so the meteorology, swell components and spectra for a time are held in one item. Products
+// earlier in the list take precedence when they report the same measurement, and items observed within
+// the tolerance of each other are joined. The data count limit applies to each product separately.
+// See MergeBuoyData for how the series are joined.
+func (b *Buoy) FetchMergedData(products []BuoyDataProduct, tolerance time.Duration, dataCountLimit int) error {
+	return b.FetchMergedDataContext(context.Background(), products, tolerance, dataCountLimit)
+}
+
+// Same as FetchMergedData, but the downloads are bound to the given context
+func (b *Buoy) FetchMergedDataContext(ctx context.Context, products []BuoyDataProduct, tolerance time.Duration, dataCountLimit int) error {
+	series := make([][]BuoyDataItem, len(products))
+	for index, product := range products {
+		productBuoy := Buoy{StationID: b.StationID, Client: b.Client}
+
+		var fetchErr error
+		switch product {
+		case LatestReadingProduct:
+			fetchErr = productBuoy.FetchLatestBuoyReadingContext(ctx)
+		case StandardDataProduct:
+			fetchErr = productBuoy.FetchStandardDataContext(ctx, dataCountLimit)
+		case DetailedWaveDataProduct:
+			fetchErr = productBuoy.FetchDetailedWaveDataContext(ctx, dataCountLimit)
+		case WaveSpectraProduct:
+			fetchErr = productBuoy.FetchRawWaveSpectraDataContext(ctx, dataCountLimit)
+		case DirectionalWaveSpectraProduct:
+			fetchErr = productBuoy.FetchDirectionalWaveSpectraDataContext(ctx, dataCountLimit)
+		default:
+			fetchErr = fmt.Errorf("Unknown buoy data product %q", product)
+		}
+		if fetchErr != nil {
+			return fetchErr
+		}
+
+		series[index] = productBuoy.BuoyData
+	}
+
+	b.BuoyData = MergeBuoyData(tolerance, series...)
+	return nil
+}
+
 // Finds the closest BuoyDataItem to a given time and returns the data at that data point.
-// If it fails, the duration returned is -1.
+// Items where every measurement is missing are skipped. If it fails, the duration returned is -1.
 func (b *Buoy) FindConditionsForDateAndTime(date time.Time) (BuoyDataItem, time.Duration) {
 	if b.BuoyData == nil {
 		return BuoyDataItem{}, -1
@@ -458,18 +719,25 @@ func (b *Buoy) FindConditionsForDateAndTime(date time.Time) (BuoyDataItem, time.
 		return BuoyDataItem{}, -1
 	}
 
-	minBuoy := b.BuoyData[0]
-	minDuration := date.Sub(b.BuoyData[0].Date)
+	minIndex := -1
+	var minDuration time.Duration
+
+	for index := 0; index < len(b.BuoyData); index++ {
+		if !b.BuoyData[index].HasData() {
+			continue
+		}
 
-	for index := 1; index < len(b.BuoyData); index++ {
 		newDuration := date.Sub(b.BuoyData[index].Date)
-		if math.Abs(newDuration.Seconds()) < math.Abs(minDuration.Seconds()) {
-			minBuoy = b.BuoyData[index]
+		if minIndex < 0 || math.Abs(newDuration.Seconds()) < math.Abs(minDuration.Seconds()) {
+			minIndex = index
 			minDuration = newDuration
 		}
 	}
 
-	return minBuoy, minDuration
+	if minIndex < 0 {
+		return BuoyDataItem{}, -1
+	}
+	return b.BuoyData[minIndex], minDuration
 }
 
 // Convert a Buoy object to a json formatted string
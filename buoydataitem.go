@@ -1,41 +1,83 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
+	"sort"
 	"time"
 )
 
 // Holds all of the data that a buoy could report in either the Standard Meteorological Data
 // or the Detailed Wave Data reports. Refer to http://www.ndbc.noaa.gov/data/realtime2/ for
-// detailed descriptions. All
+// detailed descriptions. All measurements the buoy did not report are missing values (NaN), check
+// them with IsMissing. Missing values are left out of the json representation.
 type BuoyDataItem struct {
 	Date time.Time
 
 	// Wind
-	WindDirection float64 `json:",omitempty"`
-	WindSpeed     float64 `json:",omitempty"`
-	WindGust      float64 `json:",omitempty"`
+	WindDirection float64
+	WindSpeed     float64
+	WindGust      float64
 
 	// Waves
-	WaveSummary     Swell           `json:",omitempty"`
-	SwellComponents []Swell         `json:",omitempty"`
-	Steepness       string          `json:",omitempty"`
-	AveragePeriod   float64         `json:",omitempty"`
+	WaveSummary     Swell   `json:",omitempty"`
+	SwellComponents []Swell `json:",omitempty"`
+	Steepness       string  `json:",omitempty"`
+	AveragePeriod   float64
 	WaveSpectra     BuoySpectraItem `json:",omitempty"`
 
 	// Meteorology
-	Pressure            float64 `json:",omitempty"`
-	AirTemperature      float64 `json:",omitempty"`
-	WaterTemperature    float64 `json:",omitempty"`
-	DewpointTemperature float64 `json:",omitempty"`
-	Visibility          float64 `json:",omitempty"`
-	PressureTendency    float64 `json:",omitempty"`
-	WaterLevel          float64 `json:",omitempty"`
+	Pressure            float64
+	AirTemperature      float64
+	WaterTemperature    float64
+	DewpointTemperature float64
+	Visibility          float64
+	PressureTendency    float64
+	WaterLevel          float64
 
 	// Units
 	Units UnitSystem
+
+	// Quality control flags of the measurements, set by RunQualityControl
+	QCFlags map[BuoyVariable]QCFlag `json:",omitempty"`
+}
+
+// Creates a new BuoyDataItem in the given unit system with every measurement missing
+func NewBuoyDataItem(units UnitSystem) BuoyDataItem {
+	return BuoyDataItem{
+		WindDirection:       MissingValue(),
+		WindSpeed:           MissingValue(),
+		WindGust:            MissingValue(),
+		WaveSummary:         NewMissingSwell(units),
+		AveragePeriod:       MissingValue(),
+		Pressure:            MissingValue(),
+		AirTemperature:      MissingValue(),
+		WaterTemperature:    MissingValue(),
+		DewpointTemperature: MissingValue(),
+		Visibility:          MissingValue(),
+		PressureTendency:    MissingValue(),
+		WaterLevel:          MissingValue(),
+		Units:               units,
+	}
+}
+
+// Returns if the item holds at least one measurement that is not missing
+func (b BuoyDataItem) HasData() bool {
+	measurements := []float64{
+		b.WindDirection, b.WindSpeed, b.WindGust,
+		b.WaveSummary.WaveHeight, b.WaveSummary.Period, b.AveragePeriod,
+		b.Pressure, b.AirTemperature, b.WaterTemperature, b.DewpointTemperature,
+		b.Visibility, b.PressureTendency, b.WaterLevel,
+	}
+	for _, measurement := range measurements {
+		if !IsMissing(measurement) {
+			return true
+		}
+	}
+	return len(b.WaveSpectra.Energies) > 0
 }
 
+// Converts the measurements to the given unit system. Missing values stay missing.
 func (b *BuoyDataItem) ChangeUnits(newUnits UnitSystem) {
 	if newUnits == b.Units {
 		return
@@ -58,8 +100,8 @@ func (b *BuoyDataItem) ChangeUnits(newUnits UnitSystem) {
 		b.WaterTemperature = CelsiusToFahrenheit(b.WaterTemperature)
 		b.DewpointTemperature = CelsiusToFahrenheit(b.DewpointTemperature)
 		b.Pressure = HectoPascalToInchMercury(b.Pressure)
-		b.PressureTendency = HectoPascalToInchMercury(b.Pressure)
-		b.WaterLevel = FeetToMeters(b.WaterLevel)
+		b.PressureTendency = HectoPascalToInchMercury(b.PressureTendency)
+		b.WaterLevel = MetersToFeet(b.WaterLevel)
 	}
 
 	b.WaveSummary.ChangeUnits(newUnits)
@@ -70,10 +112,19 @@ func (b *BuoyDataItem) ChangeUnits(newUnits UnitSystem) {
 	b.Units = newUnits
 }
 
-// Finds the dominant wave direction
+// Finds the dominant wave direction. Swell components with a missing period are ignored, and
+// nothing is changed if the dominant period is missing.
 func (b *BuoyDataItem) InterpolateDominantWaveDirection() {
+	if IsMissing(b.WaveSummary.Period) {
+		return
+	}
+
 	minPeriodDiff := math.Inf(1)
 	for _, swell := range b.SwellComponents {
+		if IsMissing(swell.Period) {
+			continue
+		}
+
 		periodDiff := math.Abs(swell.Period - b.WaveSummary.Period)
 		if periodDiff < minPeriodDiff {
 			minPeriodDiff = periodDiff
@@ -82,10 +133,19 @@ func (b *BuoyDataItem) InterpolateDominantWaveDirection() {
 	}
 }
 
-// Finds the dominant wave period
+// Finds the dominant wave period. Swell components with a missing height are ignored, and
+// nothing is changed if the significant wave height is missing.
 func (b *BuoyDataItem) InterpolateDominantPeriod() {
+	if IsMissing(b.WaveSummary.WaveHeight) {
+		return
+	}
+
 	minHeightDiff := math.Inf(1)
 	for _, swell := range b.SwellComponents {
+		if IsMissing(swell.WaveHeight) {
+			continue
+		}
+
 		heightDiff := math.Abs(swell.WaveHeight - b.WaveSummary.WaveHeight)
 		if heightDiff < minHeightDiff {
 			minHeightDiff = heightDiff
@@ -93,3 +153,172 @@ func (b *BuoyDataItem) InterpolateDominantPeriod() {
 		}
 	}
 }
+
+// Names a float measurement of a BuoyDataItem
+type BuoyVariable string
+
+const (
+	WindDirectionVariable       BuoyVariable = "WindDirection"
+	WindSpeedVariable           BuoyVariable = "WindSpeed"
+	WindGustVariable            BuoyVariable = "WindGust"
+	WaveHeightVariable          BuoyVariable = "WaveHeight"
+	DominantPeriodVariable      BuoyVariable = "DominantPeriod"
+	WaveDirectionVariable       BuoyVariable = "WaveDirection"
+	AveragePeriodVariable       BuoyVariable = "AveragePeriod"
+	PressureVariable            BuoyVariable = "Pressure"
+	AirTemperatureVariable      BuoyVariable = "AirTemperature"
+	WaterTemperatureVariable    BuoyVariable = "WaterTemperature"
+	DewpointTemperatureVariable BuoyVariable = "DewpointTemperature"
+	VisibilityVariable          BuoyVariable = "Visibility"
+	PressureTendencyVariable    BuoyVariable = "PressureTendency"
+	WaterLevelVariable          BuoyVariable = "WaterLevel"
+)
+
+// A float measurement of a BuoyDataItem and how to get to its value
+type buoyMeasurement struct {
+	Variable BuoyVariable
+	Value    func(*BuoyDataItem) *float64
+
+	// Directions wrap around at 360 degrees, so their differences are taken around the compass
+	Circular bool
+}
+
+// Every float measurement of a BuoyDataItem
+var buoyMeasurements = []buoyMeasurement{
+	{WindDirectionVariable, func(b *BuoyDataItem) *float64 { return &b.WindDirection }, true},
+	{WindSpeedVariable, func(b *BuoyDataItem) *float64 { return &b.WindSpeed }, false},
+	{WindGustVariable, func(b *BuoyDataItem) *float64 { return &b.WindGust }, false},
+	{WaveHeightVariable, func(b *BuoyDataItem) *float64 { return &b.WaveSummary.WaveHeight }, false},
+	{DominantPeriodVariable, func(b *BuoyDataItem) *float64 { return &b.WaveSummary.Period }, false},
+	{AveragePeriodVariable, func(b *BuoyDataItem) *float64 { return &b.AveragePeriod }, false},
+	{PressureVariable, func(b *BuoyDataItem) *float64 { return &b.Pressure }, false},
+	{AirTemperatureVariable, func(b *BuoyDataItem) *float64 { return &b.AirTemperature }, false},
+	{WaterTemperatureVariable, func(b *BuoyDataItem) *float64 { return &b.WaterTemperature }, false},
+	{DewpointTemperatureVariable, func(b *BuoyDataItem) *float64 { return &b.DewpointTemperature }, false},
+	{VisibilityVariable, func(b *BuoyDataItem) *float64 { return &b.Visibility }, false},
+	{PressureTendencyVariable, func(b *BuoyDataItem) *float64 { return &b.PressureTendency }, false},
+	{WaterLevelVariable, func(b *BuoyDataItem) *float64 { return &b.WaterLevel }, false},
+	{WaveDirectionVariable, func(b *BuoyDataItem) *float64 { return &b.WaveSummary.Direction }, true},
+}
+
+// Fills everything missing from the item with the values of another item observed at about the same
+// time. Values the item already has are kept. The other item must be in the same unit system.
+func (b *BuoyDataItem) merge(other BuoyDataItem) {
+	for _, measurement := range buoyMeasurements {
+		// The wave direction is merged below to keep the compass direction with it
+		if measurement.Variable == WaveDirectionVariable {
+			continue
+		}
+		if value := measurement.Value(b); IsMissing(*value) {
+			*value = *measurement.Value(&other)
+		}
+	}
+
+	if IsMissing(b.WaveSummary.Direction) && !IsMissing(other.WaveSummary.Direction) {
+		b.WaveSummary.Direction = other.WaveSummary.Direction
+		b.WaveSummary.CompassDirection = other.WaveSummary.CompassDirection
+	} else if b.WaveSummary.CompassDirection == "" {
+		b.WaveSummary.CompassDirection = other.WaveSummary.CompassDirection
+	}
+	if len(b.SwellComponents) == 0 {
+		b.SwellComponents = other.SwellComponents
+	}
+	if b.Steepness == "" {
+		b.Steepness = other.Steepness
+	}
+	if len(b.WaveSpectra.Frequencies) == 0 {
+		b.WaveSpectra = other.WaveSpectra
+	}
+}
+
+// Joins several time series of BuoyDataItem objects, such as the standard data and the detailed wave data
+// of a buoy, into one series by observation time. An item is joined to the closest item of the earlier
+// series observed within the tolerance, and items with no match are kept on their own. When two series
+// report the same measurement the earlier series takes precedence, and later series only fill in what is
+// missing. Every item is converted to the units of the first item. The merged series is ordered newest
+// first, like the NDBC files.
+func MergeBuoyData(tolerance time.Duration, series ...[]BuoyDataItem) []BuoyDataItem {
+	merged := []BuoyDataItem{}
+	var units UnitSystem
+	for _, items := range series {
+		// Merged is kept oldest first so the closest item can be found with a binary search
+		unmatched := []BuoyDataItem{}
+		joined := make([]bool, len(merged))
+		for _, item := range items {
+			if units == "" {
+				units = item.Units
+			} else if item.Units != units {
+				// Convert a copy so the swell components of the given series are left alone
+				item.SwellComponents = append([]Swell(nil), item.SwellComponents...)
+				item.ChangeUnits(units)
+			}
+
+			closestIndex := closestBuoyDataIndex(merged, item.Date, tolerance)
+			if closestIndex < 0 || joined[closestIndex] {
+				unmatched = append(unmatched, item)
+				continue
+			}
+
+			merged[closestIndex].merge(item)
+			joined[closestIndex] = true
+		}
+
+		merged = append(merged, unmatched...)
+		sort.SliceStable(merged, func(i, j int) bool {
+			return merged[i].Date.Before(merged[j].Date)
+		})
+	}
+
+	for i, j := 0, len(merged)-1; i < j; i, j = i+1, j-1 {
+		merged[i], merged[j] = merged[j], merged[i]
+	}
+	return merged
+}
+
+// Get the index of the item closest to the date within the tolerance, or -1 if there is none. The
+// items must be ordered oldest first.
+func closestBuoyDataIndex(items []BuoyDataItem, date time.Time, tolerance time.Duration) int {
+	index := sort.Search(len(items), func(i int) bool {
+		return !items[i].Date.Before(date)
+	})
+
+	closestIndex := -1
+	var closestOffset time.Duration
+	for _, candidate := range []int{index - 1, index} {
+		if candidate < 0 || candidate >= len(items) {
+			continue
+		}
+
+		offset := items[candidate].Date.Sub(date)
+		if offset < 0 {
+			offset = -offset
+		}
+		if offset <= tolerance && (closestIndex < 0 || offset < closestOffset) {
+			closestIndex = candidate
+			closestOffset = offset
+		}
+	}
+	return closestIndex
+}
+
+// Encodes the item as json, leaving out missing measurements
+func (b BuoyDataItem) MarshalJSON() ([]byte, error) {
+	type buoyDataItemJSON BuoyDataItem
+	return marshalOmittingMissing(buoyDataItemJSON(b))
+}
+
+// Decodes the item from json. Measurements left out of the json are missing.
+func (b *BuoyDataItem) UnmarshalJSON(data []byte) error {
+	type buoyDataItemJSON BuoyDataItem
+	item := buoyDataItemJSON{}
+	markFloatsMissing(&item)
+	item.WaveSummary = NewMissingSwell("")
+
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*b = BuoyDataItem(item)
+	return nil
+}
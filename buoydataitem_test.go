@@ -0,0 +1,139 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMissingStandardDataValues(t *testing.T) {
+	rawData := `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+2017 10 16 18 50  MM   MM   MM 99.00 99.00 99.00 999 9999.0 999.0  19.1 999.0 99.0   MM 99.00`
+
+	buoy := Buoy{}
+	buoy.ParseRawStandardData(rawData, -1)
+	if len(buoy.BuoyData) != 1 {
+		fmt.Println("Failed to parse the standard data")
+		t.FailNow()
+	}
+
+	item := buoy.BuoyData[0]
+	if !IsMissing(item.WindSpeed) || !IsMissing(item.WaveSummary.WaveHeight) || !IsMissing(item.Pressure) || !IsMissing(item.WaterLevel) {
+		fmt.Println("Missing markers and sentinels should be parsed as missing values")
+		t.FailNow()
+	}
+	if item.WaterTemperature != 19.1 {
+		fmt.Println("Reported values should be kept")
+		t.FailNow()
+	}
+
+	item.ChangeUnits(English)
+	if !IsMissing(item.AirTemperature) || IsMissing(item.WaterTemperature) {
+		fmt.Println("Missing values should stay missing when converting units")
+		t.FailNow()
+	}
+
+	rawJSON, jsonErr := json.Marshal(item)
+	if jsonErr != nil {
+		fmt.Println("Failed to encode an item with missing values")
+		t.FailNow()
+	}
+	if strings.Contains(string(rawJSON), "AirTemperature") || !strings.Contains(string(rawJSON), "WaterTemperature") {
+		fmt.Println("Missing values should be left out of the json")
+		t.FailNow()
+	}
+
+	decodedItem := BuoyDataItem{}
+	json.Unmarshal(rawJSON, &decodedItem)
+	if !IsMissing(decodedItem.AirTemperature) || decodedItem.WaterTemperature != item.WaterTemperature {
+		fmt.Println("Missing values should be restored when decoding json")
+		t.FailNow()
+	}
+}
+
+func TestFindConditionsSkipsMissingData(t *testing.T) {
+	now := time.Now()
+	emptyItem := NewBuoyDataItem(Metric)
+	emptyItem.Date = now
+
+	reportedItem := NewBuoyDataItem(Metric)
+	reportedItem.Date = now.Add(-time.Hour)
+	reportedItem.WaveSummary.WaveHeight = 1.2
+
+	buoy := Buoy{BuoyData: []BuoyDataItem{emptyItem, reportedItem}}
+	item, duration := buoy.FindConditionsForDateAndTime(now)
+	if duration < 0 || item.WaveSummary.WaveHeight != 1.2 {
+		fmt.Println("Items with no reported data should be skipped")
+		t.FailNow()
+	}
+}
+
+func TestDominantDirectionIgnoresMissingPeriods(t *testing.T) {
+	item := NewBuoyDataItem(Metric)
+	item.WaveSummary.Period = 9.0
+	item.SwellComponents = []Swell{
+		NewMissingSwell(Metric),
+		NewSwellWithCompassDirection(1.0, 8.0, "SE"),
+	}
+
+	item.InterpolateDominantWaveDirection()
+	if item.WaveSummary.CompassDirection != "SE" {
+		fmt.Println("Swell components with a missing period should be ignored")
+		t.FailNow()
+	}
+}
+
+func TestMergeBuoyDataByTime(t *testing.T) {
+	observed := time.Date(2017, 10, 16, 18, 50, 0, 0, time.UTC)
+
+	standardItem := NewBuoyDataItem(Metric)
+	standardItem.Date = observed
+	standardItem.WaveSummary.WaveHeight = 1.1
+	standardItem.WaterTemperature = 19.1
+
+	olderStandardItem := NewBuoyDataItem(Metric)
+	olderStandardItem.Date = observed.Add(-time.Hour)
+	olderStandardItem.WaterTemperature = 19.0
+
+	detailedItem := NewBuoyDataItem(Metric)
+	detailedItem.Date = observed.Add(-10 * time.Minute)
+	detailedItem.WaveSummary.WaveHeight = 1.2
+	detailedItem.AveragePeriod = 6.2
+	detailedItem.Steepness = "AVERAGE"
+	detailedItem.SwellComponents = []Swell{NewSwellWithCompassDirection(0.9, 10.0, "SSE")}
+
+	unmatchedItem := NewBuoyDataItem(Metric)
+	unmatchedItem.Date = observed.Add(-3 * time.Hour)
+	unmatchedItem.AveragePeriod = 5.0
+
+	latestItem := NewBuoyDataItem(English)
+	latestItem.Date = observed
+	latestItem.AirTemperature = 64.4
+
+	merged := MergeBuoyData(15*time.Minute,
+		[]BuoyDataItem{standardItem, olderStandardItem},
+		[]BuoyDataItem{detailedItem, unmatchedItem},
+		[]BuoyDataItem{latestItem},
+	)
+	if len(merged) != 3 || !merged[0].Date.Equal(observed) || !merged[2].Date.Equal(unmatchedItem.Date) {
+		fmt.Println("Items should be joined by observation time and ordered newest first")
+		t.FailNow()
+	}
+
+	item := merged[0]
+	if item.WaveSummary.WaveHeight != 1.1 || item.WaterTemperature != 19.1 {
+		fmt.Println("The earlier series should take precedence")
+		t.FailNow()
+	}
+	if item.AveragePeriod != 6.2 || item.Steepness != "AVERAGE" || len(item.SwellComponents) != 1 {
+		fmt.Println("Missing measurements should be filled from later series")
+		t.FailNow()
+	}
+	if item.AirTemperature < 17.99 || item.AirTemperature > 18.01 || latestItem.Units != English {
+		fmt.Println("Later series should be converted to the units of the first")
+		t.FailNow()
+	}
+}
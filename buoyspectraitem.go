@@ -1,10 +1,9 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
 	"sort"
-
-	"github.com/mpiannucci/peakdetect"
 )
 
 // Represents the Wave Spectra measured by a NDBC wave buoy for a given time step
@@ -12,38 +11,70 @@ import (
 // To the data for a given frequency. The seperation frequency is what NDBC defines as the difference
 // between a Swell wave and a Wind wave.
 //
+// Angles holds the mean wave direction (alpha1) for each frequency. When the directional coefficients
+// are fetched, Alpha2, R1 and R2 complete the first four Fourier coefficients of the directional
+// distribution at each frequency. R1 and R2 are normalized to the range 0 to 1.
+//
 // All of the math for this struct can be found here -> http://www.ndbc.noaa.gov/algor.shtml
 type BuoySpectraItem struct {
 	Frequencies []float64
 	Energies    []float64
 	Angles      []float64
+	Alpha2      []float64 `json:",omitempty"`
+	R1          []float64 `json:",omitempty"`
+	R2          []float64 `json:",omitempty"`
 
 	SeperationFrequency float64
 }
 
-func (b BuoySpectraItem) AveragePeriod() float64 {
-	zeroMoment := 0.0
-	secondMoment := 0.0
+// Returns if the item has all four directional Fourier coefficients for every frequency
+func (b BuoySpectraItem) HasDirectionalCoefficients() bool {
+	frequencyCount := len(b.Frequencies)
+	return frequencyCount > 0 &&
+		len(b.Angles) == frequencyCount &&
+		len(b.Alpha2) == frequencyCount &&
+		len(b.R1) == frequencyCount &&
+		len(b.R2) == frequencyCount
+}
 
-	if b.Frequencies == nil {
-		return -1.0
-	} else if b.Energies == nil {
-		return -1.0
+// Calculates the directional spread in degrees at each frequency from the r1 coefficient, as
+// sqrt(2 * (1 - r1)). A narrow groundswell has a small spread and a messy wind sea a large one.
+// Returns nil if the r1 coefficients are not available.
+func (b BuoySpectraItem) DirectionalSpread() []float64 {
+	if len(b.R1) == 0 || len(b.R1) != len(b.Frequencies) {
+		return nil
 	}
 
-	for index, _ := range b.Frequencies {
-		bandwidth := 0.01
-		if index > 0 {
-			bandwidth = math.Abs(b.Frequencies[index] - b.Frequencies[index-1])
-		} else if len(b.Frequencies) > 1 {
-			bandwidth = math.Abs(b.Frequencies[index+1] - b.Frequencies[index])
+	spread := make([]float64, len(b.R1))
+	for index, r1 := range b.R1 {
+		if IsMissing(r1) {
+			spread[index] = MissingValue()
+			continue
 		}
+		spread[index] = math.Sqrt(2.0*(1.0-math.Min(r1, 1.0))) * 180.0 / math.Pi
+	}
+	return spread
+}
 
-		zeroMoment += SolveZeroSpectralMoment(b.Energies[index], bandwidth)
-		secondMoment += SolveSecondSpectralMoment(b.Energies[index], bandwidth, b.Frequencies[index])
+// Get the width of the frequency band at the given index, taken as the distance to the previous
+// frequency, or to the next one for the first band. A lone frequency is given a width of 0.01 Hz.
+func frequencyBandwidth(frequencies []float64, index int) float64 {
+	if index > 0 {
+		return math.Abs(frequencies[index] - frequencies[index-1])
+	} else if len(frequencies) > 1 {
+		return math.Abs(frequencies[index+1] - frequencies[index])
 	}
+	return 0.01
+}
 
-	return math.Sqrt(zeroMoment / secondMoment)
+func (b BuoySpectraItem) AveragePeriod() float64 {
+	if b.Frequencies == nil {
+		return -1.0
+	} else if b.Energies == nil {
+		return -1.0
+	}
+
+	return b.Moments().ZeroCrossingPeriod()
 }
 
 func (b BuoySpectraItem) WaveSummary() Swell {
@@ -59,21 +90,10 @@ func (b BuoySpectraItem) WaveSummary() Swell {
 		return Swell{}
 	}
 
-	// Calculate the Significant wave height over the entire spectra
-	// And find the dominant frequency index
+	// Find the dominant frequency index
 	maxEnergyIndex := -1
 	maxEnergy := -1.0
-	zeroMoment := 0.0
 	for index, _ := range b.Frequencies {
-		bandwidth := 0.01
-		if index > 0 {
-			bandwidth = math.Abs(b.Frequencies[index] - b.Frequencies[index-1])
-		} else if len(b.Frequencies) > 1 {
-			bandwidth = math.Abs(b.Frequencies[index+1] - b.Frequencies[index])
-		}
-
-		zeroMoment += SolveZeroSpectralMoment(b.Energies[index], bandwidth)
-
 		if b.Energies[index] > maxEnergy {
 			maxEnergy = b.Energies[index]
 			maxEnergyIndex = index
@@ -81,7 +101,7 @@ func (b BuoySpectraItem) WaveSummary() Swell {
 	}
 
 	primarySwell := Swell{Units: Metric}
-	primarySwell.WaveHeight = 4.0 * math.Sqrt(zeroMoment)
+	primarySwell.WaveHeight = b.Moments().SignificantWaveHeight()
 	primarySwell.Period = 1.0 / b.Frequencies[maxEnergyIndex]
 	primarySwell.Direction = b.Angles[maxEnergyIndex]
 	primarySwell.CompassDirection = DegreeToDirection(primarySwell.Direction)
@@ -89,6 +109,8 @@ func (b BuoySpectraItem) WaveSummary() Swell {
 	return primarySwell
 }
 
+// Finds the swell and wind sea components of the spectra by partitioning it with the
+// DefaultPartitionOptions. Components are sorted with the highest peak energy first.
 func (b BuoySpectraItem) FindSwellComponents() []Swell {
 	if b.Frequencies == nil {
 		return nil
@@ -102,48 +124,55 @@ func (b BuoySpectraItem) FindSwellComponents() []Swell {
 		return nil
 	}
 
-	// Find the peaks from the energy data
-	minIndexes, _, maxIndexes, maxEnergies := peakdetect.PeakDetect(b.Energies, 0.01)
+	partitions, partitionErr := b.Partition(DefaultPartitionOptions())
+	if partitionErr != nil {
+		return nil
+	}
 
-	// Allocate the list of components to the size of the local max peaks found
-	components := make([]Swell, len(maxIndexes), len(maxIndexes))
+	components := make([]Swell, len(partitions))
+	for index, partition := range partitions {
+		components[index] = partition.Swell
+	}
 
-	// Loop through and find all of the swell components
-	prevIndex := 0
-	for maxIndex, maxEnergy := range maxEnergies {
-		minIndex := prevIndex
-		if maxIndex >= len(minIndexes) {
-			minIndex = len(b.Energies)
-		} else {
-			minIndex = minIndexes[maxIndex]
-		}
+	sort.Sort(sort.Reverse(ByMaxEnergy(components)))
 
-		zeroMoment := 0.0
-		for i := prevIndex; i < minIndex; i++ {
-			bandwidth := 0.01
-			if i > 0 {
-				bandwidth = math.Abs(b.Frequencies[i] - b.Frequencies[i-1])
-			} else if len(b.Frequencies) > 1 {
-				bandwidth = math.Abs(b.Frequencies[i+1] - b.Frequencies[i])
-			}
+	return components
+}
 
-			zeroMoment += SolveZeroSpectralMoment(b.Energies[i], bandwidth)
-		}
+// Encodes the spectra as json, with missing values encoded as null
+func (b BuoySpectraItem) MarshalJSON() ([]byte, error) {
+	type buoySpectraItemJSON BuoySpectraItem
+	return marshalOmittingMissing(buoySpectraItemJSON(b))
+}
 
-		// Add the component we found!!
-		swellComponent := Swell{Units: Metric}
-		swellComponent.WaveHeight = 4.0 * math.Sqrt(zeroMoment)
-		swellComponent.Period = 1.0 / b.Frequencies[maxIndexes[maxIndex]]
-		swellComponent.Direction = b.Angles[maxIndexes[maxIndex]]
-		swellComponent.CompassDirection = DegreeToDirection(swellComponent.Direction)
-		swellComponent.MaxEnergy = maxEnergy
-		swellComponent.FrequencyIndex = maxIndexes[maxIndex]
-		components[maxIndex] = swellComponent
-
-		prevIndex = minIndex
+// Decodes the spectra from json, with null values decoded as missing
+func (b *BuoySpectraItem) UnmarshalJSON(data []byte) error {
+	var item struct {
+		Frequencies         []*float64
+		Energies            []*float64
+		Angles              []*float64
+		Alpha2              []*float64
+		R1                  []*float64
+		R2                  []*float64
+		SeperationFrequency *float64
 	}
 
-	sort.Sort(sort.Reverse(ByMaxEnergy(components)))
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
 
-	return components
+	*b = BuoySpectraItem{
+		Frequencies:         floatsFromNullable(item.Frequencies),
+		Energies:            floatsFromNullable(item.Energies),
+		Angles:              floatsFromNullable(item.Angles),
+		Alpha2:              floatsFromNullable(item.Alpha2),
+		R1:                  floatsFromNullable(item.R1),
+		R2:                  floatsFromNullable(item.R2),
+		SeperationFrequency: MissingValue(),
+	}
+	if item.SeperationFrequency != nil {
+		b.SeperationFrequency = *item.SeperationFrequency
+	}
+	return nil
 }
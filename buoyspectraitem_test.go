@@ -0,0 +1,315 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	testAlphaSpectraData  = "#YY  MM DD hh mm alpha1 (freq)\n2017 10 16 18 00 150.0 (0.050) 160.0 (0.100) 200.0 (0.150) 220.0 (0.200)"
+	testEnergySpectraData = "#YY  MM DD hh mm Sep_Freq  < spec_1 (freq_1) ... >\n2017 10 16 18 00 0.130 0.50 (0.050) 4.00 (0.100) 1.00 (0.150) 0.50 (0.200)"
+	testAlpha2SpectraData = "#YY  MM DD hh mm alpha2 (freq)\n2017 10 16 18 00 152.0 (0.050) 158.0 (0.100) 205.0 (0.150) 999.0 (0.200)"
+	testR1SpectraData     = "#YY  MM DD hh mm r1 (freq)\n2017 10 16 18 00 0.90 (0.050) 0.95 (0.100) 0.60 (0.150) 0.40 (0.200)"
+	testR2SpectraData     = "#YY  MM DD hh mm r2 (freq)\n2017 10 16 18 00 0.70 (0.050) 0.85 (0.100) 0.30 (0.150) 0.20 (0.200)"
+)
+
+// Creates a buoy holding the canned spectra with all of its directional coefficients
+func newTestSpectraBuoy() (*Buoy, error) {
+	buoy := &Buoy{}
+	spectraErr := buoy.ParseRawWaveSpectraData(strings.Split(testAlphaSpectraData, "\n"), strings.Split(testEnergySpectraData, "\n"), 1)
+	if spectraErr != nil {
+		return nil, spectraErr
+	}
+
+	coefficientErr := buoy.ParseRawDirectionalCoefficientData(
+		strings.Split(testAlpha2SpectraData, "\n"),
+		strings.Split(testR1SpectraData, "\n"),
+		strings.Split(testR2SpectraData, "\n"),
+	)
+	return buoy, coefficientErr
+}
+
+func TestDirectionalCoefficientParsing(t *testing.T) {
+	buoy, parseErr := newTestSpectraBuoy()
+	if parseErr != nil {
+		fmt.Println("Failed to parse the directional coefficients")
+		t.FailNow()
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if !spectra.HasDirectionalCoefficients() {
+		fmt.Println("The directional coefficients were not attached to the spectra")
+		t.FailNow()
+	}
+	if spectra.R1[1] != 0.95 || spectra.Alpha2[0] != 152.0 || !IsMissing(spectra.Alpha2[3]) {
+		fmt.Println("The directional coefficients were parsed incorrectly")
+		t.FailNow()
+	}
+
+	spread := spectra.DirectionalSpread()
+	if math.Abs(spread[1]-18.12) > 0.01 || spread[3] < spread[1] {
+		fmt.Println("The directional spread was calculated incorrectly")
+		t.FailNow()
+	}
+
+	if _, jsonErr := json.Marshal(buoy); jsonErr != nil {
+		fmt.Println("Failed to encode spectra with missing coefficients")
+		t.FailNow()
+	}
+}
+
+func TestWaveSpectraMissingValues(t *testing.T) {
+	rawAlpha := strings.Replace(testAlphaSpectraData, "200.0 (0.150)", "999.0 (0.150)", 1)
+	rawEnergy := strings.Replace(testEnergySpectraData, "0.50 (0.200)", "MM (0.200)", 1)
+
+	buoy := &Buoy{}
+	spectraErr := buoy.ParseRawWaveSpectraData(strings.Split(rawAlpha, "\n"), strings.Split(rawEnergy, "\n"), 1)
+	coefficientErr := buoy.ParseRawDirectionalCoefficientData(
+		strings.Split(testAlpha2SpectraData, "\n"),
+		strings.Split(testR1SpectraData, "\n"),
+		strings.Split(testR2SpectraData, "\n"),
+	)
+	if spectraErr != nil || coefficientErr != nil {
+		fmt.Println("Failed to parse spectra with missing values")
+		t.FailNow()
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if !IsMissing(spectra.Angles[2]) || !IsMissing(spectra.Energies[3]) || spectra.Angles[1] != 160.0 {
+		fmt.Println("A 999 direction and an MM energy should be parsed as missing:", spectra.Angles, spectra.Energies)
+		t.FailNow()
+	}
+	if summary := buoy.BuoyData[0].WaveSummary; IsMissing(summary.WaveHeight) || summary.Direction != 160.0 {
+		fmt.Println("The wave summary should skip the missing bins:", summary)
+		t.FailNow()
+	}
+
+	// Without a mean direction the energy of the bin is spread evenly over every direction
+	spectrum, spectrumErr := spectra.DirectionalSpectrum(FourierSeriesMethod, 5.0)
+	if spectrumErr != nil {
+		fmt.Println(spectrumErr)
+		t.FailNow()
+	}
+	row := spectrum.Energy[2]
+	for _, energy := range row {
+		if math.Abs(energy-row[0]) > 1e-12 {
+			fmt.Println("A bin without a direction should have no directional information")
+			t.FailNow()
+		}
+	}
+	if !IsMissing(spectrum.Energy[3][0]) {
+		fmt.Println("A bin without energy should stay missing")
+		t.FailNow()
+	}
+}
+
+func TestDirectionalSpectrumReconstruction(t *testing.T) {
+	buoy, parseErr := newTestSpectraBuoy()
+	if parseErr != nil {
+		fmt.Println("Failed to parse the directional coefficients")
+		t.FailNow()
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	zeroMoment := 0.0
+	for index, energy := range spectra.Energies {
+		zeroMoment += energy * frequencyBandwidth(spectra.Frequencies, index)
+	}
+
+	for _, method := range []DirectionalSpreadingMethod{MaximumEntropyMethod, FourierSeriesMethod} {
+		spectrum, spectrumErr := spectra.DirectionalSpectrum(method, 5.0)
+		if spectrumErr != nil || len(spectrum.Directions) != 72 || len(spectrum.Energy) != len(spectra.Frequencies) {
+			fmt.Println("Failed to build the directional spectrum with method", method)
+			t.FailNow()
+		}
+
+		if math.Abs(spectrum.EnergyWithinDirections(0, 360)-zeroMoment) > 1e-9 {
+			fmt.Println("The directional spectrum should hold all of the spectra energy with method", method)
+			t.FailNow()
+		}
+
+		// Bins centred on the edge between two windows are only counted once
+		quarters := 0.0
+		for from := 0.0; from < 360.0; from += 90.0 {
+			quarters += spectrum.EnergyWithinDirections(from, from+90.0)
+		}
+		if math.Abs(quarters-zeroMoment) > 1e-9 {
+			fmt.Println("Adjacent windows should add up to the total energy with method", method)
+			t.FailNow()
+		}
+
+		windowEnergy := spectrum.EnergyWithinDirections(100, 220)
+		if windowEnergy < 0.75*zeroMoment || spectrum.EnergyWithinDirections(280, 40) > 0.1*zeroMoment {
+			fmt.Println("The energy should arrive from the mean wave direction with method", method)
+			t.FailNow()
+		}
+	}
+
+	if _, spectrumErr := (BuoySpectraItem{}).DirectionalSpectrum(MaximumEntropyMethod, 5.0); spectrumErr != ErrNoDirectionalCoefficients {
+		fmt.Println("Expected spectra without coefficients to be rejected")
+		t.FailNow()
+	}
+}
+
+// Creates a spectra with a long period swell from the south, a wind sea from the west and a
+// small bump of noise between them
+func newTestBimodalSpectra() BuoySpectraItem {
+	spectra := BuoySpectraItem{SeperationFrequency: MissingValue()}
+	for frequency := 0.05; frequency < 0.3; frequency += 0.01 {
+		swell := 3.0 * math.Exp(-math.Pow((frequency-0.07)/0.01, 2))
+		windSea := 1.0 * math.Exp(-math.Pow((frequency-0.2)/0.02, 2))
+		noise := 0.02 * math.Exp(-math.Pow((frequency-0.13)/0.005, 2))
+		direction := 180.0
+		if frequency > 0.13 {
+			direction = 270.0
+		}
+
+		spectra.Frequencies = append(spectra.Frequencies, frequency)
+		spectra.Energies = append(spectra.Energies, swell+windSea+noise)
+		spectra.Angles = append(spectra.Angles, direction)
+		spectra.Alpha2 = append(spectra.Alpha2, direction)
+		spectra.R1 = append(spectra.R1, 0.9)
+		spectra.R2 = append(spectra.R2, 0.7)
+	}
+	return spectra
+}
+
+func TestSpectralPartitioning(t *testing.T) {
+	spectra := newTestBimodalSpectra()
+
+	options := DefaultPartitionOptions()
+	options.UseDirectionalSpectrum = false
+	partitions, partitionErr := spectra.Partition(options)
+	if partitionErr != nil || len(partitions) != 2 {
+		fmt.Println("Expected the noise to be merged into the two real partitions")
+		t.FailNow()
+	}
+	if math.Abs(partitions[0].PeakFrequency-0.07) > 1e-9 || partitions[0].Swell.Direction != 180.0 || partitions[1].Swell.Direction != 270.0 {
+		fmt.Println("Partitions were found at the wrong peaks")
+		t.FailNow()
+	}
+	if math.Abs(partitions[0].EnergyFraction+partitions[1].EnergyFraction-1.0) > 1e-9 || partitions[1].MinimumFrequency <= partitions[0].MaximumFrequency {
+		fmt.Println("Partitions should split the whole spectrum without overlapping")
+		t.FailNow()
+	}
+
+	options.MinimumHeightFraction = 0
+	options.MinimumFrequencySeparation = 0
+	if partitions, _ = spectra.Partition(options); len(partitions) != 3 {
+		fmt.Println("Expected every peak to be kept without merging")
+		t.FailNow()
+	}
+
+	options = DefaultPartitionOptions()
+	options.MaximumDirectionDifference = 120
+	if partitions, _ = spectra.Partition(options); len(partitions) != 1 {
+		fmt.Println("Expected partitions with similar directions to be merged")
+		t.FailNow()
+	}
+
+	directionalPartitions, partitionErr := spectra.Partition(DefaultPartitionOptions())
+	if partitionErr != nil || len(directionalPartitions) != 2 {
+		fmt.Println("Expected the directional spectrum to have two partitions")
+		t.FailNow()
+	}
+	if math.Abs(directionalPartitions[0].Swell.Direction-180.0) > 10.0 || math.Abs(directionalPartitions[1].Swell.Direction-270.0) > 10.0 {
+		fmt.Println("Directional partitions were found at the wrong directions")
+		t.FailNow()
+	}
+
+	if components := spectra.FindSwellComponents(); len(components) != 2 || components[0].Period < 14 {
+		fmt.Println("Swell components should come from the partitions")
+		t.FailNow()
+	}
+}
+
+func TestCrossingSwellPartitioning(t *testing.T) {
+	// Two equal swells of the same period from 30 and 150 degrees. Their directional coefficients
+	// average the two, so only the directional spectrum can tell them apart.
+	spectra := BuoySpectraItem{SeperationFrequency: MissingValue()}
+	for frequency := 0.05; frequency < 0.2; frequency += 0.01 {
+		spectra.Frequencies = append(spectra.Frequencies, frequency)
+		spectra.Energies = append(spectra.Energies, 2.0*math.Exp(-math.Pow((frequency-0.08)/0.01, 2)))
+		spectra.Angles = append(spectra.Angles, 90.0)
+		spectra.R1 = append(spectra.R1, 0.45)
+		spectra.Alpha2 = append(spectra.Alpha2, 0.0)
+		spectra.R2 = append(spectra.R2, 0.4)
+	}
+
+	partitions, partitionErr := spectra.Partition(DefaultPartitionOptions())
+	if partitionErr != nil || len(partitions) != 2 {
+		fmt.Println("Expected crossing swells of the same period to be two partitions, got", len(partitions))
+		t.FailNow()
+	}
+
+	directions := []float64{partitions[0].Swell.Direction, partitions[1].Swell.Direction}
+	sort.Float64s(directions)
+	if math.Abs(directions[0]-30.0) > 15.0 || math.Abs(directions[1]-150.0) > 15.0 || partitions[0].PeakFrequency != partitions[1].PeakFrequency {
+		fmt.Println("Crossing swells were found at the wrong directions:", directions)
+		t.FailNow()
+	}
+}
+
+func TestSpectralMoments(t *testing.T) {
+	spectra := newTestBimodalSpectra()
+	moments := spectra.Moments()
+
+	zeroMoment, secondMoment := 0.0, 0.0
+	for index, frequency := range spectra.Frequencies {
+		bandwidth := frequencyBandwidth(spectra.Frequencies, index)
+		zeroMoment += SolveZeroSpectralMoment(spectra.Energies[index], bandwidth)
+		secondMoment += SolveSecondSpectralMoment(spectra.Energies[index], bandwidth, frequency)
+	}
+	if math.Abs(moments.Zero-zeroMoment) > 1e-9 || math.Abs(moments.ZeroCrossingPeriod()-math.Sqrt(zeroMoment/secondMoment)) > 1e-9 {
+		fmt.Println("Moments do not match the spectral moment formulas")
+		t.FailNow()
+	}
+	if moments.EnergyPeriod() <= moments.MeanPeriod() || moments.MeanPeriod() <= moments.ZeroCrossingPeriod() {
+		fmt.Println("Expected Te > Tm01 > Tm02 for a spectrum with a long period swell")
+		t.FailNow()
+	}
+	if width := moments.SpectralWidth(); width <= 0 || width >= 1 || moments.SpectralNarrowness() <= 0 || moments.Peakedness() <= 0 {
+		fmt.Println("Bandwidth parameters are out of range")
+		t.FailNow()
+	}
+
+	swellBand := spectra.MomentsInBand(0, 0.13)
+	windBand := spectra.MomentsInBand(0.1300001, 1)
+	if math.Abs(swellBand.Zero+windBand.Zero-moments.Zero) > 1e-9 || math.Abs(swellBand.MeanDirection()-180.0) > 1e-6 {
+		fmt.Println("Band moments should split the whole spectrum")
+		t.FailNow()
+	}
+
+	// Every band has r1 of 0.9, so a band with one direction has a spread of sqrt(2 * 0.1) radians
+	expectedSpread := math.Sqrt(0.2) * 180.0 / math.Pi
+	if math.Abs(swellBand.DirectionalSpread()-expectedSpread) > 1e-6 || moments.DirectionalSpread() <= expectedSpread {
+		fmt.Println("Directional spread is wrong")
+		t.FailNow()
+	}
+
+	options := DefaultPartitionOptions()
+	options.UseDirectionalSpectrum = false
+	partitions, _ := spectra.Partition(options)
+	partitionEnergy := 0.0
+	for _, partition := range partitions {
+		partitionEnergy += partition.Moments.Zero
+		if math.Abs(partition.Moments.SignificantWaveHeight()-partition.Swell.WaveHeight) > 1e-9 {
+			fmt.Println("Partition moments do not match the partition height")
+			t.FailNow()
+		}
+	}
+	if math.Abs(partitionEnergy-moments.Zero) > 1e-9 || math.Abs(partitions[1].Moments.MeanDirection()-270.0) > 5.0 {
+		fmt.Println("Partition moments should split the whole spectrum")
+		t.FailNow()
+	}
+
+	spectra.R1 = nil
+	if !IsMissing(spectra.Moments().DirectionalSpread()) || IsMissing(spectra.Moments().MeanDirection()) {
+		fmt.Println("Spread should be missing without r1, but the direction known")
+		t.FailNow()
+	}
+}
@@ -1,15 +1,27 @@
 package surfnerd
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 const (
-	ActiveBuoysURL = "http://www.ndbc.noaa.gov/activestations.xml"
+	// The url of the active station list on the default NDBC endpoint
+	ActiveBuoysURL = defaultNDBCEndpoint + activeBuoysPath
+
+	activeBuoysPath = "/activestations.xml"
+
+	// The directory listing of the realtime files, which holds a spectral energy file for every
+	// station that currently reports wave spectra
+	realtimeListingPath = "/data/realtime2/"
 )
 
+var spectralFilePattern = regexp.MustCompile(`href="([A-Za-z0-9]+)\.data_spec"`)
+
 // Container to hold all of the buoy locations that are reported by NOAA in their
 // active stations xml file. Works as an in
 type BuoyStations struct {
@@ -17,20 +29,65 @@ type BuoyStations struct {
 	CreationDate string   `xml:"created,attr"`
 	StationCount int      `xml:"count,attr"`
 	Stations     []*Buoy  `xml:"station"`
+
+	// The client used to fetch the station list. DefaultClient is used when nil.
+	Client *Client `xml:"-" json:"-"`
+
+	index      *StationIndex
+	indexMutex sync.Mutex
 }
 
 // Fetch all of the buoy stations in xml format from the NOAA endpoint and parse them into buoy objects.
 // Returns true if the buoys were successfully parsed into the Stations variable
 func (b *BuoyStations) GetAllActiveBuoyStations() error {
-	rawStations, dlErr := fetchRawDataFromURL(ActiveBuoysURL)
+	return b.GetAllActiveBuoyStationsContext(context.Background())
+}
+
+// Same as GetAllActiveBuoyStations, but the download is bound to the given context. Each
+// parsed station inherits the Client of the station list, and is marked with SpectralData when
+// the realtime file listing has a spectral energy file for it.
+func (b *BuoyStations) GetAllActiveBuoyStationsContext(ctx context.Context) error {
+	client := b.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	rawStations, dlErr := fetchRawDataFromURL(ctx, client.fetcher(), client.endpoints().ndbcURL(activeBuoysPath))
+	if dlErr != nil {
+		return dlErr
+	}
+
+	parseErr := xml.Unmarshal(rawStations, b)
+	if parseErr != nil {
+		return parseErr
+	}
+
+	rawListing, dlErr := fetchRawDataFromURL(ctx, client.fetcher(), client.endpoints().ndbcURL(realtimeListingPath))
 	if dlErr != nil {
 		return dlErr
 	}
+	spectralStations := parseSpectralStations(rawListing)
+
+	for _, station := range b.Stations {
+		station.Client = b.Client
+		station.SpectralData = spectralStations[strings.ToLower(station.StationID)]
+	}
 
-	xml.Unmarshal(rawStations, b)
+	b.indexMutex.Lock()
+	b.index = NewStationIndex(b.Stations)
+	b.indexMutex.Unlock()
 	return nil
 }
 
+// Reads the lower case ids of the stations with a spectral energy file from the realtime file listing
+func parseSpectralStations(rawListing []byte) map[string]bool {
+	stations := map[string]bool{}
+	for _, match := range spectralFilePattern.FindAllSubmatch(rawListing, -1) {
+		stations[strings.ToLower(string(match[1]))] = true
+	}
+	return stations
+}
+
 // Searches the list of buoys linearly to find a buoy matching the given station id.
 func (b *BuoyStations) FindBuoyByID(stationID string) *Buoy {
 	for _, buoy := range b.Stations {
@@ -41,86 +98,64 @@ func (b *BuoyStations) FindBuoyByID(stationID string) *Buoy {
 	return nil
 }
 
-// Finds and returns the closest buoy to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
-func (b *BuoyStations) FindClosestActiveBuoy(loc Location) *Buoy {
-	if len(b.Stations) < 1 {
-		return nil
+// Get the spatial index of the stations. The index is built when the stations are fetched, or on
+// first use for stations set by hand, so call ResetIndex after changing the Stations by hand. It is
+// safe to query the index from multiple goroutines.
+func (b *BuoyStations) Index() *StationIndex {
+	b.indexMutex.Lock()
+	defer b.indexMutex.Unlock()
+
+	if b.index == nil {
+		b.index = NewStationIndex(b.Stations)
 	}
+	return b.index
+}
 
-	var closestBuoy *Buoy = nil
-	closestDistance := 9999999999.9
+// Drops the spatial index so it is rebuilt from the current Stations on the next query
+func (b *BuoyStations) ResetIndex() {
+	b.indexMutex.Lock()
+	defer b.indexMutex.Unlock()
 
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		}
+	b.index = nil
+}
 
-		dist := loc.DistanceTo(*buoy.Location)
-		if dist < closestDistance {
-			closestBuoy = buoy
-			closestDistance = dist
-		}
-	}
+// Finds the n stations closest to the location that pass every filter, closest first
+func (b *BuoyStations) Nearest(loc Location, n int, filters ...StationFilter) []StationDistance {
+	return b.Index().Nearest(loc, n, filters...)
+}
 
-	return closestBuoy
+// Finds every station within the radius in kilometers of the location that passes every filter,
+// closest first
+func (b *BuoyStations) WithinRadius(loc Location, radius float64, filters ...StationFilter) []StationDistance {
+	return b.Index().WithinRadius(loc, radius, filters...)
 }
 
-// Finds and returns the closest buoy with wave data to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
-func (b *BuoyStations) FindClosestActiveWaveBuoy(loc Location) *Buoy {
-	if len(b.Stations) < 1 {
+// Finds and returns the closest buoy to a given location
+func (b *BuoyStations) FindClosestActiveBuoy(loc Location) *Buoy {
+	closest := b.Nearest(loc, 1, ActiveFilter())
+	if len(closest) < 1 {
 		return nil
 	}
-
-	var closestBuoy *Buoy = nil
-	closestDistance := 9999999999.9
-
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		} else if buoy.Type != "buoy" {
-			continue
-		}
-
-		dist := loc.DistanceTo(*buoy.Location)
-		if dist < closestDistance {
-			closestBuoy = buoy
-			closestDistance = dist
-		}
-	}
-
-	return closestBuoy
+	return closest[0].Buoy
 }
 
-// Finds and returns the 3 closest buoys with wave data to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
-func (b *BuoyStations) FindClosestActiveWaveBuoys(loc Location) []*Buoy {
-	if len(b.Stations) < 1 {
+// Finds and returns the closest buoy with wave data to a given location
+func (b *BuoyStations) FindClosestActiveWaveBuoy(loc Location) *Buoy {
+	closest := b.Nearest(loc, 1, SpectralDataFilter())
+	if len(closest) < 1 {
 		return nil
 	}
+	return closest[0].Buoy
+}
 
-	closestBuoys := make([]*Buoy, 3, 3)
-	closestDistances := [...]float64{9999999.999, 9999999.999, 9999999.999}
-
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		} else if buoy.Type != "buoy" {
-			continue
-		}
-
-		dist := loc.DistanceTo(*buoy.Location)
-
-		for i := 0; i < 3; i++ {
-			if dist < closestDistances[i] {
-				closestBuoys[i] = buoy
-				closestDistances[i] = dist
-				break
-			}
-		}
+// Finds and returns the 3 closest buoys with wave data to a given location, closest first. Fewer
+// buoys are returned when there are not 3 to be found.
+func (b *BuoyStations) FindClosestActiveWaveBuoys(loc Location) []*Buoy {
+	closest := b.Nearest(loc, 3, SpectralDataFilter())
+	closestBuoys := make([]*Buoy, len(closest))
+	for index, station := range closest {
+		closestBuoys[index] = station.Buoy
 	}
-
 	return closestBuoys
 }
 
@@ -0,0 +1,164 @@
+package surfnerd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A cached response for a single url. An Expires value of zero means the entry never
+// expires on its own.
+type CacheEntry struct {
+	URL          string
+	Data         []byte `json:"-"`
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+	FetchedAt    time.Time
+	Expires      time.Time
+}
+
+// Returns if the entry is still fresh at the given time
+func (c CacheEntry) IsFresh(now time.Time) bool {
+	return c.Expires.IsZero() || now.Before(c.Expires)
+}
+
+// Returns if the entry can be revalidated with the server instead of refetched
+func (c CacheEntry) HasValidators() bool {
+	return c.ETag != "" || c.LastModified != ""
+}
+
+// Storage for cached responses. Get returns false when there is no usable entry for a key.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Put(key string, entry *CacheEntry) error
+}
+
+// A Cache that keeps every entry as a pair of files in a directory. The response body is
+// stored as-is in a .data file next to a small json file holding the metadata.
+type FileCache struct {
+	Directory string
+}
+
+// Creates a new FileCache in the given directory, creating the directory if needed
+func NewFileCache(directory string) (*FileCache, error) {
+	mkdirErr := os.MkdirAll(directory, 0755)
+	if mkdirErr != nil {
+		return nil, mkdirErr
+	}
+	return &FileCache{Directory: directory}, nil
+}
+
+// Get the file path prefix for the given cache key
+func (f *FileCache) pathForKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(f.Directory, hex.EncodeToString(hash[:]))
+}
+
+// Reads the entry for the given key from disk
+func (f *FileCache) Get(key string) (*CacheEntry, bool) {
+	path := f.pathForKey(key)
+
+	rawMetadata, metadataErr := ioutil.ReadFile(path + ".json")
+	if metadataErr != nil {
+		return nil, false
+	}
+
+	entry := &CacheEntry{}
+	if json.Unmarshal(rawMetadata, entry) != nil {
+		return nil, false
+	}
+
+	data, dataErr := ioutil.ReadFile(path + ".data")
+	if dataErr != nil {
+		return nil, false
+	}
+	entry.Data = data
+
+	return entry, true
+}
+
+// Writes the entry for the given key to disk. Each file is written to a temporary file and renamed
+// into place, and the data is moved in before the metadata, so a crash never leaves a torn entry
+// that is read back.
+func (f *FileCache) Put(key string, entry *CacheEntry) error {
+	path := f.pathForKey(key)
+
+	rawMetadata, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	os.Remove(path + ".json")
+	dataErr := writeFileAtomically(path+".data", entry.Data)
+	if dataErr != nil {
+		return dataErr
+	}
+
+	return writeFileAtomically(path+".json", rawMetadata)
+}
+
+// Writes the file to a temporary file in the same directory and renames it over the path
+func writeFileAtomically(path string, data []byte) error {
+	tempFile, createErr := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if createErr != nil {
+		return createErr
+	}
+
+	_, writeErr := tempFile.Write(data)
+	closeErr := tempFile.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Chmod(tempFile.Name(), 0644)
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tempFile.Name(), path)
+	}
+	if writeErr != nil {
+		os.Remove(tempFile.Name())
+	}
+	return writeErr
+}
+
+// Removes every entry that was fetched before the given time
+func (f *FileCache) Prune(fetchedBefore time.Time) error {
+	files, listErr := ioutil.ReadDir(f.Directory)
+	if listErr != nil {
+		return listErr
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(f.Directory, strings.TrimSuffix(file.Name(), ".json"))
+		rawMetadata, readErr := ioutil.ReadFile(path + ".json")
+		if readErr != nil {
+			continue
+		}
+
+		entry := CacheEntry{}
+		if json.Unmarshal(rawMetadata, &entry) != nil || entry.FetchedAt.Before(fetchedBefore) {
+			os.Remove(path + ".json")
+			os.Remove(path + ".data")
+		}
+	}
+
+	return nil
+}
+
+// Removes every entry from the cache
+func (f *FileCache) Clear() error {
+	removeErr := os.RemoveAll(f.Directory)
+	if removeErr != nil {
+		return removeErr
+	}
+	return os.MkdirAll(f.Directory, 0755)
+}
@@ -0,0 +1,127 @@
+package surfnerd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCachingFetcherRevalidation(t *testing.T) {
+	requestCount := 0
+	revalidationCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidationCount++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "buoy data")
+	}))
+	defer server.Close()
+
+	cacheDir, _ := ioutil.TempDir("", "surfnerd-cache")
+	defer os.RemoveAll(cacheDir)
+	cache, _ := NewFileCache(cacheDir)
+
+	currentTime := time.Date(2017, 10, 16, 18, 10, 0, 0, time.UTC)
+	fetcher := NewCachingFetcher(&HTTPFetcher{Client: server.Client()}, cache)
+	fetcher.now = func() time.Time { return currentTime }
+
+	url := server.URL + "/data/realtime2/44097.txt"
+	for i := 0; i < 2; i++ {
+		data, fetchErr := fetcher.Fetch(context.Background(), url)
+		if fetchErr != nil || string(data) != "buoy data" {
+			fmt.Println("Failed to fetch through the cache")
+			t.FailNow()
+		}
+	}
+	if requestCount != 1 {
+		fmt.Println("A fresh cache entry should not hit the server")
+		t.FailNow()
+	}
+
+	// Past the next NDBC update the entry is stale and gets revalidated
+	currentTime = currentTime.Add(25 * time.Minute)
+	data, fetchErr := fetcher.Fetch(context.Background(), url)
+	if fetchErr != nil || string(data) != "buoy data" || revalidationCount != 1 {
+		fmt.Println("Failed to revalidate a stale cache entry")
+		t.FailNow()
+	}
+
+	fetcher.Disabled = true
+	fetcher.Fetch(context.Background(), url)
+	if requestCount != 3 {
+		fmt.Println("A disabled cache should always hit the server")
+		t.FailNow()
+	}
+}
+
+func TestDefaultCachePolicy(t *testing.T) {
+	fetchedAt := time.Date(2017, 10, 16, 18, 10, 0, 0, time.UTC)
+
+	expires, cacheable := DefaultCachePolicy("http://www.ndbc.noaa.gov/data/realtime2/44097.spec", fetchedAt)
+	if !cacheable || !expires.Equal(time.Date(2017, 10, 16, 18, 30, 0, 0, time.UTC)) {
+		fmt.Println("Realtime buoy data should expire at the next half hour")
+		t.Fail()
+	}
+
+	expires, cacheable = DefaultCachePolicy("http://www.ndbc.noaa.gov/activestations.xml", fetchedAt)
+	if !cacheable || !expires.Equal(fetchedAt.Add(24*time.Hour)) {
+		fmt.Println("The station list should be cached for a day")
+		t.Fail()
+	}
+
+	expires, cacheable = DefaultCachePolicy("http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/20171016/multi_1.at_10m20171016_12z.ascii", fetchedAt)
+	if !cacheable || !expires.IsZero() {
+		fmt.Println("Model data should never expire within a model run")
+		t.Fail()
+	}
+}
+
+func TestCachingFetcherSkipsUnpublishedModelRuns(t *testing.T) {
+	published := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !published {
+			fmt.Fprint(w, "Error { code = 0; message = \"multi_1.at_10m20171016_12z is not an available dataset\"; }")
+			return
+		}
+		fmt.Fprint(w, "Attributes {\n}\n")
+	}))
+	defer server.Close()
+
+	cacheDir, _ := ioutil.TempDir("", "surfnerd-cache")
+	defer os.RemoveAll(cacheDir)
+	cache, _ := NewFileCache(cacheDir)
+	fetcher := NewCachingFetcher(&HTTPFetcher{Client: server.Client()}, cache)
+
+	url := server.URL + "/dods/wave/mww3/20171016/multi_1.at_10m20171016_12z.das"
+	fetcher.Fetch(context.Background(), url)
+	if _, found := cache.Get(url); found {
+		fmt.Println("The error page of an unpublished run should not be cached")
+		t.FailNow()
+	}
+
+	published = true
+	data, fetchErr := fetcher.Fetch(context.Background(), url)
+	if fetchErr != nil || checkModelResponse(data, url) != nil {
+		fmt.Println("The run should be fetched again once it is published")
+		t.FailNow()
+	}
+	if _, found := cache.Get(url); !found {
+		fmt.Println("The published run should be cached")
+		t.FailNow()
+	}
+
+	files, _ := ioutil.ReadDir(cacheDir)
+	if len(files) != 2 {
+		fmt.Println("Temporary files should be renamed into place, found", len(files), "files")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,171 @@
+package surfnerd
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	stationListCacheDuration = 24 * time.Hour
+	monthlyArchiveDuration   = 24 * time.Hour
+	ndbcUpdateInterval       = 30 * time.Minute
+)
+
+// Decides how long the response for a url stays fresh. Returning false means the response
+// should not be cached at all, and a zero expiry means it never expires.
+type CachePolicy func(url string, fetchedAt time.Time) (expires time.Time, cacheable bool)
+
+// The default cache policy, tuned to how often NOAA updates each product:
+//
+// The active station list is cached for a day.
+//
+// Realtime buoy files are cached until the next half hour, when NDBC publishes new observations.
+//
+// Yearly buoy archives never change once published, so they never expire. Monthly archives for
+// the current year are cached for a day.
+//
+// Model data never expires. Model urls contain the date and cycle of the model run, so a new
+// run is always fetched under a new key and a cached run never goes stale. The error pages NOMADS
+// serves for runs that are not published yet are never cached, see CachingFetcher.
+//
+// Anything else is not cached.
+func DefaultCachePolicy(url string, fetchedAt time.Time) (time.Time, bool) {
+	switch {
+	case strings.Contains(url, "activestations.xml"):
+		return fetchedAt.Add(stationListCacheDuration), true
+	case strings.Contains(url, "/data/realtime2/"), strings.Contains(url, "/data/latest_obs/"):
+		return fetchedAt.Truncate(ndbcUpdateInterval).Add(ndbcUpdateInterval), true
+	case strings.Contains(url, "/data/historical/"):
+		return time.Time{}, true
+	case strings.HasSuffix(url, ".txt.gz"):
+		return fetchedAt.Add(monthlyArchiveDuration), true
+	case strings.Contains(url, "/dods/"):
+		return time.Time{}, true
+	}
+	return time.Time{}, false
+}
+
+// A Fetcher that stores responses in a Cache and serves them until the CachePolicy says they
+// are stale. Stale entries with an ETag or Last-Modified header are revalidated with the server
+// when the wrapped Fetcher is a ConditionalFetcher. Set Disabled to bypass the cache entirely.
+// Model responses that are error pages, which NOMADS serves with a successful status code, are
+// returned but never cached, so a run is fetched again once it is published.
+type CachingFetcher struct {
+	Fetcher  Fetcher
+	Cache    Cache
+	Policy   CachePolicy
+	Disabled bool
+
+	// Used to get the current time, time.Now when nil
+	now func() time.Time
+}
+
+// Creates a new CachingFetcher around the given fetcher using the DefaultCachePolicy
+func NewCachingFetcher(fetcher Fetcher, cache Cache) *CachingFetcher {
+	return &CachingFetcher{
+		Fetcher: fetcher,
+		Cache:   cache,
+		Policy:  DefaultCachePolicy,
+	}
+}
+
+// Creates a new Client whose downloads are cached on disk in the given directory
+func NewCachingClient(directory string) (*Client, error) {
+	cache, cacheErr := NewFileCache(directory)
+	if cacheErr != nil {
+		return nil, cacheErr
+	}
+	return NewClient(NewCachingFetcher(defaultFetcher, cache)), nil
+}
+
+func (c *CachingFetcher) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// Returns the cached data for the url if it is fresh, otherwise fetches and caches it
+func (c *CachingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if c.Disabled || c.Cache == nil {
+		return c.Fetcher.Fetch(ctx, url)
+	}
+
+	policy := c.Policy
+	if policy == nil {
+		policy = DefaultCachePolicy
+	}
+
+	now := c.currentTime()
+	if _, cacheable := policy(url, now); !cacheable {
+		return c.Fetcher.Fetch(ctx, url)
+	}
+
+	entry, found := c.Cache.Get(url)
+	if found && entry.IsFresh(now) {
+		return entry.Data, nil
+	}
+
+	validators := CacheValidators{}
+	if found && entry.HasValidators() {
+		validators.ETag = entry.ETag
+		validators.LastModified = entry.LastModified
+	}
+
+	response, fetchErr := c.fetchConditional(ctx, url, validators)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	if response.NotModified {
+		if !found {
+			// Nothing to fall back on, so the server should never do this
+			return c.Fetcher.Fetch(ctx, url)
+		}
+
+		entry.Expires, _ = policy(url, now)
+		c.Cache.Put(url, entry)
+		return entry.Data, nil
+	}
+
+	if !isCacheableResponse(url, response.Data) {
+		return response.Data, nil
+	}
+
+	newEntry := &CacheEntry{
+		URL:          url,
+		Data:         response.Data,
+		ETag:         response.ETag,
+		LastModified: response.LastModified,
+		FetchedAt:    now,
+	}
+	newEntry.Expires, _ = policy(url, now)
+
+	// A failure to write the cache should not fail the download
+	c.Cache.Put(url, newEntry)
+
+	return newEntry.Data, nil
+}
+
+// Returns if the response is real data rather than an error page served with a successful status
+func isCacheableResponse(url string, data []byte) bool {
+	if strings.Contains(url, "/dods/") {
+		return checkModelResponse(data, url) == nil
+	}
+	return true
+}
+
+// Makes a conditional request when the wrapped fetcher supports it, otherwise a plain one
+func (c *CachingFetcher) fetchConditional(ctx context.Context, url string, validators CacheValidators) (*ConditionalResponse, error) {
+	conditionalFetcher, ok := c.Fetcher.(ConditionalFetcher)
+	if !ok {
+		data, fetchErr := c.Fetcher.Fetch(ctx, url)
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return &ConditionalResponse{Data: data}, nil
+	}
+
+	return conditionalFetcher.FetchConditional(ctx, url, validators)
+}
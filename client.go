@@ -0,0 +1,88 @@
+package surfnerd
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultFetchTimeout = 30 * time.Second
+)
+
+// Holds the Fetcher used to download data from NOAA and the Endpoints the urls are built from.
+// Buoys, buoy station lists, and model fetches all go through a Client, so swapping the Fetcher
+// or Endpoints is enough to change how the whole library talks to the network. Models are chosen
+// for a location from the Models registry, or the DefaultModelRegistry when it is nil.
+type Client struct {
+	Fetcher   Fetcher
+	Endpoints Endpoints
+	Models    *ModelRegistry
+}
+
+var (
+	defaultFetcher Fetcher = NewRetryFetcher(NewHTTPFetcher(defaultFetchTimeout))
+
+	// The Client used by all of the package level fetch functions and by any Buoy or
+	// BuoyStations that does not have its own Client set. Its endpoints can be overridden
+	// with the SURFNERD_NDBC_URL and SURFNERD_NOMADS_URL environment variables.
+	DefaultClient = &Client{
+		Fetcher:   defaultFetcher,
+		Endpoints: LoadEndpointsFromEnv(),
+	}
+)
+
+// Creates a new Client that downloads through the given Fetcher from the default endpoints. Like the
+// DefaultClient, the endpoints can be overridden with the SURFNERD_NDBC_URL and SURFNERD_NOMADS_URL
+// environment variables.
+func NewClient(fetcher Fetcher) *Client {
+	return &Client{
+		Fetcher:   fetcher,
+		Endpoints: LoadEndpointsFromEnv(),
+	}
+}
+
+// Returns the Fetcher to use for this client, falling back on a retrying http
+// fetcher when none is set.
+func (c *Client) fetcher() Fetcher {
+	if c == nil || c.Fetcher == nil {
+		return defaultFetcher
+	}
+	return c.Fetcher
+}
+
+// Returns the endpoints to build urls with, filling in defaults for any that are empty
+func (c *Client) endpoints() Endpoints {
+	if c == nil {
+		return DefaultEndpoints()
+	}
+	return c.Endpoints.withDefaults()
+}
+
+// Returns the registry to choose models from, falling back on the DefaultModelRegistry
+func (c *Client) models() *ModelRegistry {
+	if c == nil || c.Models == nil {
+		return DefaultModelRegistry
+	}
+	return c.Models
+}
+
+// Fetch all of the active buoy stations from NOAA using this client. Every station
+// returned will also fetch its data through this client.
+func (c *Client) GetAllActiveBuoyStations(ctx context.Context) (*BuoyStations, error) {
+	stations := &BuoyStations{Client: c}
+	fetchErr := stations.GetAllActiveBuoyStationsContext(ctx)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return stations, nil
+}
+
+// Finds a buoy for a given identification string using this client. Returns
+// nil with no error if the station list does not contain the buoy.
+func (c *Client) GetBuoyByID(ctx context.Context, stationID string) (*Buoy, error) {
+	stations, fetchErr := c.GetAllActiveBuoyStations(ctx)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return stations.FindBuoyByID(stationID), nil
+}
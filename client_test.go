@@ -0,0 +1,137 @@
+package surfnerd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testActiveStationsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<stations created="2017-10-16T18:10:01UTC" count="2">
+<station id="44097" lat="40.967" lon="-71.126" elev="0" name="Block Island, RI" owner="Scripps" pgm="IOOS Partners" type="buoy" met="n" currents="n" waterquality="n" dart="n"/>
+<station id="44017" lat="40.694" lon="-72.048" elev="0" name="MONTAUK POINT" owner="NDBC" pgm="NDBC Meteorological/Ocean" type="buoy" met="y" currents="n" waterquality="n" dart="n"/>
+</stations>`
+
+const testRealtimeListing = `<html><body><pre>
+<a href="44017.data_spec">44017.data_spec</a> 16-Oct-2017 18:40  41K
+<a href="44017.swdir">44017.swdir</a>         16-Oct-2017 18:40  32K
+<a href="44017.txt">44017.txt</a>             16-Oct-2017 18:40  220K
+<a href="44097.txt">44097.txt</a>             16-Oct-2017 18:40  198K
+</pre></body></html>`
+
+const testStandardData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+2017 10 16 18 50 230  7.0  9.0   1.1     8   5.4 190 1016.1  18.2  19.1  14.0   MM -1.2    MM
+2017 10 16 17 50 220  6.0  8.0   1.0     8   5.2 185 1017.3  18.0  19.0  13.8   MM -1.0    MM
+`
+
+// Creates a client that serves the canned NDBC responses from a local test server
+func newTestNDBCClient() (*Client, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "activestations.xml"):
+			fmt.Fprint(w, testActiveStationsXML)
+		case strings.HasSuffix(r.URL.Path, "/data/realtime2/"):
+			fmt.Fprint(w, testRealtimeListing)
+		case strings.HasSuffix(r.URL.Path, "44017.txt"):
+			fmt.Fprint(w, testStandardData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	client := NewClient(&HTTPFetcher{Client: server.Client()})
+	client.Endpoints = Endpoints{NDBC: server.URL, NOMADS: server.URL}
+	return client, server.Close
+}
+
+func TestClientFetchesStationsOffline(t *testing.T) {
+	client, closeServer := newTestNDBCClient()
+	defer closeServer()
+
+	buoy, fetchErr := client.GetBuoyByID(context.Background(), "44017")
+	if fetchErr != nil {
+		fmt.Println("Failed to fetch the station list from the test server")
+		t.FailNow()
+	}
+	if buoy == nil || buoy.Client != client {
+		fmt.Println("The buoy did not inherit the client of the station list")
+		t.FailNow()
+	}
+	if !buoy.DoesBuoyHaveSpectralData() {
+		fmt.Println("The buoy with a spectral energy file was not marked as reporting spectra")
+		t.FailNow()
+	}
+
+	otherBuoy, _ := client.GetBuoyByID(context.Background(), "44097")
+	if otherBuoy == nil || otherBuoy.DoesBuoyHaveSpectralData() {
+		fmt.Println("The buoy without a spectral energy file was marked as reporting spectra")
+		t.FailNow()
+	}
+
+	fetchErr = buoy.FetchStandardDataContext(context.Background(), -1)
+	if fetchErr != nil {
+		fmt.Println("Failed to fetch the standard data from the test server")
+		t.FailNow()
+	}
+	if len(buoy.BuoyData) != 2 {
+		fmt.Println("Wrong number of standard data items parsed")
+		t.FailNow()
+	}
+}
+
+func TestClientContextCancellation(t *testing.T) {
+	client, closeServer := newTestNDBCClient()
+	defer closeServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, fetchErr := client.GetAllActiveBuoyStations(ctx)
+	if fetchErr == nil {
+		fmt.Println("Expected a cancelled context to fail the fetch")
+		t.FailNow()
+	}
+}
+
+func TestEndpointsFromFile(t *testing.T) {
+	configFile, _ := ioutil.TempFile("", "surfnerd-endpoints")
+	defer os.Remove(configFile.Name())
+	configFile.WriteString(`{"ndbc": "https://ndbc.mirror.local/"}`)
+	configFile.Close()
+
+	endpoints, loadErr := LoadEndpointsFromFile(configFile.Name())
+	if loadErr != nil {
+		fmt.Println("Failed to load the endpoints file")
+		t.FailNow()
+	}
+
+	buoy := Buoy{StationID: "44097", Client: &Client{Endpoints: endpoints}}
+	if buoy.CreateStandardDataURL() != "https://ndbc.mirror.local/data/realtime2/44097.txt" {
+		fmt.Println("Buoy url was not built from the configured endpoint")
+		t.Fail()
+	}
+
+	model := NewEastCoastWaveModel()
+	url := model.CreateURLWithEndpoints(endpoints, NewLocationForLatLong(41.0, 288.5), 0, 1)
+	if !strings.HasPrefix(url, "http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/") {
+		fmt.Println("The default NOMADS endpoint should be used when none is configured")
+		t.Fail()
+	}
+}
+
+func TestNewClientUsesEnvironmentEndpoints(t *testing.T) {
+	os.Setenv(NOMADSEndpointEnvironmentVariable, "https://nomads.mirror.local/")
+	defer os.Unsetenv(NOMADSEndpointEnvironmentVariable)
+
+	client := NewClient(&HTTPFetcher{})
+	if client.Endpoints.NOMADS != "https://nomads.mirror.local" || client.Endpoints.NDBC != defaultNDBCEndpoint {
+		fmt.Println("New clients should honor the endpoint environment variables like the DefaultClient")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,103 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Holds a 10 minute continuous wind measurement. Once an hour the item also holds the highest
+// gust of the hour and the time it happened, otherwise those are missing and GustTime is zero.
+// Speeds are in meters per second and directions are in degrees the wind is coming from. More
+// info is available here http://www.ndbc.noaa.gov/measdes.shtml#cwind
+type ContinuousWindItem struct {
+	Date time.Time
+
+	// The average wind over the 10 minutes ending at Date
+	WindDirection float64
+	WindSpeed     float64
+
+	// The highest 5 second gust of the hour
+	GustDirection float64
+	WindGust      float64
+	GustTime      time.Time
+}
+
+// Creates and returns the url for fetching the buoys continuous wind data.
+// The url returns tab delimited ascii data.
+func (b Buoy) CreateContinuousWindDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, continuousWindPostfix)
+}
+
+// Parses the contents of a NDBC continuous wind (.cwind) file into a time series of ContinuousWindItem
+// objects. Input a negative integer to parse all available data points. A malformed line is reported as
+// a *ParseError and leaves the ContinuousWindData untouched.
+func (b *Buoy) ParseRawContinuousWindData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
+	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
+		dataLineCount = dataCountLimit
+	}
+
+	windData := make([]ContinuousWindItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
+		item := ContinuousWindItem{Date: values.date()}
+		item.WindDirection = values.float("WDIR", directionSentinel)
+		item.WindSpeed = values.float("WSPD", speedSentinel)
+		item.GustDirection = values.float("GDR", directionSentinel)
+		item.WindGust = values.float("GST", speedSentinel)
+		item.GustTime = values.clockTime("GTIME", item.Date)
+
+		if values.err != nil {
+			return values.err
+		}
+		windData[itemIndex] = item
+	}
+
+	b.ContinuousWindData = windData
+	return nil
+}
+
+// Grabs the latest continuous wind data as a time series of ContinuousWindItem objects. Input a
+// negative integer to download all available data points.
+func (b *Buoy) FetchContinuousWindData(dataCountLimit int) error {
+	return b.FetchContinuousWindDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchContinuousWindData, but the download is bound to the given context
+func (b *Buoy) FetchContinuousWindDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchErr := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateContinuousWindDataURL())
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawContinuousWindData(string(rawData), dataCountLimit)
+}
+
+// Encodes the item as json, leaving out missing measurements
+func (c ContinuousWindItem) MarshalJSON() ([]byte, error) {
+	type continuousWindItemJSON ContinuousWindItem
+	return marshalOmittingMissing(continuousWindItemJSON(c))
+}
+
+// Decodes the item from json. Measurements left out of the json are missing.
+func (c *ContinuousWindItem) UnmarshalJSON(data []byte) error {
+	type continuousWindItemJSON ContinuousWindItem
+	item := continuousWindItemJSON{}
+	markFloatsMissing(&item)
+
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*c = ContinuousWindItem(item)
+	return nil
+}
@@ -0,0 +1,235 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// A current measurement over one depth bin of an acoustic doppler current profiler. Depth is in
+// meters below the surface, Speed is in meters per second, and Direction is the direction in degrees
+// the water is flowing towards, which is the opposite of the convention used for wind and waves.
+type CurrentBin struct {
+	Depth     float64
+	Speed     float64
+	Direction float64
+}
+
+// The currents measured by a buoys ADCP at each depth bin for a given time. Bins are ordered from
+// the surface down, and bins the profiler did not report have missing values. More info is available
+// here http://www.ndbc.noaa.gov/measdes.shtml#adcp
+type CurrentProfile struct {
+	Date time.Time
+	Bins []CurrentBin
+}
+
+// Creates and returns the url for fetching the buoys ADCP current data.
+// The url returns tab delimited ascii data.
+func (b Buoy) CreateCurrentDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, currentDataPostfix)
+}
+
+// Creates and returns the url for fetching the current data of buoys with the newer ADCP
+// instruments, which report one line per depth bin. The url returns tab delimited ascii data.
+func (b Buoy) CreateDetailedCurrentDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, detailedCurrentDataPostfix)
+}
+
+// Parses the contents of a NDBC current data file into a time series of CurrentProfile objects. Both
+// the .adcp files, with every bin of a time on one line, and the .adcp2 files, with one line per bin,
+// are understood. Input a negative integer to parse all available profiles. A malformed line is reported
+// as a *ParseError and leaves the CurrentProfiles untouched.
+func (b *Buoy) ParseRawCurrentData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	var profiles []CurrentProfile
+	var parseErr error
+	if table.hasColumn("Bin") {
+		profiles, parseErr = parseBinnedCurrentTable(table)
+	} else {
+		profiles, parseErr = parseWideCurrentTable(table)
+	}
+	if parseErr != nil {
+		return parseErr
+	}
+
+	if dataCountLimit < len(profiles) && dataCountLimit >= 0 {
+		profiles = profiles[:dataCountLimit]
+	}
+
+	b.CurrentProfiles = profiles
+	return nil
+}
+
+// Parses a .adcp table, where each line holds the DEPnn, DIRnn and SPDnn columns of every bin
+func parseWideCurrentTable(table *ndbcTable) ([]CurrentProfile, error) {
+	binCount := 0
+	for table.hasColumn(fmt.Sprintf("DEP%02d", binCount+1)) {
+		binCount++
+	}
+	if binCount == 0 {
+		return nil, &ParseError{Line: 1, Column: "DEP01", Err: ErrMissingColumn}
+	}
+
+	profiles := make([]CurrentProfile, len(table.Rows))
+	for profileIndex, row := range table.Rows {
+		values := table.reader(row)
+		profile := CurrentProfile{Date: values.date(), Bins: make([]CurrentBin, binCount)}
+		for binIndex, _ := range profile.Bins {
+			binNumber := binIndex + 1
+			profile.Bins[binIndex] = CurrentBin{
+				Depth:     values.float(fmt.Sprintf("DEP%02d", binNumber), depthSentinel),
+				Direction: values.float(fmt.Sprintf("DIR%02d", binNumber), directionSentinel),
+				Speed:     currentSpeed(values.float(fmt.Sprintf("SPD%02d", binNumber), currentSpeedSentinel)),
+			}
+		}
+
+		if values.err != nil {
+			return nil, values.err
+		}
+		profiles[profileIndex] = profile
+	}
+
+	return profiles, nil
+}
+
+// Converts a current speed from the cm/s NDBC reports to m/s, keeping missing speeds missing
+func currentSpeed(centimetersPerSecond float64) float64 {
+	if IsMissing(centimetersPerSecond) {
+		return MissingValue()
+	}
+	return centimetersPerSecond / 100.0
+}
+
+// Parses a .adcp2 table, where each line holds one bin and consecutive lines with the same time
+// make up a profile
+func parseBinnedCurrentTable(table *ndbcTable) ([]CurrentProfile, error) {
+	if columnErr := table.requireColumns("Depth", "Dir", "Speed"); columnErr != nil {
+		return nil, columnErr
+	}
+
+	profiles := []CurrentProfile{}
+	for _, row := range table.Rows {
+		values := table.reader(row)
+		date := values.date()
+		bin := CurrentBin{
+			Depth:     values.float("Depth", depthSentinel),
+			Direction: values.float("Dir", directionSentinel),
+			Speed:     currentSpeed(values.float("Speed", currentSpeedSentinel)),
+		}
+		if values.err != nil {
+			return nil, values.err
+		}
+
+		if len(profiles) == 0 || !profiles[len(profiles)-1].Date.Equal(date) {
+			profiles = append(profiles, CurrentProfile{Date: date})
+		}
+		lastProfile := &profiles[len(profiles)-1]
+		lastProfile.Bins = append(lastProfile.Bins, bin)
+	}
+
+	return profiles, nil
+}
+
+// Grabs the latest ADCP current profiles as a time series of CurrentProfile objects. The .adcp file is
+// tried first, then the .adcp2 file used by stations with the newer instruments. Input a negative integer
+// to download all available profiles.
+func (b *Buoy) FetchCurrentProfiles(dataCountLimit int) error {
+	return b.FetchCurrentProfilesContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchCurrentProfiles, but the downloads are bound to the given context
+func (b *Buoy) FetchCurrentProfilesContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchErr := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateCurrentDataURL())
+	if isNotFoundError(fetchErr) {
+		rawData, fetchErr = fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateDetailedCurrentDataURL())
+	}
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawCurrentData(string(rawData), dataCountLimit)
+}
+
+// Get the shallowest bin of the profile with a current reported. Returns an error if no bin
+// has a current.
+func (c CurrentProfile) SurfaceCurrent() (CurrentBin, error) {
+	for _, bin := range c.Bins {
+		if !IsMissing(bin.Depth) && !IsMissing(bin.Speed) && !IsMissing(bin.Direction) {
+			return bin, nil
+		}
+	}
+	return CurrentBin{}, errors.New("No current reported in the profile")
+}
+
+// Get the current at the given depth in meters, interpolated between the reported bins around it. The
+// speed and direction are interpolated as a vector so the current is not smeared when it turns with depth.
+// Depths above the shallowest bin or below the deepest bin get the current of that bin.
+func (c CurrentProfile) CurrentAtDepth(depth float64) (CurrentBin, error) {
+	bins := []CurrentBin{}
+	for _, bin := range c.Bins {
+		if !IsMissing(bin.Depth) && !IsMissing(bin.Speed) && !IsMissing(bin.Direction) {
+			bins = append(bins, bin)
+		}
+	}
+	if len(bins) == 0 {
+		return CurrentBin{}, errors.New("No current reported in the profile")
+	}
+
+	if depth <= bins[0].Depth {
+		return CurrentBin{Depth: depth, Speed: bins[0].Speed, Direction: bins[0].Direction}, nil
+	}
+	for index := 1; index < len(bins); index++ {
+		upper, lower := bins[index-1], bins[index]
+		if depth > lower.Depth {
+			continue
+		}
+
+		fraction := (depth - upper.Depth) / (lower.Depth - upper.Depth)
+		upperEast, upperNorth := currentComponents(upper)
+		lowerEast, lowerNorth := currentComponents(lower)
+		east := upperEast + fraction*(lowerEast-upperEast)
+		north := upperNorth + fraction*(lowerNorth-upperNorth)
+
+		direction := normalizeDirection(math.Atan2(east, north) * 180.0 / math.Pi)
+		return CurrentBin{Depth: depth, Speed: math.Hypot(east, north), Direction: direction}, nil
+	}
+
+	deepest := bins[len(bins)-1]
+	return CurrentBin{Depth: depth, Speed: deepest.Speed, Direction: deepest.Direction}, nil
+}
+
+// Splits the current of a bin into its east and north flowing components
+func currentComponents(bin CurrentBin) (east, north float64) {
+	directionRad := bin.Direction * math.Pi / 180.0
+	return bin.Speed * math.Sin(directionRad), bin.Speed * math.Cos(directionRad)
+}
+
+// Encodes the bin as json, leaving out missing measurements
+func (c CurrentBin) MarshalJSON() ([]byte, error) {
+	type currentBinJSON CurrentBin
+	return marshalOmittingMissing(currentBinJSON(c))
+}
+
+// Decodes the bin from json. Measurements left out of the json are missing.
+func (c *CurrentBin) UnmarshalJSON(data []byte) error {
+	type currentBinJSON CurrentBin
+	bin := currentBinJSON{}
+	markFloatsMissing(&bin)
+
+	jsonErr := json.Unmarshal(data, &bin)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*c = CurrentBin(bin)
+	return nil
+}
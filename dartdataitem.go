@@ -0,0 +1,125 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// How a DART station took a water column height reading
+type DartMeasurementType int
+
+const (
+	// Readings taken every 15 minutes while the station is in standard mode
+	DartStandardMeasurement DartMeasurementType = 1
+
+	// Readings taken every minute after the station is triggered into event mode
+	DartEventMeasurement DartMeasurementType = 2
+
+	// Readings taken every 15 seconds at the start of an event
+	DartEventFineMeasurement DartMeasurementType = 3
+)
+
+// Returns if the reading was taken while the station was in event mode
+func (d DartMeasurementType) IsEventMode() bool {
+	return d == DartEventMeasurement || d == DartEventFineMeasurement
+}
+
+// A water column height reading from a DART tsunami station. The height is the depth of the water
+// above the bottom pressure recorder in meters. More info is available here
+// http://www.ndbc.noaa.gov/measdes.shtml#dart
+type DartDataItem struct {
+	Date              time.Time
+	MeasurementType   DartMeasurementType
+	WaterColumnHeight float64
+}
+
+// Encodes the item as json, leaving out a missing height
+func (d DartDataItem) MarshalJSON() ([]byte, error) {
+	type dartDataItemJSON DartDataItem
+	return marshalOmittingMissing(dartDataItemJSON(d))
+}
+
+// Decodes the item from json. A height left out of the json is missing.
+func (d *DartDataItem) UnmarshalJSON(data []byte) error {
+	type dartDataItemJSON DartDataItem
+	item := dartDataItemJSON{}
+	markFloatsMissing(&item)
+
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*d = DartDataItem(item)
+	return nil
+}
+
+// Creates and returns the url for fetching the water column heights of a DART station.
+// The url returns tab delimited ascii data.
+func (b Buoy) CreateDartDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, dartDataPostfix)
+}
+
+// Parses the contents of a NDBC DART (.dart) file into a time series of DartDataItem objects. Input a
+// negative integer to parse all available data points. A malformed line is reported as a *ParseError
+// and leaves the DartData untouched.
+func (b *Buoy) ParseRawDartData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh", "T", "HEIGHT"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
+	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
+		dataLineCount = dataCountLimit
+	}
+
+	dartData := make([]DartDataItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
+		item := DartDataItem{Date: values.date()}
+		item.WaterColumnHeight = values.float("HEIGHT", pressureSentinel)
+
+		rawType := values.text("T")
+		measurementType, typeErr := strconv.Atoi(rawType)
+		if typeErr != nil || measurementType < 1 || measurementType > 3 {
+			return &ParseError{Line: row.Line, Column: "T", Value: rawType, Err: ErrInvalidValue}
+		}
+		item.MeasurementType = DartMeasurementType(measurementType)
+
+		if values.err != nil {
+			return values.err
+		}
+		dartData[itemIndex] = item
+	}
+
+	b.DartData = dartData
+	return nil
+}
+
+// Grabs the latest water column heights of a DART station as a time series of DartDataItem objects.
+// Input a negative integer to download all available data points.
+func (b *Buoy) FetchDartData(dataCountLimit int) error {
+	return b.FetchDartDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchDartData, but the download is bound to the given context
+func (b *Buoy) FetchDartDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchErr := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateDartDataURL())
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawDartData(string(rawData), dataCountLimit)
+}
+
+// Compares the event mode readings of the DartData with a tidal baseline fitted to the standard
+// readings, and returns the readings that differ from the tide by more than the threshold in meters.
+// Fetch at least a few days of data so the tide can be fitted.
+func (b Buoy) DetectDartAnomalies(threshold float64) ([]DartAnomaly, error) {
+	return DetectDartAnomalies(b.DartData, threshold)
+}
@@ -0,0 +1,193 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+// The method used to estimate the directional distribution at each frequency from the
+// four directional Fourier coefficients measured by a buoy
+type DirectionalSpreadingMethod string
+
+const (
+	// Maximum Entropy Method of Lygre & Krogstad (1986). Resolves narrow and bimodal seas
+	// much better than the truncated Fourier series and never produces negative energy.
+	MaximumEntropyMethod DirectionalSpreadingMethod = "mem"
+
+	// The truncated Fourier series of Longuet-Higgins et al (1963). It is smooth and broad,
+	// and any negative lobes it produces are clipped to zero.
+	FourierSeriesMethod DirectionalSpreadingMethod = "fourier"
+)
+
+var (
+	// Returned when a directional spectrum is requested from spectra without all four coefficients
+	ErrNoDirectionalCoefficients = errors.New("Spectra does not have the directional coefficients needed for a directional spectrum")
+
+	// Returned when a directional spectrum is requested with an unknown spreading method
+	ErrUnknownSpreadingMethod = errors.New("Unknown directional spreading method")
+)
+
+// A frequency-direction wave energy spectrum S(f, theta). Directions are the direction waves are
+// coming from in degrees and are evenly spaced around the compass starting at 0. Energy is indexed
+// by frequency then direction and is in m^2/Hz/degree, so summing Energy * direction step * frequency
+// bandwidth gives the zero spectral moment.
+type DirectionalSpectrum struct {
+	Frequencies []float64
+	Directions  []float64
+	Energy      [][]float64
+}
+
+// Creates the directional spectrum from the energy and directional coefficients of the spectra, using
+// the given spreading method and a direction bin width close to directionStep degrees. Frequencies without
+// r1 or alpha1 have their energy spread evenly in all directions, and frequencies without r2 or alpha2
+// are spread using only the first order coefficients.
+func (b BuoySpectraItem) DirectionalSpectrum(method DirectionalSpreadingMethod, directionStep float64) (*DirectionalSpectrum, error) {
+	if !b.HasDirectionalCoefficients() || len(b.Energies) != len(b.Frequencies) {
+		return nil, ErrNoDirectionalCoefficients
+	} else if directionStep <= 0 || directionStep > 180 {
+		return nil, errors.New("Direction step must be between 0 and 180 degrees")
+	}
+
+	var spreading func(a1, b1, a2, b2, theta float64) float64
+	switch method {
+	case MaximumEntropyMethod:
+		spreading = maximumEntropySpreading
+	case FourierSeriesMethod:
+		spreading = fourierSeriesSpreading
+	default:
+		return nil, ErrUnknownSpreadingMethod
+	}
+
+	directionCount := int(math.Round(360.0 / directionStep))
+	directionStep = 360.0 / float64(directionCount)
+
+	spectrum := &DirectionalSpectrum{
+		Frequencies: append([]float64{}, b.Frequencies...),
+		Directions:  make([]float64, directionCount),
+		Energy:      make([][]float64, len(b.Frequencies)),
+	}
+	for directionIndex, _ := range spectrum.Directions {
+		spectrum.Directions[directionIndex] = float64(directionIndex) * directionStep
+	}
+
+	for frequencyIndex, energy := range b.Energies {
+		row := make([]float64, directionCount)
+		spectrum.Energy[frequencyIndex] = row
+		if IsMissing(energy) {
+			for directionIndex, _ := range row {
+				row[directionIndex] = MissingValue()
+			}
+			continue
+		}
+
+		a1, b1, a2, b2 := b.fourierCoefficients(frequencyIndex)
+
+		// Normalize numerically so the distribution integrates to one over the discrete directions
+		total := 0.0
+		for directionIndex, direction := range spectrum.Directions {
+			row[directionIndex] = spreading(a1, b1, a2, b2, direction*math.Pi/180.0)
+			total += row[directionIndex] * directionStep
+		}
+		for directionIndex, _ := range row {
+			if total > 0 {
+				row[directionIndex] *= energy / total
+			} else {
+				row[directionIndex] = energy / 360.0
+			}
+		}
+	}
+
+	return spectrum, nil
+}
+
+// Get the a1, b1, a2 and b2 Fourier coefficients at a frequency from the alpha and r values, replacing
+// missing values with coefficients that carry no directional information
+func (b BuoySpectraItem) fourierCoefficients(index int) (a1, b1, a2, b2 float64) {
+	alpha1 := b.Angles[index] * math.Pi / 180.0
+	alpha2 := b.Alpha2[index] * math.Pi / 180.0
+	r1 := b.R1[index]
+	r2 := b.R2[index]
+	if IsMissing(alpha1) || IsMissing(r1) {
+		return 0, 0, 0, 0
+	}
+
+	a1 = r1 * math.Cos(alpha1)
+	b1 = r1 * math.Sin(alpha1)
+	if IsMissing(alpha2) || IsMissing(r2) {
+		return a1, b1, 0, 0
+	}
+
+	a2 = r2 * math.Cos(2.0*alpha2)
+	b2 = r2 * math.Sin(2.0*alpha2)
+	return
+}
+
+// The unnormalized Longuet-Higgins directional distribution at theta radians, clipped at zero
+func fourierSeriesSpreading(a1, b1, a2, b2, theta float64) float64 {
+	distribution := (0.5 + a1*math.Cos(theta) + b1*math.Sin(theta) + a2*math.Cos(2.0*theta) + b2*math.Sin(2.0*theta)) / math.Pi
+	return math.Max(distribution, 0)
+}
+
+// The unnormalized Lygre & Krogstad maximum entropy directional distribution at theta radians
+func maximumEntropySpreading(a1, b1, a2, b2, theta float64) float64 {
+	c1 := complex(a1, b1)
+	c2 := complex(a2, b2)
+
+	// A perfectly unidirectional first coefficient makes the estimator singular, so keep it just inside
+	if magnitude := cmplx.Abs(c1); magnitude > 0.999 {
+		c1 *= complex(0.999/magnitude, 0)
+	}
+
+	phi1 := (c1 - c2*cmplx.Conj(c1)) / complex(1.0-math.Pow(cmplx.Abs(c1), 2), 0)
+	phi2 := c2 - c1*phi1
+
+	numerator := real(1.0 - phi1*cmplx.Conj(c1) - phi2*cmplx.Conj(c2))
+	denominator := math.Pow(cmplx.Abs(1.0-phi1*cmplx.Exp(complex(0, -theta))-phi2*cmplx.Exp(complex(0, -2.0*theta))), 2)
+	if denominator == 0 {
+		return 0
+	}
+	return math.Max(numerator/(2.0*math.Pi*denominator), 0)
+}
+
+// Get the width of each direction bin in degrees
+func (d DirectionalSpectrum) directionStep() float64 {
+	if len(d.Directions) == 0 {
+		return 0
+	}
+	return 360.0 / float64(len(d.Directions))
+}
+
+// Get the energy, or zero spectral moment, in m^2 of the waves arriving from within the
+// window running clockwise from the from direction to the to direction in degrees. A direction
+// bin is counted when its center is inside the window, which includes the from edge but not the
+// to edge, so adjacent windows never count a bin twice. A window of 0 to 360 covers every
+// direction. Frequencies with missing energy are skipped.
+func (d DirectionalSpectrum) EnergyWithinDirections(from, to float64) float64 {
+	width := normalizeDirection(to - from)
+	if width == 0 && to != from {
+		width = 360.0
+	}
+
+	directionStep := d.directionStep()
+	zeroMoment := 0.0
+	for frequencyIndex, row := range d.Energy {
+		bandwidth := frequencyBandwidth(d.Frequencies, frequencyIndex)
+		for directionIndex, energy := range row {
+			if IsMissing(energy) {
+				continue
+			}
+
+			if normalizeDirection(d.Directions[directionIndex]-from) < width {
+				zeroMoment += energy * directionStep * bandwidth
+			}
+		}
+	}
+	return zeroMoment
+}
+
+// Get the significant wave height in meters of the waves arriving from within the window
+// running clockwise from the from direction to the to direction in degrees
+func (d DirectionalSpectrum) WaveHeightWithinDirections(from, to float64) float64 {
+	return 4.0 * math.Sqrt(d.EnergyWithinDirections(from, to))
+}
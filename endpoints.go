@@ -0,0 +1,87 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	defaultNDBCEndpoint   = "http://www.ndbc.noaa.gov"
+	defaultNOMADSEndpoint = "http://nomads.ncep.noaa.gov:9090"
+
+	// Environment variables that override the default endpoints
+	NDBCEndpointEnvironmentVariable   = "SURFNERD_NDBC_URL"
+	NOMADSEndpointEnvironmentVariable = "SURFNERD_NOMADS_URL"
+)
+
+// The base urls of the NOAA servers the library downloads from. NDBC serves the buoy data and
+// NOMADS serves the model data. Every url is built from one of these, so they can be pointed at
+// an https host, an internal mirror, or a local stand-in server for integration tests. Empty
+// values fall back on the public NOAA servers.
+type Endpoints struct {
+	NDBC   string `json:"ndbc"`
+	NOMADS string `json:"nomads"`
+}
+
+// Get the endpoints of the public NOAA servers
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		NDBC:   defaultNDBCEndpoint,
+		NOMADS: defaultNOMADSEndpoint,
+	}
+}
+
+// Load the endpoints from a json file in the form {"ndbc": "https://...", "nomads": "https://..."}.
+// Any endpoint missing from the file keeps its default value.
+func LoadEndpointsFromFile(filename string) (Endpoints, error) {
+	rawData, readErr := ioutil.ReadFile(filename)
+	if readErr != nil {
+		return DefaultEndpoints(), readErr
+	}
+
+	endpoints := Endpoints{}
+	jsonErr := json.Unmarshal(rawData, &endpoints)
+	if jsonErr != nil {
+		return DefaultEndpoints(), jsonErr
+	}
+
+	return endpoints.withDefaults(), nil
+}
+
+// Load the endpoints from the SURFNERD_NDBC_URL and SURFNERD_NOMADS_URL environment variables.
+// Any variable that is not set keeps its default value.
+func LoadEndpointsFromEnv() Endpoints {
+	endpoints := Endpoints{
+		NDBC:   os.Getenv(NDBCEndpointEnvironmentVariable),
+		NOMADS: os.Getenv(NOMADSEndpointEnvironmentVariable),
+	}
+	return endpoints.withDefaults()
+}
+
+// Fills in any empty endpoint with its default value and strips trailing slashes
+func (e Endpoints) withDefaults() Endpoints {
+	defaults := DefaultEndpoints()
+	if e.NDBC == "" {
+		e.NDBC = defaults.NDBC
+	}
+	if e.NOMADS == "" {
+		e.NOMADS = defaults.NOMADS
+	}
+
+	e.NDBC = strings.TrimRight(e.NDBC, "/")
+	e.NOMADS = strings.TrimRight(e.NOMADS, "/")
+	return e
+}
+
+// Creates a NDBC url from a path format and its arguments
+func (e Endpoints) ndbcURL(pathFormat string, args ...interface{}) string {
+	return e.withDefaults().NDBC + fmt.Sprintf(pathFormat, args...)
+}
+
+// Creates a NOMADS url from a path format and its arguments
+func (e Endpoints) nomadsURL(pathFormat string, args ...interface{}) string {
+	return e.withDefaults().NOMADS + fmt.Sprintf(pathFormat, args...)
+}
@@ -0,0 +1,94 @@
+package surfnerd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// Returned when NDBC does not have the requested data file for a station
+	ErrStationDataNotFound = errors.New("No data found for the requested buoy station")
+
+	// Returned when NOMADS does not (yet) serve the requested model run
+	ErrModelRunNotPublished = errors.New("The requested model run has not been published")
+)
+
+// Describes a response from a NOAA server that did not have a successful status code
+type HTTPError struct {
+	StatusCode int
+	URL        string
+}
+
+func (h *HTTPError) Error() string {
+	return fmt.Sprintf("Received HTTP status %d (%s) from %s", h.StatusCode, http.StatusText(h.StatusCode), h.URL)
+}
+
+// Returns if the error is likely to go away when the request is retried
+func (h *HTTPError) Temporary() bool {
+	return h.StatusCode >= 500 || h.StatusCode == http.StatusTooManyRequests
+}
+
+// Returns if the error means the resource does not exist on the server
+func isNotFoundError(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusGone
+	}
+	return false
+}
+
+// An error of one of the kinds the package returns, such as ErrStationDataNotFound, caused by
+// another error. Both can be checked for with errors.Is and errors.As.
+type wrappedError struct {
+	kind  error
+	cause error
+}
+
+func (w *wrappedError) Error() string {
+	return w.kind.Error() + ": " + w.cause.Error()
+}
+
+func (w *wrappedError) Is(target error) bool {
+	return target == w.kind
+}
+
+func (w *wrappedError) Unwrap() error {
+	return w.cause
+}
+
+// Wraps an error from a buoy data download so a missing file can be checked
+// for with errors.Is(err, ErrStationDataNotFound)
+func stationFetchError(err error) error {
+	if isNotFoundError(err) {
+		return &wrappedError{kind: ErrStationDataNotFound, cause: err}
+	}
+	return err
+}
+
+// Wraps an error from a model data download so a missing model run can be checked
+// for with errors.Is(err, ErrModelRunNotPublished)
+func modelFetchError(err error) error {
+	if isNotFoundError(err) {
+		return &wrappedError{kind: ErrModelRunNotPublished, cause: err}
+	}
+	return err
+}
+
+// The GrADS data server answers requests for unpublished model runs with a successful
+// status code and an error message as the body, so the body has to be checked as well.
+func checkModelResponse(rawData []byte, url string) error {
+	trimmed := bytes.TrimSpace(rawData)
+	switch {
+	case len(trimmed) == 0:
+		return fmt.Errorf("%w: empty response from %s", ErrModelRunNotPublished, url)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return fmt.Errorf("%w: unexpected html response from %s", ErrModelRunNotPublished, url)
+	case bytes.Contains(trimmed, []byte("is not an available dataset")):
+		return fmt.Errorf("%w: %s", ErrModelRunNotPublished, url)
+	case bytes.HasPrefix(trimmed, []byte("Error")):
+		return fmt.Errorf("%w: %s", ErrModelRunNotPublished, url)
+	}
+	return nil
+}
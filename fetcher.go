@@ -0,0 +1,117 @@
+package surfnerd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// A Fetcher downloads the raw contents found at a url. Every NOAA download made by the library
+// goes through a Fetcher so the transport can be replaced, wrapped, or stubbed out with canned
+// data for offline testing.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+// Adapts an ordinary function to the Fetcher interface
+type FetcherFunc func(ctx context.Context, url string) ([]byte, error)
+
+// Calls the wrapped function
+func (f FetcherFunc) Fetch(ctx context.Context, url string) ([]byte, error) {
+	return f(ctx, url)
+}
+
+// Validators from an earlier response that let the server answer with
+// "not modified" instead of sending the data again
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// The result of a conditional fetch. When NotModified is set, Data is empty and the
+// previously fetched data is still current.
+type ConditionalResponse struct {
+	Data         []byte
+	ETag         string
+	LastModified string
+	NotModified  bool
+}
+
+// A Fetcher that can also revalidate previously fetched data using ETag and
+// If-Modified-Since headers.
+type ConditionalFetcher interface {
+	Fetcher
+	FetchConditional(ctx context.Context, url string, validators CacheValidators) (*ConditionalResponse, error)
+}
+
+// Fetches data over http using the given http.Client. If no client is set the
+// http.DefaultClient is used.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// Creates a new HTTPFetcher whose requests are limited to the given timeout. A timeout
+// of zero means requests never time out on their own.
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	return &HTTPFetcher{
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Performs a GET request for the given url, bound to the given context. Responses without
+// a successful status code are returned as an *HTTPError.
+func (h *HTTPFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	response, fetchErr := h.FetchConditional(ctx, url, CacheValidators{})
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return response.Data, nil
+}
+
+// Performs a conditional GET request for the given url, sending the given validators so
+// the server can answer with 304 Not Modified when the data has not changed.
+func (h *HTTPFetcher) FetchConditional(ctx context.Context, url string, validators CacheValidators) (*ConditionalResponse, error) {
+	request, requestErr := http.NewRequest("GET", url, nil)
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	request = request.WithContext(ctx)
+
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	httpClient := h.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, httpErr := httpClient.Do(request)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	defer response.Body.Close()
+
+	result := &ConditionalResponse{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
+	} else if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &HTTPError{StatusCode: response.StatusCode, URL: url}
+	}
+
+	data, readErr := ioutil.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, readErr
+	}
+	result.Data = data
+	return result, nil
+}
@@ -0,0 +1,337 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Paths are relative to the NDBC endpoint of the buoys client
+const (
+	baseHistoricalDataURL = "/data/historical/%s/%s%s%d.txt.gz"
+	baseMonthlyDataURL    = "/data/%s/%s/%s%s%d.txt.gz"
+)
+
+// A NDBC archive dataset. Every station has a yearly file for each dataset it reports once
+// the year is over, and monthly files for the months of the current year that have been
+// quality controlled.
+type HistoricalDataset string
+
+const (
+	StandardMeteorologicalDataset HistoricalDataset = "stdmet"
+	SpectralWaveDensityDataset    HistoricalDataset = "swden"
+	SpectralWaveAlpha1Dataset     HistoricalDataset = "swdir"
+	SpectralWaveAlpha2Dataset     HistoricalDataset = "swdir2"
+	SpectralWaveR1Dataset         HistoricalDataset = "swr1"
+	SpectralWaveR2Dataset         HistoricalDataset = "swr2"
+)
+
+// The letter NDBC puts between the station id and the year in the archive file names
+var historicalDatasetFileCodes = map[HistoricalDataset]string{
+	StandardMeteorologicalDataset: "h",
+	SpectralWaveDensityDataset:    "w",
+	SpectralWaveAlpha1Dataset:     "d",
+	SpectralWaveAlpha2Dataset:     "i",
+	SpectralWaveR1Dataset:         "j",
+	SpectralWaveR2Dataset:         "k",
+}
+
+// Creates and returns the url of the gzip compressed archive of a dataset for a whole year
+func (b Buoy) CreateHistoricalDataURL(dataset HistoricalDataset, year int) string {
+	return b.client().endpoints().ndbcURL(baseHistoricalDataURL, dataset, b.StationID, historicalDatasetFileCodes[dataset], year)
+}
+
+// Creates and returns the url of the gzip compressed archive of a dataset for a month of the given year.
+// NDBC only publishes monthly files for the current year, older months are in the yearly files.
+func (b Buoy) CreateMonthlyDataURL(dataset HistoricalDataset, year int, month time.Month) string {
+	monthCode := strconv.FormatInt(int64(month), 16)
+	return b.client().endpoints().ndbcURL(baseMonthlyDataURL, dataset, month.String()[:3], b.StationID, monthCode, year)
+}
+
+// Grabs the archived standard meteorological data for a whole year as a time series of BuoyDataItem
+// objects. The archives go back decades and older years have different headers, which are handled
+// when parsing.
+func (b *Buoy) FetchHistoricalStandardData(year int) error {
+	return b.FetchHistoricalStandardDataContext(context.Background(), year)
+}
+
+// Same as FetchHistoricalStandardData, but the download is bound to the given context
+func (b *Buoy) FetchHistoricalStandardDataContext(ctx context.Context, year int) error {
+	return b.fetchArchivedStandardData(ctx, b.CreateHistoricalDataURL(StandardMeteorologicalDataset, year))
+}
+
+// Grabs the archived standard meteorological data for a month of the current year as a time series
+// of BuoyDataItem objects
+func (b *Buoy) FetchMonthlyStandardData(month time.Month) error {
+	return b.FetchMonthlyStandardDataContext(context.Background(), month)
+}
+
+// Same as FetchMonthlyStandardData, but the download is bound to the given context
+func (b *Buoy) FetchMonthlyStandardDataContext(ctx context.Context, month time.Month) error {
+	url := b.CreateMonthlyDataURL(StandardMeteorologicalDataset, time.Now().UTC().Year(), month)
+	return b.fetchArchivedStandardData(ctx, url)
+}
+
+func (b *Buoy) fetchArchivedStandardData(ctx context.Context, url string) error {
+	rawData, fetchErr := fetchArchiveFromURL(ctx, b.client().fetcher(), url)
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawStandardData(string(rawData), -1)
+}
+
+// Grabs the archived energy and mean direction spectra for a whole year as a time series of
+// BuoyDataItem objects
+func (b *Buoy) FetchHistoricalWaveSpectraData(year int) error {
+	return b.FetchHistoricalWaveSpectraDataContext(context.Background(), year)
+}
+
+// Same as FetchHistoricalWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchHistoricalWaveSpectraDataContext(ctx context.Context, year int) error {
+	return b.fetchArchivedWaveSpectraData(ctx, false, func(dataset HistoricalDataset) string {
+		return b.CreateHistoricalDataURL(dataset, year)
+	})
+}
+
+// Grabs the archived energy spectra with all four directional Fourier coefficients for a whole
+// year as a time series of BuoyDataItem objects
+func (b *Buoy) FetchHistoricalDirectionalWaveSpectraData(year int) error {
+	return b.FetchHistoricalDirectionalWaveSpectraDataContext(context.Background(), year)
+}
+
+// Same as FetchHistoricalDirectionalWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchHistoricalDirectionalWaveSpectraDataContext(ctx context.Context, year int) error {
+	return b.fetchArchivedWaveSpectraData(ctx, true, func(dataset HistoricalDataset) string {
+		return b.CreateHistoricalDataURL(dataset, year)
+	})
+}
+
+// Grabs the archived energy and mean direction spectra for a month of the current year as a time
+// series of BuoyDataItem objects
+func (b *Buoy) FetchMonthlyWaveSpectraData(month time.Month) error {
+	return b.FetchMonthlyWaveSpectraDataContext(context.Background(), month)
+}
+
+// Same as FetchMonthlyWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchMonthlyWaveSpectraDataContext(ctx context.Context, month time.Month) error {
+	year := time.Now().UTC().Year()
+	return b.fetchArchivedWaveSpectraData(ctx, false, func(dataset HistoricalDataset) string {
+		return b.CreateMonthlyDataURL(dataset, year, month)
+	})
+}
+
+// Grabs the archived energy spectra with all four directional Fourier coefficients for a month of
+// the current year as a time series of BuoyDataItem objects
+func (b *Buoy) FetchMonthlyDirectionalWaveSpectraData(month time.Month) error {
+	return b.FetchMonthlyDirectionalWaveSpectraDataContext(context.Background(), month)
+}
+
+// Same as FetchMonthlyDirectionalWaveSpectraData, but the downloads are bound to the given context
+func (b *Buoy) FetchMonthlyDirectionalWaveSpectraDataContext(ctx context.Context, month time.Month) error {
+	year := time.Now().UTC().Year()
+	return b.fetchArchivedWaveSpectraData(ctx, true, func(dataset HistoricalDataset) string {
+		return b.CreateMonthlyDataURL(dataset, year, month)
+	})
+}
+
+func (b *Buoy) fetchArchivedWaveSpectraData(ctx context.Context, withCoefficients bool, datasetURL func(HistoricalDataset) string) error {
+	datasets := []HistoricalDataset{SpectralWaveDensityDataset, SpectralWaveAlpha1Dataset}
+	if withCoefficients {
+		datasets = append(datasets, SpectralWaveAlpha2Dataset, SpectralWaveR1Dataset, SpectralWaveR2Dataset)
+	}
+
+	rawData := make([]string, len(datasets))
+	for index, dataset := range datasets {
+		rawDatasetData, fetchErr := fetchArchiveFromURL(ctx, b.client().fetcher(), datasetURL(dataset))
+		if fetchErr != nil {
+			return stationFetchError(fetchErr)
+		}
+		rawData[index] = string(rawDatasetData)
+	}
+
+	parseErr := b.ParseRawHistoricalWaveSpectraData(rawData[0], rawData[1], -1)
+	if parseErr != nil || !withCoefficients {
+		return parseErr
+	}
+	return b.ParseRawHistoricalDirectionalCoefficientData(rawData[2], rawData[3], rawData[4])
+}
+
+// Parses the contents of archived spectral density (swden) and mean direction (swdir) files into a
+// time series of BuoyDataItem objects. Unlike the realtime files, the archives list the frequencies
+// once in the header. Pass an empty string for the direction data to parse the energy alone. Input a
+// negative integer to parse all available data points.
+func (b *Buoy) ParseRawHistoricalWaveSpectraData(rawEnergyData, rawAlphaData string, dataCountLimit int) error {
+	energySeries, energyErr := parseHistoricalSpectralData(rawEnergyData, densitySentinel)
+	if energyErr != nil {
+		return energyErr
+	}
+
+	var alphaSeries *historicalSpectralSeries
+	if rawAlphaData != "" {
+		var alphaErr error
+		alphaSeries, alphaErr = parseHistoricalSpectralData(rawAlphaData, directionSentinel)
+		if alphaErr != nil {
+			return alphaErr
+		} else if len(alphaSeries.Frequencies) != len(energySeries.Frequencies) {
+			return errors.New("Swell direction and energy spectra frequencies do not match, could not parse")
+		}
+	}
+
+	dataLineCount := len(energySeries.Dates)
+	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
+		dataLineCount = dataCountLimit
+	}
+
+	buoyData := make([]BuoyDataItem, dataLineCount)
+	for index, date := range energySeries.Dates[:dataLineCount] {
+		buoyItem := NewBuoyDataItem(Metric)
+		buoyItem.Date = date
+
+		item := BuoySpectraItem{
+			Frequencies:         append([]float64{}, energySeries.Frequencies...),
+			Energies:            energySeries.Values[index],
+			SeperationFrequency: MissingValue(),
+		}
+		if alphaSeries != nil {
+			if angles, ok := alphaSeries.valuesAt(date); ok {
+				item.Angles = angles
+			}
+		}
+
+		buoyItem.WaveSpectra = item
+		if item.Angles != nil {
+			buoyItem.WaveSummary = item.WaveSummary()
+			buoyItem.SwellComponents = item.FindSwellComponents()
+			buoyItem.Steepness = SolveSteepness(buoyItem.WaveSummary.WaveHeight, buoyItem.WaveSummary.Period)
+		}
+		buoyItem.AveragePeriod = item.AveragePeriod()
+
+		buoyData[index] = buoyItem
+	}
+
+	b.BuoyData = buoyData
+	return nil
+}
+
+// Adds the archived alpha2, r1 and r2 directional coefficients to the wave spectra already in BuoyData,
+// which must be parsed with ParseRawHistoricalWaveSpectraData first. Lines are matched to the existing
+// items by their observation time. Some archive files hold r1 and r2 scaled by 100, these are scaled
+// back to the range 0 to 1.
+func (b *Buoy) ParseRawHistoricalDirectionalCoefficientData(rawAlpha2Data, rawR1Data, rawR2Data string) error {
+	alpha2Series, alpha2Err := parseHistoricalSpectralData(rawAlpha2Data, directionSentinel)
+	if alpha2Err != nil {
+		return alpha2Err
+	}
+	r1Series, r1Err := parseHistoricalSpectralData(rawR1Data, directionSentinel)
+	if r1Err != nil {
+		return r1Err
+	}
+	r2Series, r2Err := parseHistoricalSpectralData(rawR2Data, directionSentinel)
+	if r2Err != nil {
+		return r2Err
+	}
+
+	for i, _ := range b.BuoyData {
+		spectra := &b.BuoyData[i].WaveSpectra
+		date := b.BuoyData[i].Date
+
+		alpha2, hasAlpha2 := alpha2Series.valuesAt(date)
+		r1, hasR1 := r1Series.valuesAt(date)
+		r2, hasR2 := r2Series.valuesAt(date)
+		if !hasAlpha2 || !hasR1 || !hasR2 {
+			continue
+		}
+
+		frequencyCount := len(spectra.Frequencies)
+		if len(alpha2) != frequencyCount || len(r1) != frequencyCount || len(r2) != frequencyCount {
+			return fmt.Errorf("Directional coefficients for %s do not match the %d spectra frequencies", date.Format(time.RFC3339), frequencyCount)
+		}
+
+		spectra.Alpha2 = alpha2
+		spectra.R1 = normalizeCoefficients(r1)
+		spectra.R2 = normalizeCoefficients(r2)
+	}
+
+	return nil
+}
+
+// The values of an archived spectral file, one row of values per observation time
+type historicalSpectralSeries struct {
+	Frequencies []float64
+	Dates       []time.Time
+	Values      [][]float64
+
+	dateIndices map[int64]int
+}
+
+// Get the values observed at the given time
+func (h *historicalSpectralSeries) valuesAt(date time.Time) ([]float64, bool) {
+	index, ok := h.dateIndices[date.Unix()]
+	if !ok {
+		return nil, false
+	}
+	return h.Values[index], true
+}
+
+// Parses an archived spectral file, where the header holds the date columns followed by one column
+// for each frequency
+func parseHistoricalSpectralData(rawData string, sentinel float64) (*historicalSpectralSeries, error) {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return nil, tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return nil, columnErr
+	}
+
+	series := &historicalSpectralSeries{dateIndices: map[int64]int{}}
+	frequencyColumns := []string{}
+	for _, column := range table.Columns {
+		frequency, parseErr := strconv.ParseFloat(column, 64)
+		if parseErr != nil {
+			continue
+		}
+		series.Frequencies = append(series.Frequencies, frequency)
+		frequencyColumns = append(frequencyColumns, column)
+	}
+	if len(frequencyColumns) == 0 {
+		return nil, &ParseError{Line: 1, Column: "frequency", Err: ErrMissingColumn}
+	}
+
+	for _, row := range table.Rows {
+		values := table.reader(row)
+		date := values.date()
+		rowValues := make([]float64, len(frequencyColumns))
+		for index, column := range frequencyColumns {
+			rowValues[index] = values.float(column, sentinel)
+		}
+		if values.err != nil {
+			return nil, values.err
+		}
+
+		series.dateIndices[date.Unix()] = len(series.Dates)
+		series.Dates = append(series.Dates, date)
+		series.Values = append(series.Values, rowValues)
+	}
+
+	return series, nil
+}
+
+// Scales r coefficients stored as percentages back to the range 0 to 1
+func normalizeCoefficients(coefficients []float64) []float64 {
+	scale := 1.0
+	for _, coefficient := range coefficients {
+		if coefficient > 1.0 {
+			scale = 100.0
+			break
+		}
+	}
+
+	normalized := make([]float64, len(coefficients))
+	for index, coefficient := range coefficients {
+		normalized[index] = coefficient / scale
+	}
+	return normalized
+}
@@ -0,0 +1,105 @@
+package surfnerd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLegacyStandardDataHeaders(t *testing.T) {
+	// Two digit years, no minute column, old column names and no TIDE column
+	rawData := `YY MM DD hh  WD  WSPD GST  WVHT  DPD   APD  MWD  BAR    ATMP  WTMP  DEWP  VIS
+98 01 01 00 320  8.2  9.9  1.30  9.09  5.76 999 1021.4   1.9   6.6 999.0 99.0
+98 01 01 01 330  8.8 10.4  1.40  8.33  5.81 999 1021.9   1.4   6.5 999.0 99.0
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawStandardData(rawData, -1)
+	if parseErr != nil || len(buoy.BuoyData) != 2 {
+		fmt.Println("Failed to parse standard data with a legacy header")
+		t.FailNow()
+	}
+
+	item := buoy.BuoyData[1]
+	if !item.Date.Equal(time.Date(1998, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		fmt.Println("Two digit years without minutes were parsed incorrectly")
+		t.FailNow()
+	}
+	if item.WindDirection != 330 || item.Pressure != 1021.9 || !IsMissing(item.WaveSummary.Direction) || !IsMissing(item.WaterLevel) {
+		fmt.Println("Legacy columns were not matched to their current names")
+		t.FailNow()
+	}
+}
+
+func TestHistoricalSpectraFetch(t *testing.T) {
+	archives := map[string]string{
+		"44017w2016.txt.gz": "YYYY MM DD hh mm  .0200  .0325  .0375\n2016 01 01 00 00   0.00   0.50   1.20\n2016 01 01 01 00   0.00   0.40 999.00\n",
+		"44017d2016.txt.gz": "YYYY MM DD hh mm  .0200  .0325  .0375\n2016 01 01 00 00  999.0  190.0  180.0\n2016 01 01 01 00  999.0  195.0  185.0\n",
+		"44017i2016.txt.gz": "YYYY MM DD hh mm  .0200  .0325  .0375\n2016 01 01 00 00  999.0  188.0  182.0\n",
+		"44017j2016.txt.gz": "YYYY MM DD hh mm  .0200  .0325  .0375\n2016 01 01 00 00  999.0   90.0   80.0\n",
+		"44017k2016.txt.gz": "YYYY MM DD hh mm  .0200  .0325  .0375\n2016 01 01 00 00  999.0   70.0   60.0\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fileName := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		rawData, ok := archives[fileName]
+		if !ok || !strings.HasPrefix(r.URL.Path, "/data/historical/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		compressed := bytes.Buffer{}
+		writer := gzip.NewWriter(&compressed)
+		writer.Write([]byte(rawData))
+		writer.Close()
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	client := NewClient(&HTTPFetcher{Client: server.Client()})
+	client.Endpoints = Endpoints{NDBC: server.URL, NOMADS: server.URL}
+	buoy := Buoy{StationID: "44017", Client: client}
+
+	fetchErr := buoy.FetchHistoricalDirectionalWaveSpectraDataContext(context.Background(), 2016)
+	if fetchErr != nil || len(buoy.BuoyData) != 2 {
+		fmt.Println("Failed to fetch the historical spectra")
+		t.FailNow()
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if len(spectra.Frequencies) != 3 || spectra.Frequencies[1] != 0.0325 || spectra.Energies[2] != 1.2 || spectra.Angles[1] != 190.0 {
+		fmt.Println("The historical spectra was parsed incorrectly")
+		t.FailNow()
+	}
+	if !spectra.HasDirectionalCoefficients() || math.Abs(spectra.R1[1]-0.9) > 1e-9 || !IsMissing(spectra.R2[0]) {
+		fmt.Println("The historical directional coefficients were parsed incorrectly")
+		t.FailNow()
+	}
+	if buoy.BuoyData[1].WaveSpectra.HasDirectionalCoefficients() || !IsMissing(buoy.BuoyData[1].WaveSpectra.Energies[2]) {
+		fmt.Println("Observations without coefficients should be left without them")
+		t.FailNow()
+	}
+
+	fetchErr = buoy.FetchHistoricalStandardDataContext(context.Background(), 2016)
+	if !errors.Is(fetchErr, ErrStationDataNotFound) {
+		fmt.Println("Expected a missing archive to be reported as not found")
+		t.FailNow()
+	}
+}
+
+func TestMonthlyDataURL(t *testing.T) {
+	buoy := Buoy{StationID: "44017", Client: NewClient(nil)}
+	url := buoy.CreateMonthlyDataURL(SpectralWaveDensityDataset, 2017, time.October)
+	if !strings.HasSuffix(url, "/data/swden/Oct/44017a2017.txt.gz") {
+		fmt.Println("Monthly archive url was created incorrectly:", url)
+		t.FailNow()
+	}
+}
@@ -1,9 +1,22 @@
 package surfnerd
 
 import (
+	"errors"
 	"math"
 )
 
+const (
+	// The mean radius of the earth in kilometers
+	earthRadius = 6371.0088
+
+	// The WGS 84 ellipsoid, with the axes in kilometers
+	wgs84SemiMajorAxis  = 6378.137
+	wgs84Flattening     = 1.0 / 298.257223563
+	wgs84SemiMinorAxis  = wgs84SemiMajorAxis * (1.0 - wgs84Flattening)
+	vincentyIterations  = 200
+	vincentyConvergence = 1e-12
+)
+
 // Container holding location information.
 type Location struct {
 	Latitude     float64 `xml:"lat,attr"`
@@ -14,11 +27,22 @@ type Location struct {
 
 // Get an adjusted longitude that will be + or - 180 degrees
 func (l Location) AdjustedLongitude() float64 {
-	if l.Longitude > 180 {
-		return l.Longitude - 360.0
-	} else {
-		return l.Longitude
-	}
+	return NormalizeLongitude(l.Longitude)
+}
+
+// Get the longitude between 0 and 360 degrees east, as used by the NOAA models
+func (l Location) AbsoluteLongitude() float64 {
+	return NormalizeAbsoluteLongitude(l.Longitude)
+}
+
+// Normalizes a longitude in degrees to the range -180 up to 180
+func NormalizeLongitude(longitude float64) float64 {
+	return normalizeDirection(longitude+180.0) - 180.0
+}
+
+// Normalizes a longitude in degrees to the range 0 up to 360
+func NormalizeAbsoluteLongitude(longitude float64) float64 {
+	return normalizeDirection(longitude)
 }
 
 // Get an adjusted latitude that will be + or - 85
@@ -30,17 +54,118 @@ func (l Location) AdjustedLatitude() float64 {
 	}
 }
 
-// Get the lat and long components of the distance between two locations
+// Get the lat and long components of the distance between two locations in degrees. The longitude
+// component is taken the short way around the globe, so mixing -180 to 180 and 0 to 360 longitudes is fine.
 func (l Location) ComponentDistanceTo(otherLoc Location) (latDist, lonDist float64) {
 	latDist = math.Abs(l.Latitude - otherLoc.Latitude)
-	lonDist = math.Abs(l.Longitude - otherLoc.Longitude)
+	lonDist = math.Abs(NormalizeLongitude(l.Longitude - otherLoc.Longitude))
 	return
 }
 
-// Get the estimated distance vector between two location points
+// Get the great circle distance between two locations in kilometers
 func (l Location) DistanceTo(otherLoc Location) float64 {
-	latDist, lonDist := l.ComponentDistanceTo(otherLoc)
-	return math.Sqrt(math.Pow(latDist, 2) + math.Pow(lonDist, 2))
+	return l.HaversineDistanceTo(otherLoc)
+}
+
+// Get the great circle distance between two locations in nautical miles
+func (l Location) NauticalMilesTo(otherLoc Location) float64 {
+	return KilometersToNauticalMiles(l.HaversineDistanceTo(otherLoc))
+}
+
+// Get the great circle distance between two locations in kilometers with the haversine formula, treating
+// the earth as a sphere. This is within about half a percent of the distance on the ellipsoid.
+func (l Location) HaversineDistanceTo(otherLoc Location) float64 {
+	latitude, otherLatitude := l.Latitude*math.Pi/180.0, otherLoc.Latitude*math.Pi/180.0
+	latitudeDifference := otherLatitude - latitude
+	longitudeDifference := NormalizeLongitude(otherLoc.Longitude-l.Longitude) * math.Pi / 180.0
+
+	haversine := math.Pow(math.Sin(latitudeDifference/2.0), 2) +
+		math.Cos(latitude)*math.Cos(otherLatitude)*math.Pow(math.Sin(longitudeDifference/2.0), 2)
+	return 2.0 * earthRadius * math.Asin(math.Sqrt(math.Min(haversine, 1.0)))
+}
+
+// Get the distance between two locations in kilometers on the WGS 84 ellipsoid with Vincenty's inverse
+// formula, which is accurate to well under a meter. The formula does not converge for nearly antipodal
+// locations, in which case an error is returned.
+func (l Location) VincentyDistanceTo(otherLoc Location) (float64, error) {
+	reducedLatitude := math.Atan((1.0 - wgs84Flattening) * math.Tan(l.Latitude*math.Pi/180.0))
+	otherReducedLatitude := math.Atan((1.0 - wgs84Flattening) * math.Tan(otherLoc.Latitude*math.Pi/180.0))
+	longitudeDifference := NormalizeLongitude(otherLoc.Longitude-l.Longitude) * math.Pi / 180.0
+
+	sinReduced, cosReduced := math.Sin(reducedLatitude), math.Cos(reducedLatitude)
+	otherSinReduced, otherCosReduced := math.Sin(otherReducedLatitude), math.Cos(otherReducedLatitude)
+
+	lambda := longitudeDifference
+	var sinSigma, cosSigma, sigma, cosSquaredAlpha, cos2SigmaMidpoint float64
+	for iteration := 0; ; iteration++ {
+		if iteration >= vincentyIterations {
+			return MissingValue(), errors.New("Vincenty formula did not converge, the locations are nearly antipodal")
+		}
+
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Hypot(otherCosReduced*sinLambda, cosReduced*otherSinReduced-sinReduced*otherCosReduced*cosLambda)
+		if sinSigma == 0 {
+			return 0, nil
+		}
+		cosSigma = sinReduced*otherSinReduced + cosReduced*otherCosReduced*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosReduced * otherCosReduced * sinLambda / sinSigma
+		cosSquaredAlpha = 1.0 - sinAlpha*sinAlpha
+		cos2SigmaMidpoint = 0
+		if cosSquaredAlpha != 0 {
+			// Both locations are on the equator otherwise
+			cos2SigmaMidpoint = cosSigma - 2.0*sinReduced*otherSinReduced/cosSquaredAlpha
+		}
+
+		c := wgs84Flattening / 16.0 * cosSquaredAlpha * (4.0 + wgs84Flattening*(4.0-3.0*cosSquaredAlpha))
+		previousLambda := lambda
+		lambda = longitudeDifference + (1.0-c)*wgs84Flattening*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaMidpoint+c*cosSigma*(-1.0+2.0*cos2SigmaMidpoint*cos2SigmaMidpoint)))
+		if math.Abs(lambda-previousLambda) < vincentyConvergence {
+			break
+		}
+	}
+
+	uSquared := cosSquaredAlpha * (wgs84SemiMajorAxis*wgs84SemiMajorAxis - wgs84SemiMinorAxis*wgs84SemiMinorAxis) / (wgs84SemiMinorAxis * wgs84SemiMinorAxis)
+	a := 1.0 + uSquared/16384.0*(4096.0+uSquared*(-768.0+uSquared*(320.0-175.0*uSquared)))
+	b := uSquared / 1024.0 * (256.0 + uSquared*(-128.0+uSquared*(74.0-47.0*uSquared)))
+	deltaSigma := b * sinSigma * (cos2SigmaMidpoint + b/4.0*(cosSigma*(-1.0+2.0*cos2SigmaMidpoint*cos2SigmaMidpoint)-
+		b/6.0*cos2SigmaMidpoint*(-3.0+4.0*sinSigma*sinSigma)*(-3.0+4.0*cos2SigmaMidpoint*cos2SigmaMidpoint)))
+	return wgs84SemiMinorAxis * a * (sigma - deltaSigma), nil
+}
+
+// Get the bearing in degrees clockwise from north to start out on to follow the great circle
+// from this location to the other location
+func (l Location) InitialBearingTo(otherLoc Location) float64 {
+	latitude, otherLatitude := l.Latitude*math.Pi/180.0, otherLoc.Latitude*math.Pi/180.0
+	longitudeDifference := NormalizeLongitude(otherLoc.Longitude-l.Longitude) * math.Pi / 180.0
+
+	y := math.Sin(longitudeDifference) * math.Cos(otherLatitude)
+	x := math.Cos(latitude)*math.Sin(otherLatitude) - math.Sin(latitude)*math.Cos(otherLatitude)*math.Cos(longitudeDifference)
+	return NormalizeAbsoluteLongitude(math.Atan2(y, x) * 180.0 / math.Pi)
+}
+
+// Get the bearing in degrees clockwise from north the great circle from this location arrives
+// at the other location on
+func (l Location) FinalBearingTo(otherLoc Location) float64 {
+	return NormalizeAbsoluteLongitude(otherLoc.InitialBearingTo(l) + 180.0)
+}
+
+// Get the location reached by travelling the given distance in kilometers along a great circle
+// from this location, starting out on the given bearing in degrees clockwise from north
+func (l Location) DestinationPoint(bearing, distance float64) Location {
+	latitude := l.Latitude * math.Pi / 180.0
+	longitude := l.AdjustedLongitude() * math.Pi / 180.0
+	angularDistance := distance / earthRadius
+	bearing = bearing * math.Pi / 180.0
+
+	destinationLatitude := math.Asin(math.Sin(latitude)*math.Cos(angularDistance) +
+		math.Cos(latitude)*math.Sin(angularDistance)*math.Cos(bearing))
+	destinationLongitude := longitude + math.Atan2(math.Sin(bearing)*math.Sin(angularDistance)*math.Cos(latitude),
+		math.Cos(angularDistance)-math.Sin(latitude)*math.Sin(destinationLatitude))
+
+	return Location{Latitude: destinationLatitude * 180.0 / math.Pi, Longitude: NormalizeLongitude(destinationLongitude * 180.0 / math.Pi)}
 }
 
 // Create a new Location object from a given latitude and longitude pair
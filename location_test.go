@@ -0,0 +1,83 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestLongitudeNormalization(t *testing.T) {
+	if NormalizeLongitude(289.0) != -71.0 || NormalizeLongitude(-190.0) != 170.0 || NormalizeLongitude(180.0) != -180.0 {
+		fmt.Println("Longitudes were not normalized to -180 to 180")
+		t.FailNow()
+	}
+	if NormalizeAbsoluteLongitude(-71.0) != 289.0 || NormalizeAbsoluteLongitude(720.0) != 0.0 {
+		fmt.Println("Longitudes were not normalized to 0 to 360")
+		t.FailNow()
+	}
+
+	model := NewEastCoastWaveModel()
+	relative := NewLocationForLatLong(41.0, -71.5)
+	absolute := NewLocationForLatLong(41.0, 288.5)
+	if !model.ContainsLocation(relative) || !model.ContainsLocation(absolute) {
+		fmt.Println("Model should contain locations in either longitude convention")
+		t.FailNow()
+	}
+
+	relativeLat, relativeLon := model.LocationIndices(relative)
+	absoluteLat, absoluteLon := model.LocationIndices(absolute)
+	if relativeLat != absoluteLat || relativeLon != absoluteLon {
+		fmt.Println("Model indices should not depend on the longitude convention")
+		t.FailNow()
+	}
+}
+
+func TestGreatCircleDistances(t *testing.T) {
+	// Boston Logan to Heathrow, about 5265 km on the ellipsoid
+	boston := NewLocationForLatLong(42.3656, -71.0096)
+	london := NewLocationForLatLong(51.4700, 360.0-0.4543)
+
+	vincenty, vincentyErr := boston.VincentyDistanceTo(london)
+	if vincentyErr != nil || math.Abs(vincenty-5265.0) > 15.0 {
+		fmt.Println("Vincenty distance is wrong:", vincenty)
+		t.FailNow()
+	}
+	haversine := boston.DistanceTo(london)
+	if math.Abs(haversine-vincenty)/vincenty > 0.005 || math.Abs(boston.NauticalMilesTo(london)-haversine/1.852) > 1e-9 {
+		fmt.Println("Haversine distance is wrong:", haversine)
+		t.FailNow()
+	}
+
+	// A degree of longitude at 60 degrees north is half as long as at the equator
+	equator := NewLocationForLatLong(0, 0).DistanceTo(NewLocationForLatLong(0, 1))
+	north := NewLocationForLatLong(60, 179.5).DistanceTo(NewLocationForLatLong(60, -179.5))
+	if math.Abs(north/equator-0.5) > 0.001 {
+		fmt.Println("Distances should shrink with latitude and wrap across the antimeridian")
+		t.FailNow()
+	}
+
+	if bearing := boston.InitialBearingTo(london); math.Abs(bearing-53.3) > 0.5 {
+		fmt.Println("Initial bearing is wrong:", bearing)
+		t.FailNow()
+	}
+	if bearing := boston.FinalBearingTo(london); math.Abs(bearing-108.0) > 3.0 {
+		fmt.Println("Final bearing is wrong:", bearing)
+		t.FailNow()
+	}
+
+	destination := boston.DestinationPoint(boston.InitialBearingTo(london), haversine)
+	if destination.DistanceTo(london) > 0.01 {
+		fmt.Println("Destination point should land back on the other location")
+		t.FailNow()
+	}
+}
+
+func TestDestinationPoint(t *testing.T) {
+	// Heading east for a quarter of the way around the earth ends on the equator 90 degrees further east
+	start := NewLocationForLatLong(40.0, -70.0)
+	destination := start.DestinationPoint(90.0, math.Pi*earthRadius/2.0)
+	if math.Abs(destination.Latitude) > 1e-6 || math.Abs(destination.Longitude-20.0) > 1e-6 {
+		fmt.Println("Destination point is wrong:", destination)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,211 @@
+package surfnerd
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// NDBC marks missing values with "MM" in the realtime files, and with all nines sentinels
+// such as 99.00, 999 and 9999.0 in the historical files. Any value at or above the sentinel
+// for its column is treated as missing.
+const (
+	noSentinel        = 0.0
+	speedSentinel     = 99.0
+	heightSentinel    = 99.0
+	periodSentinel    = 99.0
+	directionSentinel = 999.0
+	pressureSentinel  = 9999.0
+	tempSentinel      = 999.0
+	visSentinel       = 99.0
+	tideSentinel      = 99.0
+	densitySentinel   = 999.0
+
+	// Water quality columns of the .ocean files
+	depthSentinel               = 9999.0
+	conductivitySentinel        = 999.0
+	salinitySentinel            = 99.0
+	oxygenSaturationSentinel    = 999.0
+	oxygenConcentrationSentinel = 99.0
+	chlorophyllSentinel         = 999.0
+	turbiditySentinel           = 999.0
+	phSentinel                  = 99.0
+	redoxPotentialSentinel      = 9999.0
+
+	// Current speeds of the .adcp files, in cm/s
+	currentSpeedSentinel = 999.0
+
+	// Times of day given as hhmm, such as the peak gust time
+	clockTimeSentinel = 9999.0
+)
+
+// Get the value used to represent a measurement the source did not report. Missing values
+// are NaN so they can never be mistaken for a real reading of zero.
+func MissingValue() float64 {
+	return math.NaN()
+}
+
+// Returns if a measurement is missing
+func IsMissing(value float64) bool {
+	return math.IsNaN(value)
+}
+
+// Parses a raw NDBC value, returning a missing value for "MM", anything that is not a number,
+// and any value at or above the given sentinel. Pass noSentinel to accept any number.
+func parseNDBCValue(rawValue string, sentinel float64) float64 {
+	rawValue = strings.TrimSpace(rawValue)
+	if rawValue == "" || rawValue == "MM" {
+		return MissingValue()
+	}
+
+	value, parseErr := strconv.ParseFloat(rawValue, 64)
+	if parseErr != nil {
+		return MissingValue()
+	} else if sentinel > 0 && value >= sentinel {
+		return MissingValue()
+	}
+	return value
+}
+
+// Converts a compass direction to degrees, returning a missing value for anything
+// that is not a compass direction
+func compassDirectionToDegree(direction string) float64 {
+	degree := DirectionToDegree(direction)
+	if degree < 0 {
+		return MissingValue()
+	}
+	return degree
+}
+
+// Marshals a struct the same way encoding/json does, except that float fields holding a
+// missing value are left out instead of failing to encode, and missing values inside float
+// slices are encoded as null.
+func marshalOmittingMissing(v interface{}) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	valueType := value.Type()
+
+	buffer := bytes.Buffer{}
+	buffer.WriteByte('{')
+	fieldCount := 0
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		omitEmpty := false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			tagParts := strings.Split(tag, ",")
+			if tagParts[0] != "" {
+				name = tagParts[0]
+			}
+			for _, option := range tagParts[1:] {
+				omitEmpty = omitEmpty || option == "omitempty"
+			}
+		}
+
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Float64 && IsMissing(fieldValue.Float()) {
+			continue
+		} else if omitEmpty && isEmptyJSONValue(fieldValue) {
+			continue
+		}
+
+		fieldInterface := fieldValue.Interface()
+		if values, ok := fieldInterface.([]float64); ok {
+			fieldInterface = nullableFloats(values)
+		}
+
+		rawField, jsonErr := json.Marshal(fieldInterface)
+		if jsonErr != nil {
+			return nil, jsonErr
+		}
+		rawName, _ := json.Marshal(name)
+
+		if fieldCount > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(rawName)
+		buffer.WriteByte(':')
+		buffer.Write(rawField)
+		fieldCount++
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+// Matches the omitempty rules of encoding/json
+func isEmptyJSONValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+// Sets every float field of the struct pointed to by v to a missing value. Used before
+// decoding json so fields left out of the json come back as missing. Fields tagged omitempty
+// are skipped because leaving them out of the json means they were zero.
+func markFloatsMissing(v interface{}) {
+	value := reflect.ValueOf(v).Elem()
+	valueType := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() != reflect.Float64 || !field.CanSet() {
+			continue
+		} else if strings.Contains(valueType.Field(i).Tag.Get("json"), "omitempty") {
+			continue
+		}
+		field.SetFloat(MissingValue())
+	}
+}
+
+// Converts a slice of values to pointers so missing values are encoded as null in json
+func nullableFloats(values []float64) []*float64 {
+	if values == nil {
+		return nil
+	}
+
+	nullable := make([]*float64, len(values))
+	for index, _ := range values {
+		if !IsMissing(values[index]) {
+			nullable[index] = &values[index]
+		}
+	}
+	return nullable
+}
+
+// Converts a slice of pointers decoded from json back to values, with null values missing
+func floatsFromNullable(nullable []*float64) []float64 {
+	if nullable == nil {
+		return nil
+	}
+
+	values := make([]float64, len(nullable))
+	for index, value := range nullable {
+		if value == nil {
+			values[index] = MissingValue()
+		} else {
+			values[index] = *value
+		}
+	}
+	return values
+}
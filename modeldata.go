@@ -3,10 +3,18 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// The GrADS data server gives times as days since 1-1-1 00:00 in the mixed Julian and Gregorian
+// calendar of udunits, which is two days behind the proleptic Gregorian calendar Go uses by then
+const modelTimeCalendarOffset = 2.0
+
+var modelTimeEpoch = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 // A generic map useful for encapsulating model data from NOAA GRADS servers. This holds the data in a map so
 // the data can be conveinently used for plotting and physics calculations to name a few.
 type ModelDataMap map[string][]float64
@@ -17,6 +25,9 @@ type ModelData struct {
 	Location
 	Model NOAAModel
 	Data  ModelDataMap
+
+	// The grid points the data was taken from, with the weight each was given
+	GridPoints []ModelGridPoint `json:",omitempty"`
 }
 
 // Export a ModelData object to a json formatted string
@@ -35,6 +46,52 @@ func (m *ModelData) ExportAsJSON(filename string) error {
 	return fileErr
 }
 
+// Decodes a value of the time axis of a model, in days since 1-1-1, into a UTC time. The
+// time is rounded to the second.
+func DecodeModelTime(value float64) time.Time {
+	seconds := math.Round((value - modelTimeCalendarOffset) * 24.0 * 60.0 * 60.0)
+	return time.Unix(modelTimeEpoch.Unix()+int64(seconds), 0).UTC()
+}
+
+// Encodes a time as a value of the time axis of a model, in days since 1-1-1
+func EncodeModelTime(date time.Time) float64 {
+	return float64(date.Unix()-modelTimeEpoch.Unix())/(24.0*60.0*60.0) + modelTimeCalendarOffset
+}
+
+// Get the decoded times of the time axis of the model data, or nil when the data has no times
+func (m ModelDataMap) Times() []time.Time {
+	values, ok := m["time"]
+	if !ok {
+		return nil
+	}
+
+	times := make([]time.Time, len(values))
+	for index, value := range values {
+		times[index] = DecodeModelTime(value)
+	}
+	return times
+}
+
+// Get the time of each of the count forecast steps of the model data. The decoded time axis is used when
+// the data has one, otherwise the steps are counted from the model run at the time resolution of the model.
+// Steps past the end of a time axis that is too short are left at the zero time rather than guessed.
+func (m *ModelData) forecastTimes(count int) []time.Time {
+	if times := m.Data.Times(); times != nil {
+		for len(times) < count {
+			times = append(times, time.Time{})
+		}
+		return times[:count]
+	}
+
+	times := make([]time.Time, count)
+	modelTime := m.Model.modelRun()
+	step := time.Duration(m.Model.TimeResolutionHours() * float64(time.Hour))
+	for index, _ := range times {
+		times[index] = modelTime.Add(time.Duration(index) * step)
+	}
+	return times
+}
+
 func parseRawModelData(data []byte) ModelDataMap {
 	if data == nil {
 		return nil
@@ -48,6 +105,10 @@ func parseRawModelData(data []byte) ModelDataMap {
 	modelData := ModelDataMap{}
 	currentVar := ""
 
+	// Grids with maps repeat the time axis after their values along with the latitude and longitude
+	// axes, so only the values of the first time axis are kept
+	timeParsed := false
+
 	for _, value := range splitData {
 		switch {
 		case len(value) < 1:
@@ -56,13 +117,20 @@ func parseRawModelData(data []byte) ModelDataMap {
 			datas := strings.Split(value, ",")
 			f, _ := strconv.ParseFloat(strings.TrimSpace(datas[1]), 64)
 			modelData[currentVar] = append(modelData[currentVar], f)
-		case value[0] >= '0' && value[0] <= '9':
+		case value[0] >= '0' && value[0] <= '9', value[0] == '-':
+			if currentVar != "time" || timeParsed {
+				continue
+			}
+
 			timestamps := strings.Split(value, ",")
 			for _, timestamp := range timestamps {
 				timeValue, _ := strconv.ParseFloat(strings.TrimSpace(timestamp), 64)
 				modelData["time"] = append(modelData["time"], timeValue)
 			}
 		default:
+			if currentVar == "time" {
+				timeParsed = true
+			}
 			variables := strings.Split(value, ",")
 			currentVar = variables[0]
 		}
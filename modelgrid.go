@@ -0,0 +1,369 @@
+package surfnerd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The value the models give for grid points without data, such as land points of a wave model
+const modelFillValue = 9.999e20
+
+// The model variables that are directions in degrees, which are interpolated around the compass
+var modelDirectionVariables = map[string]bool{
+	"dirpwsfc": true,
+	"swdir_1":  true,
+	"swdir_2":  true,
+	"wvdirsfc": true,
+	"wdirsfc":  true,
+}
+
+// How the model data is taken from the grid points around a location
+type GridInterpolation string
+
+const (
+	// Interpolates bilinearly between the four grid points around the location
+	BilinearInterpolation GridInterpolation = "bilinear"
+
+	// Weights the four grid points around the location by their inverse squared distance to it
+	InverseDistanceInterpolation GridInterpolation = "idw"
+
+	// Takes the data of the closest grid point with data
+	NearestOceanPoint GridInterpolation = "nearest"
+)
+
+// Options for extracting the model data at a location from the surrounding grid points
+type GridOptions struct {
+	Interpolation GridInterpolation
+
+	// How many grid cells around the location to search for a point with data. Land points have no
+	// wave data, so spots on the coast need to look out to sea. When none of the points around the
+	// location have data, the nearest point with data within this many cells is used.
+	SearchRadius int
+}
+
+// Creates and returns the grid options used when fetching model data
+func DefaultGridOptions() GridOptions {
+	return GridOptions{
+		Interpolation: BilinearInterpolation,
+		SearchRadius:  2,
+	}
+}
+
+// A grid point of a model that data was taken from, with the weight it was given
+type ModelGridPoint struct {
+	LatitudeIndex  int
+	LongitudeIndex int
+	Location       Location
+	Weight         float64
+}
+
+// A block of the model grid, with the indices of its first and last points
+type modelGridWindow struct {
+	latitudeStart  int
+	latitudeEnd    int
+	longitudeStart int
+	longitudeEnd   int
+}
+
+// Model values by variable, then time, latitude and longitude index relative to the window
+type modelGrid map[string][][][]float64
+
+// Get the number of grid points of the model along each axis
+func (n NOAAModel) gridSize() (latitudeCount, longitudeCount int) {
+	latitudeCount = int(math.Round((n.TopRightLocation.Latitude-n.BottomLeftLocation.Latitude)/n.LocationResolution)) + 1
+	longitudeCount = int(math.Round((n.TopRightLocation.Longitude-n.BottomLeftLocation.Longitude)/n.LocationResolution)) + 1
+	return
+}
+
+// Get the fractional grid indices of a location. Positions within a thousandth of a cell of a grid
+// point are moved onto it, so rounding in the resolution does not put a location on a grid line in
+// the cell before it.
+func (n NOAAModel) gridPosition(loc Location) (latitudePosition, longitudePosition float64) {
+	snap := func(position float64) float64 {
+		if rounded := math.Round(position); math.Abs(position-rounded) < 1e-3 {
+			return rounded
+		}
+		return position
+	}
+
+	latitudePosition = snap((loc.Latitude - n.BottomLeftLocation.Latitude) / n.LocationResolution)
+	longitudePosition = snap((n.modelLongitude(loc) - n.BottomLeftLocation.Longitude) / n.LocationResolution)
+	return
+}
+
+// Get the location of a grid point
+func (n NOAAModel) gridLocation(latitudeIndex, longitudeIndex int) Location {
+	return NewLocationForLatLong(
+		n.BottomLeftLocation.Latitude+float64(latitudeIndex)*n.LocationResolution,
+		n.BottomLeftLocation.Longitude+float64(longitudeIndex)*n.LocationResolution,
+	)
+}
+
+// Get the block of grid points needed to interpolate onto the location, or search the given number
+// of cells around it for a point with data
+func (n NOAAModel) gridWindow(loc Location, searchRadius int) modelGridWindow {
+	latitudeCount, longitudeCount := n.gridSize()
+	latitudePosition, longitudePosition := n.gridPosition(loc)
+	latitudeIndex, longitudeIndex := int(math.Floor(latitudePosition)), int(math.Floor(longitudePosition))
+	if searchRadius < 0 {
+		searchRadius = 0
+	}
+
+	clamp := func(index, count int) int {
+		return int(math.Max(0, math.Min(float64(index), float64(count-1))))
+	}
+	return modelGridWindow{
+		latitudeStart:  clamp(latitudeIndex-searchRadius, latitudeCount),
+		latitudeEnd:    clamp(latitudeIndex+1+searchRadius, latitudeCount),
+		longitudeStart: clamp(longitudeIndex-searchRadius, longitudeCount),
+		longitudeEnd:   clamp(longitudeIndex+1+searchRadius, longitudeCount),
+	}
+}
+
+// Formats a range of indices for an OPeNDAP constraint, such as 4:6 or 4 for a single index
+func indexRange(start, end int) string {
+	if start == end {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d:%d", start, end)
+}
+
+func isModelFillValue(value float64) bool {
+	return math.IsNaN(value) || value >= modelFillValue/10.0
+}
+
+// Parses the grids of an ascii OPeNDAP response, where each variable is given as rows of values
+// along the longitude such as [time][latitude], value, value
+func parseRawModelGrid(data []byte) (modelGrid, error) {
+	grid := modelGrid{}
+	currentVar := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) < 1:
+			continue
+		case line[0] == '[':
+			if currentVar == "" {
+				return nil, errors.New("Model grid row found before its variable, could not parse")
+			}
+
+			fields := strings.Split(line, ",")
+			indices := strings.Split(strings.Trim(fields[0], "[]"), "][")
+			if len(indices) != 2 {
+				return nil, errors.New("Model grid row does not have time and latitude indices, could not parse")
+			}
+			timeIndex, timeErr := strconv.Atoi(indices[0])
+			latitudeIndex, latitudeErr := strconv.Atoi(indices[1])
+			if timeErr != nil || latitudeErr != nil {
+				return nil, errors.New("Model grid row has invalid indices, could not parse")
+			}
+
+			row := make([]float64, 0, len(fields)-1)
+			for _, field := range fields[1:] {
+				value, parseErr := strconv.ParseFloat(strings.TrimSpace(field), 64)
+				if parseErr != nil {
+					return nil, parseErr
+				}
+				row = append(row, value)
+			}
+
+			for len(grid[currentVar]) <= timeIndex {
+				grid[currentVar] = append(grid[currentVar], [][]float64{})
+			}
+			for len(grid[currentVar][timeIndex]) <= latitudeIndex {
+				grid[currentVar][timeIndex] = append(grid[currentVar][timeIndex], nil)
+			}
+			grid[currentVar][timeIndex][latitudeIndex] = row
+		case line[0] >= '0' && line[0] <= '9', line[0] == '-':
+			// The values of one dimensional variables like the time axis are not part of the grid
+			continue
+		default:
+			currentVar = strings.Split(line, ",")[0]
+		}
+	}
+	return grid, nil
+}
+
+// Get the value of a variable at a time and grid point of the window, or the fill value when the
+// response did not include it
+func (g modelGrid) value(variable string, timeIndex, latitudeIndex, longitudeIndex int) float64 {
+	times := g[variable]
+	if timeIndex >= len(times) || latitudeIndex >= len(times[timeIndex]) || longitudeIndex >= len(times[timeIndex][latitudeIndex]) {
+		return modelFillValue
+	}
+	return times[timeIndex][latitudeIndex][longitudeIndex]
+}
+
+// Returns if the grid point has data for any variable at any time. Wave models fill every value of
+// land points, while the swell partitions of ocean points are filled only when they are absent.
+func (g modelGrid) hasData(latitudeIndex, longitudeIndex int) bool {
+	for variable, times := range g {
+		for timeIndex, _ := range times {
+			if !isModelFillValue(g.value(variable, timeIndex, latitudeIndex, longitudeIndex)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Chooses the grid points of the window to take the data at the location from, and their weights
+func (n NOAAModel) chooseGridPoints(loc Location, window modelGridWindow, grid modelGrid, options GridOptions) ([]ModelGridPoint, error) {
+	latitudePosition, longitudePosition := n.gridPosition(loc)
+	latitudeIndex, longitudeIndex := int(math.Floor(latitudePosition)), int(math.Floor(longitudePosition))
+
+	inWindow := func(latitude, longitude int) bool {
+		return latitude >= window.latitudeStart && latitude <= window.latitudeEnd &&
+			longitude >= window.longitudeStart && longitude <= window.longitudeEnd
+	}
+	newPoint := func(latitude, longitude int, weight float64) ModelGridPoint {
+		return ModelGridPoint{
+			LatitudeIndex:  latitude,
+			LongitudeIndex: longitude,
+			Location:       n.gridLocation(latitude, longitude),
+			Weight:         weight,
+		}
+	}
+
+	points := []ModelGridPoint{}
+	if options.Interpolation == BilinearInterpolation || options.Interpolation == InverseDistanceInterpolation {
+		latitudeFraction := latitudePosition - float64(latitudeIndex)
+		longitudeFraction := longitudePosition - float64(longitudeIndex)
+
+		totalWeight := 0.0
+		for latitudeOffset := 0; latitudeOffset <= 1; latitudeOffset++ {
+			for longitudeOffset := 0; longitudeOffset <= 1; longitudeOffset++ {
+				latitude, longitude := latitudeIndex+latitudeOffset, longitudeIndex+longitudeOffset
+				if !inWindow(latitude, longitude) || !grid.hasData(latitude-window.latitudeStart, longitude-window.longitudeStart) {
+					continue
+				}
+
+				point := newPoint(latitude, longitude, 0)
+				if options.Interpolation == BilinearInterpolation {
+					point.Weight = math.Abs(1.0-float64(latitudeOffset)-latitudeFraction) * math.Abs(1.0-float64(longitudeOffset)-longitudeFraction)
+				} else if distance := loc.DistanceTo(point.Location); distance < 1e-6 {
+					// The location is on the grid point, so it is the only one needed
+					point.Weight = 1.0
+					return []ModelGridPoint{point}, nil
+				} else {
+					point.Weight = 1.0 / math.Pow(distance, 2)
+				}
+
+				if point.Weight > 0 {
+					points = append(points, point)
+					totalWeight += point.Weight
+				}
+			}
+		}
+
+		for index, _ := range points {
+			points[index].Weight /= totalWeight
+		}
+		if len(points) > 0 {
+			return points, nil
+		}
+	} else if options.Interpolation != NearestOceanPoint {
+		return nil, errors.New("Unknown grid interpolation, could not extract the model data")
+	}
+
+	// Take the closest point with data in the window
+	closestDistance := math.Inf(1)
+	for latitude := window.latitudeStart; latitude <= window.latitudeEnd; latitude++ {
+		for longitude := window.longitudeStart; longitude <= window.longitudeEnd; longitude++ {
+			if !grid.hasData(latitude-window.latitudeStart, longitude-window.longitudeStart) {
+				continue
+			}
+
+			point := newPoint(latitude, longitude, 1.0)
+			if distance := loc.DistanceTo(point.Location); distance < closestDistance {
+				closestDistance = distance
+				points = []ModelGridPoint{point}
+			}
+		}
+	}
+	if len(points) == 0 {
+		return nil, errors.New("No model grid point with data near the location, could not extract the model data")
+	}
+	return points, nil
+}
+
+// Combines the values of the chosen grid points into a single series for each variable. Points without
+// a value at a time are left out and the weights of the others rescaled. When none of the points have a
+// value the fill value is kept, as the model does.
+func (g modelGrid) interpolate(window modelGridWindow, points []ModelGridPoint) ModelDataMap {
+	modelData := ModelDataMap{}
+	for variable, times := range g {
+		series := make([]float64, len(times))
+		for timeIndex, _ := range times {
+			total, cosine, sine, totalWeight := 0.0, 0.0, 0.0, 0.0
+			for _, point := range points {
+				value := g.value(variable, timeIndex, point.LatitudeIndex-window.latitudeStart, point.LongitudeIndex-window.longitudeStart)
+				if isModelFillValue(value) {
+					continue
+				}
+
+				total += point.Weight * value
+				cosine += point.Weight * math.Cos(value*math.Pi/180.0)
+				sine += point.Weight * math.Sin(value*math.Pi/180.0)
+				totalWeight += point.Weight
+			}
+
+			switch {
+			case totalWeight <= 0:
+				series[timeIndex] = modelFillValue
+			case modelDirectionVariables[variable]:
+				series[timeIndex] = NormalizeAbsoluteLongitude(math.Atan2(sine, cosine) * 180.0 / math.Pi)
+			default:
+				series[timeIndex] = total / totalWeight
+			}
+		}
+		modelData[variable] = series
+	}
+	return modelData
+}
+
+// Parses a response for the block of grid points around a location, as fetched with the stencil urls of
+// the models, and extracts the data at the location following the options. Useful for implementing your
+// own network fetching.
+func InterpolateRawModelData(loc Location, model NOAAModel, rawData []byte, options GridOptions) (*ModelData, error) {
+	grid, parseErr := parseRawModelGrid(rawData)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	window := model.gridWindow(loc, options.SearchRadius)
+	points, pointsErr := model.chooseGridPoints(loc, window, grid, options)
+	if pointsErr != nil {
+		return nil, pointsErr
+	}
+
+	// Interpolate the variables by their forecast names, so directions the dataset names differently
+	// are still interpolated around the compass
+	for variable, name := range model.Variables {
+		if values, ok := grid[name]; ok && name != variable {
+			delete(grid, name)
+			grid[variable] = values
+		}
+	}
+
+	modelDataContainer := grid.interpolate(window, points)
+	if times, ok := parseRawModelData(rawData)["time"]; ok {
+		for variable, series := range modelDataContainer {
+			if len(series) != len(times) {
+				return nil, fmt.Errorf("Model response has %d times but %d steps of %s, could not parse", len(times), len(series), variable)
+			}
+		}
+		modelDataContainer["time"] = times
+	}
+
+	modelData := &ModelData{
+		Location:   loc,
+		Model:      model,
+		Data:       modelDataContainer,
+		GridPoints: points,
+	}
+	return modelData, nil
+}
@@ -0,0 +1,199 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A one degree model with a three by three block of land in the bottom left corner
+var testGridModel = NOAAModel{
+	Name:               "test",
+	BottomLeftLocation: NewLocationForLatLong(0.0, 280.0),
+	TopRightLocation:   NewLocationForLatLong(10.0, 290.0),
+	LocationResolution: 1.0,
+	TimeResolution:     0.125,
+	ModelRun:           time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC),
+}
+
+const testGridData = `htsgwsfc, [1][4][4]
+[0][0], 9.999E20, 9.999E20, 9.999E20, 1.0
+[0][1], 9.999E20, 9.999E20, 2.0, 1.0
+[0][2], 9.999E20, 1.0, 4.0, 1.0
+[0][3], 1.0, 1.0, 1.0, 1.0
+
+dirpwsfc, [1][4][4]
+[0][0], 9.999E20, 9.999E20, 9.999E20, 90.0
+[0][1], 9.999E20, 9.999E20, 350.0, 90.0
+[0][2], 9.999E20, 10.0, 10.0, 90.0
+[0][3], 90.0, 90.0, 90.0, 90.0
+
+time, [1]
+736619.5
+`
+
+func TestGridInterpolationSkipsLand(t *testing.T) {
+	loc := NewLocationForLatLong(1.25, -78.5)
+
+	modelData, dataErr := InterpolateRawModelData(loc, testGridModel, []byte(testGridData), GridOptions{Interpolation: BilinearInterpolation, SearchRadius: 1})
+	if dataErr != nil {
+		fmt.Println(dataErr)
+		t.FailNow()
+	}
+
+	// The land corner is left out and the other three weighted 0.6, 0.2 and 0.2
+	if len(modelData.GridPoints) != 3 {
+		fmt.Println("Land grid point should not be used:", modelData.GridPoints)
+		t.FailNow()
+	}
+	if height := modelData.Data["htsgwsfc"][0]; math.Abs(height-2.2) > 1e-9 {
+		fmt.Println("Bilinear wave height should be 2.2, got", height)
+		t.FailNow()
+	}
+	if direction := modelData.Data["dirpwsfc"][0]; direction > 10.0 && direction < 350.0 {
+		fmt.Println("Directions should be interpolated around north, got", direction)
+		t.FailNow()
+	}
+	if len(modelData.Data["time"]) != 1 {
+		fmt.Println("Time axis should be kept")
+		t.FailNow()
+	}
+
+	nearestData, nearestErr := InterpolateRawModelData(loc, testGridModel, []byte(testGridData), GridOptions{Interpolation: NearestOceanPoint, SearchRadius: 1})
+	if nearestErr != nil {
+		fmt.Println(nearestErr)
+		t.FailNow()
+	}
+	if len(nearestData.GridPoints) != 1 || nearestData.GridPoints[0].LatitudeIndex != 1 || nearestData.GridPoints[0].LongitudeIndex != 2 {
+		fmt.Println("Nearest ocean point should be at [1][2]:", nearestData.GridPoints)
+		t.FailNow()
+	}
+	if nearestData.Data["htsgwsfc"][0] != 2.0 {
+		fmt.Println("Nearest ocean point wave height should be 2.0")
+		t.FailNow()
+	}
+
+	// A spot surrounded by land falls back to the nearest ocean point
+	landData, landErr := InterpolateRawModelData(NewLocationForLatLong(0.5, -79.1), testGridModel, []byte(testGridData), GridOptions{Interpolation: BilinearInterpolation, SearchRadius: 1})
+	if landErr != nil {
+		fmt.Println(landErr)
+		t.FailNow()
+	}
+	if len(landData.GridPoints) != 1 || landData.GridPoints[0].LatitudeIndex != 1 || landData.GridPoints[0].LongitudeIndex != 2 {
+		fmt.Println("Spot surrounded by land should use the nearest ocean point:", landData.GridPoints)
+		t.FailNow()
+	}
+}
+
+// A wind grid with its map vectors, which repeat the time axis after the values of each variable
+const testWindGridData = `time, [2]
+736619.5, 736619.625
+
+ugrd10m, [2][2][2]
+[0][0], 3.0, 3.0
+[0][1], 3.0, 3.0
+[1][0], 4.0, 4.0
+[1][1], 4.0, 4.0
+
+time, [2]
+736619.5, 736619.625
+
+lat, [2]
+1.0, 2.0
+
+lon, [2]
+281.0, 282.0
+
+vgrd10m, [2][2][2]
+[0][0], 0.0, 0.0
+[0][1], 0.0, 0.0
+[1][0], 0.0, 0.0
+[1][1], 0.0, 0.0
+
+time, [2]
+736619.5, 736619.625
+
+lat, [2]
+1.0, 2.0
+
+lon, [2]
+281.0, 282.0
+`
+
+func TestWindGridWithMaps(t *testing.T) {
+	model := &WindModel{NOAAModel: testGridModel, ModelType: GFS}
+	loc := NewLocationForLatLong(1.5, -78.5)
+
+	modelData, dataErr := InterpolateRawModelData(loc, model.NOAAModel, []byte(testWindGridData), GridOptions{Interpolation: BilinearInterpolation})
+	if dataErr != nil {
+		fmt.Println(dataErr)
+		t.FailNow()
+	}
+	if len(modelData.Data["time"]) != 2 || modelData.Data["ugrd10m"][1] != 4.0 {
+		fmt.Println("Only the time axis should be kept from the map vectors:", modelData.Data["time"])
+		t.FailNow()
+	}
+
+	modelData.Data = model.forecastData(modelData.Data, windVariables)
+	forecast := WindForecastFromModelData(modelData)
+	decoded := DecodeModelTime(736619.5)
+	if len(forecast.ForecastData) != 2 || !forecast.ForecastData[1].Date.Equal(decoded.Add(3*time.Hour)) {
+		fmt.Println("Wind forecast times should come from the time axis")
+		t.FailNow()
+	}
+
+	// A time axis that does not match the data is an error rather than replaced by the model run
+	truncated := strings.Replace(testWindGridData, "time, [2]\n736619.5, 736619.625", "time, [1]\n736619.5", 1)
+	if _, truncatedErr := InterpolateRawModelData(loc, model.NOAAModel, []byte(truncated), GridOptions{Interpolation: BilinearInterpolation}); truncatedErr == nil {
+		fmt.Println("Expected an error for a time axis that does not match the data")
+		t.FailNow()
+	}
+}
+
+func TestStencilURL(t *testing.T) {
+	model := &WaveModel{testGridModel}
+	url := model.CreateStencilURLWithEndpoints(DefaultEndpoints(), NewLocationForLatLong(1.25, -78.5), GridOptions{Interpolation: BilinearInterpolation, SearchRadius: 1}, 0, 1)
+	if !strings.Contains(url, "htsgwsfc.htsgwsfc[0:1][0:3][0:3]") {
+		fmt.Println("Stencil url should request the block around the location:", url)
+		t.FailNow()
+	}
+
+	url = model.CreateURLWithEndpoints(DefaultEndpoints(), NewLocationForLatLong(1.25, -78.5), 0, 1)
+	if !strings.Contains(url, "htsgwsfc.htsgwsfc[0:1][1][1]") {
+		fmt.Println("Point url should request a single grid point:", url)
+		t.FailNow()
+	}
+}
+
+func TestGridPositionAtHighIndices(t *testing.T) {
+	// 50N 70W lies on the 300th latitude and 180th longitude of the 10 arc-minute east coast grid
+	loc := NewLocationForLatLong(50.0, -70.0)
+	model := NewEastCoastWaveModel()
+
+	latitudePosition, longitudePosition := model.gridPosition(loc)
+	if latitudePosition != 300.0 || longitudePosition != 180.0 {
+		fmt.Println("Grid position is wrong:", latitudePosition, longitudePosition)
+		t.FailNow()
+	}
+	if latIndex, lngIndex := model.LocationIndices(loc); latIndex != 300 || lngIndex != 180 {
+		fmt.Println("Location indices are wrong:", latIndex, lngIndex)
+		t.FailNow()
+	}
+	if gridLoc := model.gridLocation(300, 180); math.Abs(gridLoc.Latitude-50.0) > 1e-9 || math.Abs(gridLoc.Longitude-290.0) > 1e-9 {
+		fmt.Println("Grid point location is wrong:", gridLoc)
+		t.FailNow()
+	}
+
+	// Models read from the metadata land on the same grid point despite the rounded resolution
+	metadata, parseErr := ParseModelMetadata([]byte(testModelDDS), []byte(testModelDAS))
+	if parseErr != nil {
+		fmt.Println(parseErr)
+		t.FailNow()
+	}
+	if latIndex, lngIndex := metadata.NOAAModel().LocationIndices(loc); latIndex != 300 || lngIndex != 180 {
+		fmt.Println("Location indices of the dataset model are wrong:", latIndex, lngIndex)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,394 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Matches declarations of the DDS such as Float32 htsgwsfc[time = 81][lat = 331][lon = 301];
+	ddsDeclarationExpression = regexp.MustCompile(`^\w+\s+([\w.]+)((?:\s*\[\s*\w+\s*=\s*\d+\s*\])*)\s*;$`)
+	ddsDimensionExpression   = regexp.MustCompile(`\[\s*(\w+)\s*=\s*(\d+)\s*\]`)
+
+	// Matches the units GrADS puts at the end of long names, such as [m]
+	longNameUnitsExpression = regexp.MustCompile(`\[([^\]]+)\]\s*$`)
+)
+
+// An axis of the grid of a model dataset. The minimum and maximum are the first and last values along
+// the axis, so the minimum of a pressure level axis is larger than its maximum. Times are in days
+// since 1-1-1, see DecodeModelTime.
+type ModelAxis struct {
+	Name       string
+	LongName   string
+	Units      string
+	Size       int
+	Minimum    float64
+	Maximum    float64
+	Resolution float64
+}
+
+// A variable of a model dataset and the axes it is given on
+type ModelVariable struct {
+	Name       string
+	LongName   string
+	Units      string
+	FillValue  float64
+	Dimensions []string
+}
+
+// The grid and variables of a model dataset, as described by the .dds and .das of its OPeNDAP
+// endpoint on NOMADS
+type ModelMetadata struct {
+	Name        string
+	Description string
+	Latitude    ModelAxis
+	Longitude   ModelAxis
+	Time        ModelAxis
+	Level       *ModelAxis `json:",omitempty"`
+	Variables   []ModelVariable
+}
+
+// Parses the .dds and .das responses of a model dataset into its ModelMetadata. Useful for implementing
+// your own network fetching.
+func ParseModelMetadata(dds, das []byte) (*ModelMetadata, error) {
+	name, axes, variables, ddsErr := parseModelDDS(dds)
+	if ddsErr != nil {
+		return nil, ddsErr
+	}
+
+	attributes, dasErr := parseModelDAS(das)
+	if dasErr != nil {
+		return nil, dasErr
+	}
+
+	metadata := &ModelMetadata{
+		Name:        name,
+		Description: attributes["NC_GLOBAL"]["title"],
+	}
+
+	for _, axis := range axes {
+		axisAttributes := attributes[axis.Name]
+		axis.LongName = axisAttributes["long_name"]
+		axis.Units = axisAttributes["units"]
+		axis.Minimum = parseModelAxisValue(axisAttributes["minimum"])
+		axis.Maximum = parseModelAxisValue(axisAttributes["maximum"])
+		axis.Resolution = parseModelAxisValue(axisAttributes["resolution"])
+
+		// GrADS names the axes after their dimension, the names are a fallback for other servers
+		switch dimension := axisAttributes["grads_dim"]; {
+		case dimension == "y" || (dimension == "" && axis.Name == "lat"):
+			metadata.Latitude = axis
+		case dimension == "x" || (dimension == "" && axis.Name == "lon"):
+			metadata.Longitude = axis
+		case dimension == "t" || (dimension == "" && axis.Name == "time"):
+			metadata.Time = axis
+		case dimension == "z" || (dimension == "" && axis.Name == "lev"):
+			level := axis
+			metadata.Level = &level
+		}
+	}
+
+	switch {
+	case metadata.Latitude.Size == 0:
+		return nil, errors.New("Model metadata does not describe a latitude axis")
+	case metadata.Longitude.Size == 0:
+		return nil, errors.New("Model metadata does not describe a longitude axis")
+	case metadata.Time.Size == 0:
+		return nil, errors.New("Model metadata does not describe a time axis")
+	}
+
+	for _, variable := range variables {
+		variableAttributes := attributes[variable.Name]
+		variable.LongName = strings.TrimSpace(strings.Trim(variableAttributes["long_name"], "* "))
+		variable.Units = variableAttributes["units"]
+		if variable.Units == "" {
+			if match := longNameUnitsExpression.FindStringSubmatch(variable.LongName); match != nil {
+				variable.Units = match[1]
+			}
+		}
+
+		variable.FillValue = modelFillValue
+		for _, key := range []string{"_FillValue", "missing_value"} {
+			if value, parseErr := strconv.ParseFloat(variableAttributes[key], 64); parseErr == nil {
+				variable.FillValue = value
+				break
+			}
+		}
+		metadata.Variables = append(metadata.Variables, variable)
+	}
+	return metadata, nil
+}
+
+// Parses the dataset name, the axes and the gridded variables of a .dds response
+func parseModelDDS(data []byte) (string, []ModelAxis, []ModelVariable, error) {
+	name := ""
+	axes := []ModelAxis{}
+	variables := []ModelVariable{}
+	inGrid, inMaps := false, false
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) < 1 || strings.HasPrefix(line, "Dataset"):
+			continue
+		case strings.HasPrefix(line, "Grid"):
+			inGrid, inMaps = true, false
+		case line == "ARRAY:":
+			inMaps = false
+		case line == "MAPS:":
+			inMaps = true
+		case line[0] == '}':
+			if !inGrid {
+				name = strings.TrimSpace(strings.Trim(line, "};"))
+			}
+			inGrid, inMaps = false, false
+		default:
+			match := ddsDeclarationExpression.FindStringSubmatch(line)
+			if match == nil {
+				return "", nil, nil, errors.New("Unexpected line in model DDS, could not parse: " + line)
+			}
+
+			dimensions := ddsDimensionExpression.FindAllStringSubmatch(match[2], -1)
+			switch {
+			case inGrid && !inMaps:
+				variable := ModelVariable{Name: match[1]}
+				for _, dimension := range dimensions {
+					variable.Dimensions = append(variable.Dimensions, dimension[1])
+				}
+				variables = append(variables, variable)
+			case !inGrid && len(dimensions) == 1 && dimensions[0][1] == match[1]:
+				size, _ := strconv.Atoi(dimensions[0][2])
+				axes = append(axes, ModelAxis{Name: match[1], Size: size})
+			}
+		}
+	}
+
+	if name == "" {
+		return "", nil, nil, errors.New("Model DDS does not name its dataset, could not parse")
+	}
+	return name, axes, variables, nil
+}
+
+// Parses the attributes of a .das response by the variable they belong to. The values are kept
+// as strings without their quotes.
+func parseModelDAS(data []byte) (map[string]map[string]string, error) {
+	attributes := map[string]map[string]string{}
+	containers := []string{}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) < 1:
+			continue
+		case strings.HasSuffix(line, "{"):
+			container := strings.TrimSpace(strings.TrimSuffix(line, "{"))
+			containers = append(containers, container)
+			if _, ok := attributes[container]; !ok {
+				attributes[container] = map[string]string{}
+			}
+		case line == "}":
+			if len(containers) == 0 {
+				return nil, errors.New("Unbalanced braces in model DAS, could not parse")
+			}
+			containers = containers[:len(containers)-1]
+		default:
+			fields := strings.SplitN(strings.TrimSuffix(line, ";"), " ", 3)
+			if len(fields) != 3 || len(containers) == 0 {
+				return nil, errors.New("Unexpected line in model DAS, could not parse: " + line)
+			}
+			value := strings.TrimSpace(fields[2])
+			if unquoted, unquoteErr := strconv.Unquote(value); unquoteErr == nil {
+				value = unquoted
+			} else {
+				value = strings.Trim(value, "\"")
+			}
+			attributes[containers[len(containers)-1]][fields[1]] = value
+		}
+	}
+
+	if len(containers) != 0 {
+		return nil, errors.New("Unbalanced braces in model DAS, could not parse")
+	}
+	return attributes, nil
+}
+
+// Parses a value of an axis attribute. Times are given by GrADS like 00z16oct2017 or 12:30z16oct2017,
+// and are converted to days since 1-1-1. Returns a missing value when the value cannot be parsed.
+func parseModelAxisValue(value string) float64 {
+	if number, parseErr := strconv.ParseFloat(value, 64); parseErr == nil {
+		return number
+	}
+
+	for _, layout := range []string{"15z02Jan2006", "15:04z02Jan2006"} {
+		if date, parseErr := time.Parse(layout, strings.ToLower(value)); parseErr == nil {
+			return EncodeModelTime(date)
+		}
+	}
+	return MissingValue()
+}
+
+// Get the first run time of the model dataset, which is the start of its time axis
+func (m ModelMetadata) ModelRun() time.Time {
+	return DecodeModelTime(m.Time.Minimum)
+}
+
+// Get the variable of the dataset with the given name
+func (m ModelMetadata) Variable(name string) (ModelVariable, bool) {
+	for _, variable := range m.Variables {
+		if variable.Name == name {
+			return variable, true
+		}
+	}
+	return ModelVariable{}, false
+}
+
+// Creates and returns a NOAAModel covering the grid of the dataset. The name is the name of the
+// dataset, which includes its run, so set the name of the model before building urls with it.
+func (m ModelMetadata) NOAAModel() NOAAModel {
+	model := NOAAModel{
+		Name:               m.Name,
+		Description:        m.Description,
+		BottomLeftLocation: NewLocationForLatLong(m.Latitude.Minimum, m.Longitude.Minimum),
+		TopRightLocation:   NewLocationForLatLong(m.Latitude.Maximum, m.Longitude.Maximum),
+		LocationResolution: m.Latitude.Resolution,
+		TimeResolution:     m.Time.Resolution,
+		Units:              Metric,
+	}
+
+	// GrADS rounds the resolution, which adds up to most of a cell across a large grid, so it is
+	// taken from the extents of the grid instead
+	if m.Latitude.Size > 1 {
+		model.LocationResolution = (m.Latitude.Maximum - m.Latitude.Minimum) / float64(m.Latitude.Size-1)
+	}
+	if m.Level != nil {
+		model.MinimumAltitude = m.Level.Minimum
+		model.MaximumAltitude = m.Level.Maximum
+		model.AltitudeResolution = m.Level.Resolution
+	}
+	return model
+}
+
+// Compares the grid of a model with the grid of the dataset, and describes every difference
+// that would put the model at the wrong grid point or time step. Returns an empty slice when
+// the model matches the dataset.
+func (m ModelMetadata) Mismatches(model NOAAModel) []string {
+	mismatches := []string{}
+	compare := func(description string, value, expected, tolerance float64) {
+		if math.IsNaN(value) || math.IsNaN(expected) || math.Abs(value-expected) > tolerance {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %g but the dataset has %g", description, value, expected))
+		}
+	}
+
+	halfCell := m.Latitude.Resolution / 2.0
+	compare("Bottom latitude", model.BottomLeftLocation.Latitude, m.Latitude.Minimum, halfCell)
+	compare("Top latitude", model.TopRightLocation.Latitude, m.Latitude.Maximum, halfCell)
+	compare("Left longitude", model.BottomLeftLocation.Longitude, m.Longitude.Minimum, halfCell)
+	compare("Right longitude", model.TopRightLocation.Longitude, m.Longitude.Maximum, halfCell)
+
+	// A small error in the resolution adds up across the grid, so compare the number of grid points
+	latitudeCount, longitudeCount := model.gridSize()
+	compare("Latitude grid size", float64(latitudeCount), float64(m.Latitude.Size), 0)
+	compare("Longitude grid size", float64(longitudeCount), float64(m.Longitude.Size), 0)
+	compare("Time resolution", model.TimeResolution, m.Time.Resolution, 1e-6)
+
+	if m.Level != nil && model.AltitudeResolution != 0 {
+		compare("Minimum altitude", model.MinimumAltitude, m.Level.Minimum, 1e-6)
+		compare("Maximum altitude", model.MaximumAltitude, m.Level.Maximum, 1e-6)
+		compare("Altitude resolution", model.AltitudeResolution, m.Level.Resolution, 1e-3)
+	}
+	return mismatches
+}
+
+// Reads the grid and variables of the model dataset at the url from its .dds and .das using the
+// DefaultClient. See Client.FetchModelMetadata.
+func FetchModelMetadata(datasetURL string) (*ModelMetadata, error) {
+	return FetchModelMetadataContext(context.Background(), datasetURL)
+}
+
+// Same as FetchModelMetadata, but the requests are bound to the given context
+func FetchModelMetadataContext(ctx context.Context, datasetURL string) (*ModelMetadata, error) {
+	return DefaultClient.FetchModelMetadata(ctx, datasetURL)
+}
+
+// Reads the grid and variables of the model dataset at the url from its .dds and .das using this
+// client. The url is the dataset url without a suffix, as created by CreateDatasetURL.
+func (c *Client) FetchModelMetadata(ctx context.Context, datasetURL string) (*ModelMetadata, error) {
+	responses := [][]byte{}
+	for _, suffix := range []string{".dds", ".das"} {
+		url := datasetURL + suffix
+		rawData, fetchErr := fetchRawDataFromURL(ctx, c.fetcher(), url)
+		if fetchErr != nil {
+			return nil, modelFetchError(fetchErr)
+		}
+		if responseErr := checkModelResponse(rawData, url); responseErr != nil {
+			return nil, responseErr
+		}
+		responses = append(responses, rawData)
+	}
+	return ParseModelMetadata(responses[0], responses[1])
+}
+
+// Reads the grid and variables of the latest published run of the model using this client. The
+// run is resolved as with ResolveModelRun and recorded as the run of the model.
+func (c *Client) FetchModelMetadataForModel(ctx context.Context, model CycledModel) (*ModelMetadata, error) {
+	run, runErr := c.ResolveModelRun(ctx, model, DefaultModelRunFallbacks)
+	if runErr != nil {
+		return nil, runErr
+	}
+	return c.FetchModelMetadata(ctx, model.CreateDatasetURL(c.endpoints(), run))
+}
+
+// Creates a WaveModel for the NOMADS multigrid wave dataset of the given name, such as multi_1.at_10m,
+// with its grid read from the metadata of its latest published run
+func (c *Client) DiscoverWaveModel(ctx context.Context, name string) (*WaveModel, error) {
+	model := &WaveModel{
+		NOAAModel{
+			Name:             name,
+			CycleInterval:    6 * time.Hour,
+			PublicationDelay: 5 * time.Hour,
+		},
+	}
+
+	metadata, metadataErr := c.FetchModelMetadataForModel(ctx, model)
+	if metadataErr != nil {
+		return nil, metadataErr
+	}
+	model.NOAAModel = discoveredModel(model.NOAAModel, *metadata)
+	return model, nil
+}
+
+// Creates a WindModel for the NOMADS GFS or NAM dataset of the given name, such as gfs_0p50, with its
+// grid read from the metadata of its latest published run. The lowest level of the dataset is used.
+func (c *Client) DiscoverWindModel(ctx context.Context, name string, modelType WindModelType) (*WindModel, error) {
+	model := &WindModel{
+		NOAAModel: NOAAModel{
+			Name:          name,
+			CycleInterval: 6 * time.Hour,
+		},
+		ModelType: modelType,
+	}
+
+	metadata, metadataErr := c.FetchModelMetadataForModel(ctx, model)
+	if metadataErr != nil {
+		return nil, metadataErr
+	}
+	model.NOAAModel = discoveredModel(model.NOAAModel, *metadata)
+	return model, nil
+}
+
+// Replaces the grid of the model with the grid of the dataset, keeping its name and cycle settings.
+// The run the metadata was read from is not pinned, so the model follows the latest run.
+func discoveredModel(model NOAAModel, metadata ModelMetadata) NOAAModel {
+	discovered := metadata.NOAAModel()
+	discovered.Name = model.Name
+	discovered.TimeLocation = model.TimeLocation
+	discovered.CycleInterval = model.CycleInterval
+	discovered.PublicationDelay = model.PublicationDelay
+	return discovered
+}
@@ -0,0 +1,225 @@
+package surfnerd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testModelDDS = `Dataset {
+    Float64 time[time = 61];
+    Float64 lat[lat = 331];
+    Float64 lon[lon = 301];
+    Grid {
+     ARRAY:
+        Float32 htsgwsfc[time = 61][lat = 331][lon = 301];
+     MAPS:
+        Float64 time[time = 61];
+        Float64 lat[lat = 331];
+        Float64 lon[lon = 301];
+    } htsgwsfc;
+    Grid {
+     ARRAY:
+        Float32 dirpwsfc[time = 61][lat = 331][lon = 301];
+     MAPS:
+        Float64 time[time = 61];
+        Float64 lat[lat = 331];
+        Float64 lon[lon = 301];
+    } dirpwsfc;
+} multi_1.at_10m20171016_12z;
+`
+
+const testModelDAS = `Attributes {
+    time {
+        String grads_dim "t";
+        String grads_mapping "linear";
+        String grads_size "61";
+        String grads_min "12z16oct2017";
+        String grads_step "3hr";
+        String units "days since 1-1-1 00:00:0.0";
+        String long_name "time";
+        String minimum "12z16oct2017";
+        String maximum "12z23oct2017";
+        Float64 resolution 0.125;
+    }
+    lat {
+        String grads_dim "y";
+        String grads_mapping "linear";
+        String grads_size "331";
+        String units "degrees_north";
+        String long_name "latitude";
+        Float64 minimum 0.0;
+        Float64 maximum 55.00011;
+        Float32 resolution 0.16667;
+    }
+    lon {
+        String grads_dim "x";
+        String grads_mapping "linear";
+        String grads_size "301";
+        String units "degrees_east";
+        String long_name "longitude";
+        Float64 minimum 260.0;
+        Float64 maximum 310.00011;
+        Float32 resolution 0.16667;
+    }
+    htsgwsfc {
+        Float32 _FillValue 9.999E20;
+        Float32 missing_value 9.999E20;
+        String long_name "** surface sig height of wind waves and swell [m] ";
+    }
+    dirpwsfc {
+        Float32 _FillValue 9.999E20;
+        Float32 missing_value 9.999E20;
+        String long_name "** surface primary wave direction [deg] ";
+    }
+    NC_GLOBAL {
+        String title "WAVEWATCH III Multi-grid model: US East Coast 10 arc-min grid";
+        String Conventions "COARDS";
+        String dataType "Grid";
+    }
+}
+`
+
+func TestParseModelMetadata(t *testing.T) {
+	metadata, parseErr := ParseModelMetadata([]byte(testModelDDS), []byte(testModelDAS))
+	if parseErr != nil {
+		fmt.Println(parseErr)
+		t.FailNow()
+	}
+
+	if metadata.Name != "multi_1.at_10m20171016_12z" || metadata.Level != nil {
+		fmt.Println("Dataset name was not parsed:", metadata.Name)
+		t.FailNow()
+	}
+	if metadata.Latitude.Size != 331 || metadata.Longitude.Size != 301 || metadata.Time.Size != 61 {
+		fmt.Println("Axis sizes were not parsed")
+		t.FailNow()
+	}
+	if metadata.Latitude.Maximum != 55.00011 || metadata.Longitude.Minimum != 260.0 || metadata.Longitude.Units != "degrees_east" {
+		fmt.Println("Axis extents were not parsed")
+		t.FailNow()
+	}
+	if !metadata.ModelRun().Equal(time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC)) {
+		fmt.Println("Time axis should start at the model run, got", metadata.ModelRun())
+		t.FailNow()
+	}
+
+	height, ok := metadata.Variable("htsgwsfc")
+	if !ok || height.Units != "m" || height.FillValue != 9.999e20 || len(height.Dimensions) != 3 || height.Dimensions[1] != "lat" {
+		fmt.Println("Variable was not parsed:", height)
+		t.FailNow()
+	}
+	if !strings.HasPrefix(height.LongName, "surface sig height") || len(metadata.Variables) != 2 {
+		fmt.Println("Variable long names were not parsed:", height.LongName)
+		t.FailNow()
+	}
+
+	model := metadata.NOAAModel()
+	if len(metadata.Mismatches(model)) != 0 {
+		fmt.Println("Model built from the metadata should match it:", metadata.Mismatches(model))
+		t.FailNow()
+	}
+
+	// A resolution that is slightly off puts the northern points a grid cell out
+	model.LocationResolution = 0.17
+	if len(metadata.Mismatches(model)) != 2 {
+		fmt.Println("Expected both grid sizes to mismatch:", metadata.Mismatches(model))
+		t.FailNow()
+	}
+}
+
+func TestDiscoverWaveModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".dds"):
+			fmt.Fprint(w, testModelDDS)
+		case strings.HasSuffix(r.URL.Path, ".das"):
+			fmt.Fprint(w, testModelDAS)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&HTTPFetcher{Client: server.Client()})
+	client.Endpoints = Endpoints{NDBC: server.URL, NOMADS: server.URL}
+
+	model, discoverErr := client.DiscoverWaveModel(context.Background(), "multi_1.at_10m")
+	if discoverErr != nil {
+		fmt.Println(discoverErr)
+		t.FailNow()
+	}
+	if model.Name != "multi_1.at_10m" || !model.ModelRun.IsZero() || model.TopRightLocation.Latitude != 55.00011 {
+		fmt.Println("Discovered model should keep its name, take the dataset grid and not pin the run")
+		t.FailNow()
+	}
+	if !model.ContainsLocation(NewLocationForLatLong(41.0, -71.0)) {
+		fmt.Println("Discovered model should cover the east coast")
+		t.FailNow()
+	}
+}
+
+// Creates the .dds and .das of a dataset with the given axes, each given as its name, grads dimension,
+// size, minimum, maximum and resolution as recorded from NOMADS
+func newTestModelMetadataResponses(name string, axes [][]string) ([]byte, []byte) {
+	dds := "Dataset {\n"
+	das := "Attributes {\n"
+	for _, axis := range axes {
+		dds += fmt.Sprintf("    Float64 %s[%s = %s];\n", axis[0], axis[0], axis[2])
+		das += fmt.Sprintf("    %s {\n        String grads_dim \"%s\";\n        String grads_size \"%s\";\n", axis[0], axis[1], axis[2])
+		das += fmt.Sprintf("        Float64 minimum %s;\n        Float64 maximum %s;\n        Float64 resolution %s;\n    }\n", axis[3], axis[4], axis[5])
+	}
+	return []byte(dds + "} " + name + ";\n"), []byte(das + "}\n")
+}
+
+func TestBuiltInModelsMatchDatasets(t *testing.T) {
+	waveTime := []string{"time", "t", "61", "736619.5", "736627.5", "0.125"}
+	datasets := []struct {
+		model NOAAModel
+		axes  [][]string
+	}{
+		{NewWestCoastWaveModel().NOAAModel, [][]string{
+			waveTime,
+			{"lat", "y", "151", "25.0", "50.00005", "0.16667"},
+			{"lon", "x", "241", "210.0", "250.00008", "0.16667"},
+		}},
+		{NewPacificIslandsWaveModel().NOAAModel, [][]string{
+			waveTime,
+			{"lat", "y", "301", "-20.0", "30.0001", "0.16667"},
+			{"lon", "x", "511", "130.0", "215.00017", "0.16667"},
+		}},
+		{NewGFSWindModel().NOAAModel, [][]string{
+			{"time", "t", "81", "736619.5", "736629.5", "0.125"},
+			{"lev", "z", "47", "1000.0", "1.0", "21.717392"},
+			{"lat", "y", "361", "-90.0", "90.0", "0.5"},
+			{"lon", "x", "720", "0.0", "359.5", "0.5"},
+		}},
+	}
+
+	metadata, parseErr := ParseModelMetadata([]byte(testModelDDS), []byte(testModelDAS))
+	if parseErr != nil {
+		fmt.Println(parseErr)
+		t.FailNow()
+	}
+	if mismatches := metadata.Mismatches(NewEastCoastWaveModel().NOAAModel); len(mismatches) != 0 {
+		fmt.Println("East coast model does not match its dataset:", mismatches)
+		t.FailNow()
+	}
+
+	for _, dataset := range datasets {
+		dds, das := newTestModelMetadataResponses(dataset.model.Name, dataset.axes)
+		metadata, parseErr := ParseModelMetadata(dds, das)
+		if parseErr != nil {
+			fmt.Println(parseErr)
+			t.FailNow()
+		}
+		if mismatches := metadata.Mismatches(dataset.model); len(mismatches) != 0 {
+			fmt.Println(dataset.model.Name, "does not match its dataset:", mismatches)
+			t.FailNow()
+		}
+	}
+}
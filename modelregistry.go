@@ -0,0 +1,331 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolutions closer than this are treated as the same when choosing a model
+const modelResolutionTolerance = 1e-9
+
+// Holds the wave and wind models forecasts can be fetched from. Models are chosen for a location by
+// their resolution, so registering a finer model for a region makes it the one used there. A registry
+// is safe to use from multiple goroutines.
+type ModelRegistry struct {
+	mutex      sync.RWMutex
+	waveModels []*WaveModel
+	windModels []*WindModel
+}
+
+// The registry used by the package level functions and by any Client that does not have its own.
+// It starts out with the models built into the library.
+var DefaultModelRegistry = NewDefaultModelRegistry()
+
+// Creates and returns an empty ModelRegistry
+func NewModelRegistry() *ModelRegistry {
+	return &ModelRegistry{}
+}
+
+// Creates and returns a ModelRegistry holding the models built into the library
+func NewDefaultModelRegistry() *ModelRegistry {
+	registry := NewModelRegistry()
+	for _, model := range []*WaveModel{NewEastCoastWaveModel(), NewWestCoastWaveModel(), NewPacificIslandsWaveModel()} {
+		registry.RegisterWaveModel(model)
+	}
+	registry.RegisterWindModel(NewGFSWindModel())
+	return registry
+}
+
+// Copies the model with its own variable mapping, so models handed in or out of a registry do not
+// share the map with it
+func copyRegisteredModel(model NOAAModel) NOAAModel {
+	if model.Variables != nil {
+		variables := make(map[string]string, len(model.Variables))
+		for variable, name := range model.Variables {
+			variables[variable] = name
+		}
+		model.Variables = variables
+	}
+	return model
+}
+
+func validateRegisteredModel(model NOAAModel) error {
+	switch {
+	case model.Name == "":
+		return errors.New("Models must have a name to be registered")
+	case model.LocationResolution <= 0:
+		return fmt.Errorf("Model %s must have a positive location resolution", model.Name)
+	case model.TimeResolution <= 0:
+		return fmt.Errorf("Model %s must have a positive time resolution", model.Name)
+	case model.TopRightLocation.Latitude <= model.BottomLeftLocation.Latitude || model.TopRightLocation.Longitude <= model.BottomLeftLocation.Longitude:
+		return fmt.Errorf("Model %s must have its top right location above and east of its bottom left location", model.Name)
+	}
+	return nil
+}
+
+// Adds a wave model to the registry, replacing any wave model with the same name
+func (r *ModelRegistry) RegisterWaveModel(model *WaveModel) error {
+	if model == nil {
+		return errors.New("No wave model given to register")
+	}
+	if validateErr := validateRegisteredModel(model.NOAAModel); validateErr != nil {
+		return validateErr
+	}
+
+	registered := *model
+	registered.NOAAModel = copyRegisteredModel(model.NOAAModel)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for index, existing := range r.waveModels {
+		if existing.Name == model.Name {
+			r.waveModels[index] = &registered
+			return nil
+		}
+	}
+	r.waveModels = append(r.waveModels, &registered)
+	return nil
+}
+
+// Adds a wind model to the registry, replacing any wind model with the same name
+func (r *ModelRegistry) RegisterWindModel(model *WindModel) error {
+	if model == nil {
+		return errors.New("No wind model given to register")
+	}
+	if validateErr := validateRegisteredModel(model.NOAAModel); validateErr != nil {
+		return validateErr
+	}
+
+	registered := *model
+	registered.NOAAModel = copyRegisteredModel(model.NOAAModel)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for index, existing := range r.windModels {
+		if existing.Name == model.Name {
+			r.windModels[index] = &registered
+			return nil
+		}
+	}
+	r.windModels = append(r.windModels, &registered)
+	return nil
+}
+
+// Get copies of the registered wave models in the order they were registered
+func (r *ModelRegistry) WaveModels() []*WaveModel {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	models := make([]*WaveModel, len(r.waveModels))
+	for index, model := range r.waveModels {
+		copied := *model
+		copied.NOAAModel = copyRegisteredModel(model.NOAAModel)
+		models[index] = &copied
+	}
+	return models
+}
+
+// Get copies of the registered wind models in the order they were registered
+func (r *ModelRegistry) WindModels() []*WindModel {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	models := make([]*WindModel, len(r.windModels))
+	for index, model := range r.windModels {
+		copied := *model
+		copied.NOAAModel = copyRegisteredModel(model.NOAAModel)
+		models[index] = &copied
+	}
+	return models
+}
+
+// Returns if the first model should be chosen over the second. Finer resolutions win, then higher
+// priorities, then names in alphabetical order so the choice never depends on registration order.
+func isPreferredModel(model, other NOAAModel) bool {
+	if resolutionDifference := model.LocationResolution - other.LocationResolution; resolutionDifference < -modelResolutionTolerance {
+		return true
+	} else if resolutionDifference > modelResolutionTolerance {
+		return false
+	}
+
+	if model.Priority != other.Priority {
+		return model.Priority > other.Priority
+	}
+	return model.Name < other.Name
+}
+
+// Get a copy of the registered wave model with the finest resolution that covers the location,
+// or nil if none covers it
+func (r *ModelRegistry) WaveModelForLocation(loc Location) *WaveModel {
+	var chosen *WaveModel
+	for _, model := range r.WaveModels() {
+		if model.ContainsLocation(loc) && (chosen == nil || isPreferredModel(model.NOAAModel, chosen.NOAAModel)) {
+			chosen = model
+		}
+	}
+	return chosen
+}
+
+// Get a copy of the registered wind model with the finest resolution that covers the location,
+// or nil if none covers it
+func (r *ModelRegistry) WindModelForLocation(loc Location) *WindModel {
+	var chosen *WindModel
+	for _, model := range r.WindModels() {
+		if model.ContainsLocation(loc) && (chosen == nil || isPreferredModel(model.NOAAModel, chosen.NOAAModel)) {
+			chosen = model
+		}
+	}
+	return chosen
+}
+
+// Same as WindModelForLocation, but only models of the given type are considered
+func (r *ModelRegistry) WindModelForLocationAndType(loc Location, modelType WindModelType) *WindModel {
+	var chosen *WindModel
+	for _, model := range r.WindModels() {
+		if model.ModelType == modelType && model.ContainsLocation(loc) && (chosen == nil || isPreferredModel(model.NOAAModel, chosen.NOAAModel)) {
+			chosen = model
+		}
+	}
+	return chosen
+}
+
+// The definition of a model in a registry configuration file. Locations are given as
+// {"latitude": 0, "longitude": 260}, with longitudes in degrees east as used by the dataset.
+type ModelDefinition struct {
+	Name                  string            `json:"name"`
+	Description           string            `json:"description"`
+	BottomLeftLocation    Location          `json:"bottomLeft"`
+	TopRightLocation      Location          `json:"topRight"`
+	LocationResolution    float64           `json:"resolution"`
+	TimeResolutionHours   float64           `json:"timeResolutionHours"`
+	Units                 UnitSystem        `json:"units"`
+	TimeLocation          string            `json:"timeZone"`
+	CycleHours            float64           `json:"cycleHours"`
+	PublicationDelayHours float64           `json:"publicationDelayHours"`
+	DatasetPath           string            `json:"datasetPath"`
+	Variables             map[string]string `json:"variables"`
+	Priority              int               `json:"priority"`
+
+	// Only used by wind models, where the type is gfs or nam
+	Type                 string  `json:"type"`
+	MinimumAltitude      float64 `json:"minimumAltitude"`
+	MaximumAltitude      float64 `json:"maximumAltitude"`
+	AltitudeResolution   float64 `json:"altitudeResolution"`
+	MinimumAltitudeIndex int     `json:"minimumAltitudeIndex"`
+}
+
+// The models of a registry configuration file, in the form {"waveModels": [...], "windModels": [...]}
+type ModelRegistryConfig struct {
+	WaveModels []ModelDefinition `json:"waveModels"`
+	WindModels []ModelDefinition `json:"windModels"`
+}
+
+// Creates the NOAAModel of the definition. Units default to metric and the cycle and publication
+// delay to those of the NOMADS models when they are not given.
+func (d ModelDefinition) NOAAModel() NOAAModel {
+	model := NOAAModel{
+		Name:               d.Name,
+		Description:        d.Description,
+		BottomLeftLocation: d.BottomLeftLocation,
+		TopRightLocation:   d.TopRightLocation,
+		MinimumAltitude:    d.MinimumAltitude,
+		MaximumAltitude:    d.MaximumAltitude,
+		AltitudeResolution: d.AltitudeResolution,
+		LocationResolution: d.LocationResolution,
+		TimeResolution:     d.TimeResolutionHours / 24.0,
+		Units:              d.Units,
+		TimeLocation:       d.TimeLocation,
+		CycleInterval:      time.Duration(d.CycleHours * float64(time.Hour)),
+		PublicationDelay:   time.Duration(d.PublicationDelayHours * float64(time.Hour)),
+		DatasetPath:        d.DatasetPath,
+		Variables:          d.Variables,
+		Priority:           d.Priority,
+	}
+	if model.Units == "" {
+		model.Units = Metric
+	}
+	return model
+}
+
+// Creates the WaveModel of the definition
+func (d ModelDefinition) WaveModel() *WaveModel {
+	return &WaveModel{d.NOAAModel()}
+}
+
+// Creates the WindModel of the definition. Returns an error when the type is not gfs or nam.
+func (d ModelDefinition) WindModel() (*WindModel, error) {
+	model := &WindModel{
+		NOAAModel:            d.NOAAModel(),
+		MinimumAltitudeIndex: d.MinimumAltitudeIndex,
+	}
+
+	switch strings.ToLower(d.Type) {
+	case "", "gfs":
+		model.ModelType = GFS
+	case "nam":
+		model.ModelType = NAM
+	default:
+		return nil, fmt.Errorf("Unknown wind model type %s for model %s", d.Type, d.Name)
+	}
+	return model, nil
+}
+
+// Registers every model of the configuration. The whole configuration is checked first, so when any
+// model is invalid its error is returned and none of the models are registered.
+func (r *ModelRegistry) Load(config ModelRegistryConfig) error {
+	waveModels := []*WaveModel{}
+	for _, definition := range config.WaveModels {
+		model := definition.WaveModel()
+		if validateErr := validateRegisteredModel(model.NOAAModel); validateErr != nil {
+			return validateErr
+		}
+		waveModels = append(waveModels, model)
+	}
+
+	windModels := []*WindModel{}
+	for _, definition := range config.WindModels {
+		model, modelErr := definition.WindModel()
+		if modelErr != nil {
+			return modelErr
+		}
+		if validateErr := validateRegisteredModel(model.NOAAModel); validateErr != nil {
+			return validateErr
+		}
+		windModels = append(windModels, model)
+	}
+
+	for _, model := range waveModels {
+		r.RegisterWaveModel(model)
+	}
+	for _, model := range windModels {
+		r.RegisterWindModel(model)
+	}
+	return nil
+}
+
+// Registers the models of a registry configuration file. Files ending in .yaml or .yml are read as
+// yaml with the same keys as the json, and every other file as json. See ModelRegistryConfig.
+func (r *ModelRegistry) LoadFromFile(filename string) error {
+	rawData, readErr := ioutil.ReadFile(filename)
+	if readErr != nil {
+		return readErr
+	}
+
+	config := ModelRegistryConfig{}
+	var parseErr error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		parseErr = unmarshalYAML(rawData, &config)
+	default:
+		parseErr = json.Unmarshal(rawData, &config)
+	}
+	if parseErr != nil {
+		return parseErr
+	}
+
+	return r.Load(config)
+}
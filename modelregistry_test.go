@@ -0,0 +1,239 @@
+package surfnerd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRegistryConfig = `{
+	"waveModels": [
+		{
+			"name": "multi_1.at_4m",
+			"description": "Multi-grid wave model: US East Coast 4 arc-min grid",
+			"bottomLeft": {"latitude": 15.0, "longitude": 260.0},
+			"topRight": {"latitude": 47.0, "longitude": 300.0},
+			"resolution": 0.0667,
+			"timeResolutionHours": 3,
+			"timeZone": "America/New_York",
+			"cycleHours": 6,
+			"publicationDelayHours": 5,
+			"variables": {"htsgwsfc": "hs"}
+		}
+	],
+	"windModels": [
+		{
+			"name": "nam_conusnest",
+			"type": "nam",
+			"bottomLeft": {"latitude": 12.2, "longitude": 207.1},
+			"topRight": {"latitude": 61.2, "longitude": 310.6},
+			"resolution": 0.046,
+			"timeResolutionHours": 3
+		}
+	]
+}`
+
+// The same configuration as testRegistryConfig, written in yaml
+const testRegistryYAMLConfig = `# Models added to the defaults
+waveModels:
+  - name: multi_1.at_4m
+    description: "Multi-grid wave model: US East Coast 4 arc-min grid"
+    bottomLeft: {latitude: 15.0, longitude: 260.0}
+    topRight:
+      latitude: 47.0
+      longitude: 300.0
+    resolution: 0.0667   # degrees
+    timeResolutionHours: 3
+    timeZone: 'America/New_York'
+    cycleHours: 6
+    publicationDelayHours: 5
+    variables:
+      htsgwsfc: hs
+windModels:
+- name: nam_conusnest
+  type: nam
+  bottomLeft: {latitude: 12.2, longitude: 207.1}
+  topRight: {latitude: 61.2, longitude: 310.6}
+  resolution: 0.046
+  timeResolutionHours: 3
+`
+
+func TestModelRegistryChoosesFinestModel(t *testing.T) {
+	// The west coast and pacific islands models overlap north east of Hawaii
+	overlap := NewLocationForLatLong(27.0, -147.0)
+	if model := GetWaveModelForLocation(overlap); model == nil || model.Name != "multi_1.wc_10m" {
+		fmt.Println("Expected the west coast model to win where it overlaps the pacific islands model")
+		t.FailNow()
+	}
+
+	configFile, _ := ioutil.TempFile("", "surfnerd-models")
+	defer os.Remove(configFile.Name())
+	configFile.WriteString(testRegistryConfig)
+	configFile.Close()
+
+	registry := NewDefaultModelRegistry()
+	if loadErr := registry.LoadFromFile(configFile.Name()); loadErr != nil {
+		fmt.Println(loadErr)
+		t.FailNow()
+	}
+	if len(registry.WaveModels()) != 4 || len(registry.WindModels()) != 2 {
+		fmt.Println("Configured models were not registered")
+		t.FailNow()
+	}
+
+	newport := NewLocationForLatLong(41.0, -71.5)
+	waveModel := registry.WaveModelForLocation(newport)
+	if waveModel == nil || waveModel.Name != "multi_1.at_4m" || waveModel.TimeResolutionHours() != 3 || waveModel.CycleInterval != 6*time.Hour {
+		fmt.Println("Expected the finer configured wave model to be chosen")
+		t.FailNow()
+	}
+	if model := registry.WaveModelForLocation(NewLocationForLatLong(50.0, -60.0)); model == nil || model.Name != "multi_1.at_10m" {
+		fmt.Println("Expected the coarser model outside of the configured model")
+		t.FailNow()
+	}
+	if model := registry.WindModelForLocation(newport); model == nil || model.ModelType != NAM {
+		fmt.Println("Expected the configured NAM model to be chosen for wind")
+		t.FailNow()
+	}
+	if model := registry.WindModelForLocationAndType(newport, GFS); model == nil || model.Name != "gfs_0p50" {
+		fmt.Println("Expected the GFS model when asking for GFS")
+		t.FailNow()
+	}
+
+	// Equal resolutions are decided by priority, then by name
+	twin := *waveModel
+	twin.Name = "multi_1.at_4m_copy"
+	registry.RegisterWaveModel(&twin)
+	if model := registry.WaveModelForLocation(newport); model.Name != "multi_1.at_4m" {
+		fmt.Println("Expected the tie to be broken by name")
+		t.FailNow()
+	}
+	twin.Priority = 1
+	registry.RegisterWaveModel(&twin)
+	if model := registry.WaveModelForLocation(newport); model.Name != "multi_1.at_4m_copy" || len(registry.WaveModels()) != 5 {
+		fmt.Println("Expected the tie to be broken by priority and the model to be replaced")
+		t.FailNow()
+	}
+
+	// The models handed out are copies, so pinning a run does not change the registry
+	waveModel.SetModelRun(time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC))
+	if !registry.WaveModelForLocation(NewLocationForLatLong(50.0, -60.0)).ModelRun.IsZero() || !registry.WaveModels()[3].ModelRun.IsZero() {
+		fmt.Println("Registered models should not be changed through the models handed out")
+		t.FailNow()
+	}
+
+	if registry.RegisterWaveModel(&WaveModel{NOAAModel{Name: "broken"}}) == nil {
+		fmt.Println("Models without a grid should not be registered")
+		t.FailNow()
+	}
+	invalidConfig := ModelRegistryConfig{
+		WaveModels: []ModelDefinition{{Name: "valid", BottomLeftLocation: NewLocationForLatLong(0, 0), TopRightLocation: NewLocationForLatLong(1, 1), LocationResolution: 0.5, TimeResolutionHours: 3}},
+		WindModels: []ModelDefinition{{Name: "hrrr", Type: "hrrr"}},
+	}
+	if registry.Load(invalidConfig) == nil {
+		fmt.Println("Unknown wind model types should not be registered")
+		t.FailNow()
+	}
+	if len(registry.WaveModels()) != 5 {
+		fmt.Println("No model of an invalid configuration should be registered")
+		t.FailNow()
+	}
+}
+
+func TestModelRegistryLoadsYAML(t *testing.T) {
+	jsonFile, _ := ioutil.TempFile("", "surfnerd-models-*.json")
+	defer os.Remove(jsonFile.Name())
+	jsonFile.WriteString(testRegistryConfig)
+	jsonFile.Close()
+
+	yamlFile, _ := ioutil.TempFile("", "surfnerd-models-*.yaml")
+	defer os.Remove(yamlFile.Name())
+	yamlFile.WriteString(testRegistryYAMLConfig)
+	yamlFile.Close()
+
+	jsonRegistry, yamlRegistry := NewModelRegistry(), NewModelRegistry()
+	if loadErr := jsonRegistry.LoadFromFile(jsonFile.Name()); loadErr != nil {
+		fmt.Println(loadErr)
+		t.FailNow()
+	}
+	if loadErr := yamlRegistry.LoadFromFile(yamlFile.Name()); loadErr != nil {
+		fmt.Println(loadErr)
+		t.FailNow()
+	}
+	if len(yamlRegistry.WaveModels()) != 1 || len(yamlRegistry.WindModels()) != 1 {
+		fmt.Println("Configured yaml models were not registered")
+		t.FailNow()
+	}
+
+	jsonWave, yamlWave := jsonRegistry.WaveModels()[0], yamlRegistry.WaveModels()[0]
+	if !reflect.DeepEqual(jsonWave, yamlWave) || yamlWave.Description != "Multi-grid wave model: US East Coast 4 arc-min grid" {
+		fmt.Println("The yaml wave model does not match the json one:", yamlWave)
+		t.FailNow()
+	}
+	if !reflect.DeepEqual(jsonRegistry.WindModels()[0], yamlRegistry.WindModels()[0]) {
+		fmt.Println("The yaml wind model does not match the json one:", yamlRegistry.WindModels()[0])
+		t.FailNow()
+	}
+
+	invalidFile, _ := ioutil.TempFile("", "surfnerd-models-*.yml")
+	defer os.Remove(invalidFile.Name())
+	invalidFile.WriteString("waveModels:\n  - name: broken\n      resolution: 0.5\n")
+	invalidFile.Close()
+	if NewModelRegistry().LoadFromFile(invalidFile.Name()) == nil {
+		fmt.Println("Expected an error for badly indented yaml")
+		t.FailNow()
+	}
+}
+
+func TestModelRegistryVariableMapping(t *testing.T) {
+	registry := NewModelRegistry()
+	registry.Load(ModelRegistryConfig{WaveModels: []ModelDefinition{{
+		Name:                "custom",
+		BottomLeftLocation:  NewLocationForLatLong(40.0, 280.0),
+		TopRightLocation:    NewLocationForLatLong(42.0, 290.0),
+		LocationResolution:  0.5,
+		TimeResolutionHours: 1,
+		DatasetPath:         "/dods/custom/%[2]s/%[1]s_%[3]s",
+		Variables:           map[string]string{"htsgwsfc": "hs", "dirpwsfc": "dp"},
+	}}})
+
+	model := registry.WaveModelForLocation(NewLocationForLatLong(41.0, -75.0))
+	if model == nil {
+		fmt.Println("Expected the custom model to cover the location")
+		t.FailNow()
+	}
+
+	// The variable mapping is copied too, so changing it does not change the registry
+	model.Variables["htsgwsfc"] = "changed"
+	if registry.WaveModels()[0].Variables["htsgwsfc"] != "hs" {
+		fmt.Println("Registered variable mappings should not be changed through the models handed out")
+		t.FailNow()
+	}
+	model.Variables["htsgwsfc"] = "hs"
+
+	model.SetModelRun(time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC))
+	url := model.CreateURLWithEndpoints(DefaultEndpoints(), NewLocationForLatLong(41.0, -75.0), 0, 1)
+	if !strings.Contains(url, "/dods/custom/20171016/custom_12z.ascii?time[0:1],dp.dp[0:1][2][10],hs.hs[0:1][2][10],perpwsfc.perpwsfc") {
+		fmt.Println("Url should be built from the dataset path and variable names of the model:", url)
+		t.FailNow()
+	}
+
+	rawData := []byte("hs, [2][1][1]\n[0][0], 1.5\n[1][0], 2.0\n\ndp, [2][1][1]\n[0][0], 90.0\n[1][0], 95.0\n")
+	modelData := WaveModelDataFromRaw(NewLocationForLatLong(41.0, -75.0), model.NOAAModel, rawData)
+	if len(modelData.Data["htsgwsfc"]) != 2 || modelData.Data["htsgwsfc"][1] != 2.0 || modelData.Data["dirpwsfc"][0] != 90.0 {
+		fmt.Println("Dataset variables should be renamed to the forecast variables:", modelData.Data)
+		t.FailNow()
+	}
+	if len(modelData.Data["swell_1"]) != 2 || !isModelFillValue(modelData.Data["swell_1"][0]) {
+		fmt.Println("Variables missing from the dataset should be filled")
+		t.FailNow()
+	}
+	if forecast := WaveForecastFromModelData(modelData); len(forecast.ForecastData) != 2 {
+		fmt.Println("Forecast should be built from the renamed variables")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,240 @@
+package surfnerd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Returned inside a ParseError when a row does not have one value for every header column
+	ErrColumnCountMismatch = errors.New("Row does not match the header columns")
+
+	// Returned inside a ParseError when a value that is not missing is not a number
+	ErrInvalidValue = errors.New("Value is not a number")
+
+	// Returned inside a ParseError when a required column is not in the header
+	ErrMissingColumn = errors.New("Required column is missing from the header")
+)
+
+// Describes a value in a NDBC text file that could not be parsed. Line is the line number in the
+// file starting from 1, and Column is the header name of the column the value belongs to.
+type ParseError struct {
+	Line   int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (p *ParseError) Error() string {
+	if p.Value == "" {
+		return fmt.Sprintf("Line %d, column %s: %v", p.Line, p.Column, p.Err)
+	}
+	return fmt.Sprintf("Line %d, column %s: %v (%q)", p.Line, p.Column, p.Err, p.Value)
+}
+
+func (p *ParseError) Unwrap() error {
+	return p.Err
+}
+
+// A single data line of a NDBC text file
+type ndbcRow struct {
+	Line   int
+	Fields []string
+}
+
+// A NDBC realtime text file split into its header and data lines. Values are looked up by the
+// column names in the header, so a station that leaves out a column or a file that gains a new
+// one still parses correctly.
+type ndbcTable struct {
+	Columns []string
+	Units   []string
+	Rows    []ndbcRow
+
+	columnIndices map[string]int
+}
+
+// Column names used in the older NDBC archive files, and the names they have in current files
+var ndbcColumnAliases = map[string]string{
+	"YYYY": "YY",
+	"WD":   "WDIR",
+	"BAR":  "PRES",
+}
+
+// Splits a NDBC text file into its header, unit and data lines. The first commented line holds
+// the column names and the second, if there is one, holds the units of each column. Older archive
+// files do not comment their header and have no unit line, so an uncommented first line that does
+// not start with a number is also taken as the header. Old column names are renamed to their
+// current names.
+func parseNDBCTable(rawData string) (*ndbcTable, error) {
+	table := &ndbcTable{columnIndices: map[string]int{}}
+
+	for lineIndex, line := range strings.Split(rawData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "#") {
+			fields[0] = strings.TrimPrefix(fields[0], "#")
+			if fields[0] == "" {
+				fields = fields[1:]
+			}
+
+			if table.Columns == nil {
+				table.Columns = fields
+			} else if table.Units == nil {
+				table.Units = fields
+			}
+			continue
+		}
+
+		if _, numberErr := strconv.ParseFloat(fields[0], 64); table.Columns == nil && numberErr != nil {
+			table.Columns = fields
+			continue
+		} else if table.Columns == nil {
+			return nil, &ParseError{Line: lineIndex + 1, Column: "header", Err: ErrMissingColumn}
+		} else if len(fields) != len(table.Columns) {
+			column := "end of line"
+			if len(fields) < len(table.Columns) {
+				column = table.Columns[len(fields)]
+			}
+			return nil, &ParseError{Line: lineIndex + 1, Column: column, Value: line, Err: ErrColumnCountMismatch}
+		}
+
+		table.Rows = append(table.Rows, ndbcRow{Line: lineIndex + 1, Fields: fields})
+	}
+
+	for index, column := range table.Columns {
+		if alias, ok := ndbcColumnAliases[column]; ok {
+			table.Columns[index] = alias
+			column = alias
+		}
+		table.columnIndices[column] = index
+	}
+
+	return table, nil
+}
+
+// Returns if the table has a column with the given name
+func (t *ndbcTable) hasColumn(column string) bool {
+	_, ok := t.columnIndices[column]
+	return ok
+}
+
+// Returns an error naming the first of the given columns missing from the header
+func (t *ndbcTable) requireColumns(columns ...string) error {
+	for _, column := range columns {
+		if !t.hasColumn(column) {
+			return &ParseError{Line: 1, Column: column, Err: ErrMissingColumn}
+		}
+	}
+	return nil
+}
+
+// Get the unit of a column, or an empty string if the file has no unit line
+func (t *ndbcTable) unit(column string) string {
+	index, ok := t.columnIndices[column]
+	if !ok || index >= len(t.Units) {
+		return ""
+	}
+	return t.Units[index]
+}
+
+// Get the raw text of a column in a row, or "MM" if the table does not have the column
+func (t *ndbcTable) text(row ndbcRow, column string) string {
+	index, ok := t.columnIndices[column]
+	if !ok {
+		return "MM"
+	}
+	return row.Fields[index]
+}
+
+// Get the value of a column in a row. Missing markers, sentinel values and columns the
+// file does not have are returned as missing values.
+func (t *ndbcTable) float(row ndbcRow, column string, sentinel float64) (float64, error) {
+	rawValue := t.text(row, column)
+	if rawValue == "MM" {
+		return MissingValue(), nil
+	}
+
+	if _, parseErr := strconv.ParseFloat(rawValue, 64); parseErr != nil {
+		return MissingValue(), &ParseError{Line: row.Line, Column: column, Value: rawValue, Err: ErrInvalidValue}
+	}
+	return parseNDBCValue(rawValue, sentinel), nil
+}
+
+// Get the observation time of a row from its date and time columns. Older archive files have
+// two digit years in the 1900s and no minute column, in which case the observation is on the hour.
+// Seconds are only read from files that have a seconds column.
+func (t *ndbcTable) date(row ndbcRow) (time.Time, error) {
+	dateColumns := []string{"YY", "MM", "DD", "hh", "mm", "ss"}
+	dateValues := make([]int, len(dateColumns))
+	for index, column := range dateColumns {
+		if (column == "mm" || column == "ss") && !t.hasColumn(column) {
+			continue
+		}
+
+		rawValue := t.text(row, column)
+		value, parseErr := strconv.Atoi(rawValue)
+		if parseErr != nil {
+			return time.Time{}, &ParseError{Line: row.Line, Column: column, Value: rawValue, Err: ErrInvalidValue}
+		}
+		dateValues[index] = value
+	}
+	if dateValues[0] < 100 {
+		dateValues[0] += 1900
+	}
+
+	return time.Date(dateValues[0], time.Month(dateValues[1]), dateValues[2], dateValues[3], dateValues[4], dateValues[5], 0, time.UTC), nil
+}
+
+// Collects the values of several columns of a row, keeping the first error found
+type ndbcRowReader struct {
+	table *ndbcTable
+	row   ndbcRow
+	err   error
+}
+
+func (t *ndbcTable) reader(row ndbcRow) *ndbcRowReader {
+	return &ndbcRowReader{table: t, row: row}
+}
+
+func (r *ndbcRowReader) float(column string, sentinel float64) float64 {
+	value, parseErr := r.table.float(r.row, column, sentinel)
+	if parseErr != nil && r.err == nil {
+		r.err = parseErr
+	}
+	return value
+}
+
+func (r *ndbcRowReader) date() time.Time {
+	date, parseErr := r.table.date(r.row)
+	if parseErr != nil && r.err == nil {
+		r.err = parseErr
+	}
+	return date
+}
+
+// Get the time of day in a hhmm column as the time nearest before the observation time, or a zero
+// time if it is missing. Peak times are reported without a date and can fall on the day before
+// the observation.
+func (r *ndbcRowReader) clockTime(column string, observed time.Time) time.Time {
+	clock := r.float(column, clockTimeSentinel)
+	if IsMissing(clock) {
+		return time.Time{}
+	}
+
+	hour, minute := int(clock)/100, int(clock)%100
+	clockTime := time.Date(observed.Year(), observed.Month(), observed.Day(), hour, minute, 0, 0, observed.Location())
+	if clockTime.After(observed) {
+		clockTime = clockTime.AddDate(0, 0, -1)
+	}
+	return clockTime
+}
+
+func (r *ndbcRowReader) text(column string) string {
+	return r.table.text(r.row, column)
+}
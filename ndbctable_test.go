@@ -0,0 +1,217 @@
+package surfnerd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestStandardDataColumnsMatchedByName(t *testing.T) {
+	// No GST or TIDE column, and the temperatures are in a different order
+	rawData := `#YY  MM DD hh mm WDIR WSPD  WVHT   DPD   APD MWD   PRES  WTMP  ATMP  DEWP  VIS PTDY
+#yr  mo dy hr mn degT m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa
+2017 10 16 18 50 230  7.0   1.1     8   5.4 190 1016.1  19.1  18.2  14.0   MM -1.2
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawStandardData(rawData, -1)
+	if parseErr != nil || len(buoy.BuoyData) != 1 {
+		fmt.Println("Failed to parse standard data with a missing column")
+		t.FailNow()
+	}
+
+	item := buoy.BuoyData[0]
+	if item.WaterTemperature != 19.1 || item.AirTemperature != 18.2 {
+		fmt.Println("Columns were not matched by name")
+		t.FailNow()
+	}
+	if !IsMissing(item.WindGust) || !IsMissing(item.WaterLevel) {
+		fmt.Println("Columns the station does not report should be missing")
+		t.FailNow()
+	}
+	if item.Date.Hour() != 18 || item.Date.Minute() != 50 {
+		fmt.Println("Failed to parse the observation time")
+		t.FailNow()
+	}
+}
+
+func TestDetailedWaveDataParsing(t *testing.T) {
+	rawData := `#YY  MM DD hh mm WVHT  SwH  SwP  WWH  WWP SwD WWD  STEEPNESS  APD MWD
+#yr  mo dy hr mn    m    m  sec    m  sec  -  degT     -      sec degT
+2017 10 16 18 40  1.1  0.9 10.0  0.5  5.3 SSE  SW    AVERAGE  6.2 163
+2017 10 16 17 40  1.0   MM   MM  0.5  5.0  MM  SW        N/A  6.0 999
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawDetailedWaveData(rawData, -1)
+	if parseErr != nil || len(buoy.BuoyData) != 2 {
+		fmt.Println("Failed to parse the detailed wave data")
+		t.FailNow()
+	}
+
+	first := buoy.BuoyData[0]
+	if first.SwellComponents[0].CompassDirection != "SSE" || first.WaveSummary.Period != 10.0 || first.Steepness != "AVERAGE" {
+		fmt.Println("Detailed wave data parsed incorrectly")
+		t.FailNow()
+	}
+
+	second := buoy.BuoyData[1]
+	if !IsMissing(second.SwellComponents[0].Direction) || !IsMissing(second.WaveSummary.Direction) || second.Steepness != "" {
+		fmt.Println("Missing detailed wave values should be missing")
+		t.FailNow()
+	}
+}
+
+func TestMalformedRowReportsLineAndColumn(t *testing.T) {
+	rawData := `#YY  MM DD hh mm WDIR WSPD GST
+#yr  mo dy hr mn degT m/s  m/s
+2017 10 16 18 50 230  7.0  9.0
+2017 10 16 18 40 230  7.0
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawStandardData(rawData, -1)
+
+	var lineErr *ParseError
+	if !errors.As(parseErr, &lineErr) || lineErr.Line != 4 || lineErr.Column != "GST" || !errors.Is(parseErr, ErrColumnCountMismatch) {
+		fmt.Println("Expected a short row to report its line and first missing column")
+		t.FailNow()
+	}
+
+	rawData = `#YY  MM DD hh mm WDIR WSPD GST
+2017 10 16 18 50 230  fast  9.0
+`
+	parseErr = buoy.ParseRawStandardData(rawData, -1)
+	if !errors.As(parseErr, &lineErr) || lineErr.Line != 2 || lineErr.Column != "WSPD" || lineErr.Value != "fast" {
+		fmt.Println("Expected an invalid value to report its line and column")
+		t.FailNow()
+	}
+}
+
+func TestOceanDataParsing(t *testing.T) {
+	rawData := `#YY  MM DD hh mm   DEPTH  OTMP   COND   SAL   O2% O2PPM  CLCON  TURB    PH    EH
+#yr  mo dy hr mn       m  degC  mS/cm   psu     %   ppm   ug/l   FTU     -    mv
+2017 10 16 18 00     1.0  19.3  47.31  33.10   MM    MM     MM    MM  8.05    MM
+2017 10 16 18 00    10.0  18.7  46.90  33.15   MM    MM     MM    MM    MM    MM
+2017 10 16 17 00  9999.0  18.9 999.00 99.000 999.0 99.00 999.00 999.0 99.00 9999.0
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawOceanData(rawData, -1)
+	if parseErr != nil || len(buoy.OceanData) != 3 {
+		fmt.Println("Failed to parse the ocean data")
+		t.FailNow()
+	}
+
+	item := buoy.OceanData[1]
+	if item.Depth != 10.0 || item.WaterTemperature != 18.7 || item.Salinity != 33.15 || !IsMissing(item.PH) {
+		fmt.Println("Ocean data parsed incorrectly")
+		t.FailNow()
+	}
+
+	item = buoy.OceanData[2]
+	if !IsMissing(item.Depth) || !IsMissing(item.Conductivity) || !IsMissing(item.Salinity) || !IsMissing(item.OxygenSaturation) ||
+		!IsMissing(item.OxygenConcentration) || !IsMissing(item.PH) || !IsMissing(item.RedoxPotential) || item.WaterTemperature != 18.9 {
+		fmt.Println("Historical all nines sentinels should be parsed as missing")
+		t.FailNow()
+	}
+}
+
+func TestContinuousWindAndSupplementalParsing(t *testing.T) {
+	rawWindData := `#YY  MM DD hh mm WDIR WSPD GDR GST GTIME
+#yr  mo dy hr mn degT m/s degT m/s hhmm
+2017 10 16 00 00 230  7.0 240 11.2  2347
+2017 10 16 23 50 225  6.8 999 99.0  9999
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawContinuousWindData(rawWindData, -1)
+	if parseErr != nil || len(buoy.ContinuousWindData) != 2 {
+		fmt.Println("Failed to parse the continuous wind data")
+		t.FailNow()
+	}
+
+	hourlyItem := buoy.ContinuousWindData[0]
+	if hourlyItem.WindGust != 11.2 || hourlyItem.GustTime.Day() != 15 || hourlyItem.GustTime.Hour() != 23 || hourlyItem.GustTime.Minute() != 47 {
+		fmt.Println("The peak gust should be placed before the observation time")
+		t.FailNow()
+	}
+	if !IsMissing(buoy.ContinuousWindData[1].WindGust) || !buoy.ContinuousWindData[1].GustTime.IsZero() {
+		fmt.Println("A gust that was not reported should be missing")
+		t.FailNow()
+	}
+
+	rawSupplementalData := `#YY  MM DD hh mm   PRES  PTIME  WSPD  WDIR WTIME
+#yr  mo dy hr mn    hPa   hhmm   m/s  degT  hhmm
+2017 10 16 18 00 1016.0   1752   9.8   230  1750
+`
+
+	parseErr = buoy.ParseRawSupplementalData(rawSupplementalData, -1)
+	if parseErr != nil || len(buoy.SupplementalData) != 1 {
+		fmt.Println("Failed to parse the supplemental data")
+		t.FailNow()
+	}
+
+	supplementalItem := buoy.SupplementalData[0]
+	if supplementalItem.LowestPressure != 1016.0 || supplementalItem.HighestWindSpeed != 9.8 || supplementalItem.HighestWindTime.Minute() != 50 {
+		fmt.Println("Supplemental data parsed incorrectly")
+		t.FailNow()
+	}
+}
+
+func TestCurrentProfileParsing(t *testing.T) {
+	rawWideData := `#YY  MM DD hh mm DEP01 DIR01 SPD01 DEP02 DIR02 SPD02 DEP03 DIR03 SPD03
+#yr  mo dy hr mn     m  degT  cm/s     m  degT  cm/s     m  degT  cm/s
+2017 10 16 18 00     2    90    20     6     0    20    10    MM    MM
+2017 10 16 17 00     2    95    18     6     5    19  9999   999   999
+`
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawCurrentData(rawWideData, 1)
+	if parseErr != nil || len(buoy.CurrentProfiles) != 1 || len(buoy.CurrentProfiles[0].Bins) != 3 {
+		fmt.Println("Failed to parse the adcp current data")
+		t.FailNow()
+	}
+
+	profile := buoy.CurrentProfiles[0]
+	if profile.Bins[0].Speed != 0.2 || !IsMissing(profile.Bins[2].Speed) {
+		fmt.Println("The adcp bins were parsed incorrectly")
+		t.FailNow()
+	}
+
+	current, currentErr := profile.CurrentAtDepth(4)
+	if currentErr != nil || math.Abs(current.Direction-45) > 1e-9 || math.Abs(current.Speed-0.1414) > 1e-4 {
+		fmt.Println("The current between bins should be interpolated as a vector")
+		t.FailNow()
+	}
+	if deepest, _ := profile.CurrentAtDepth(20); deepest.Direction != 0 {
+		fmt.Println("Missing bins should be skipped when interpolating")
+		t.FailNow()
+	}
+
+	buoy.ParseRawCurrentData(rawWideData, -1)
+	if bin := buoy.CurrentProfiles[1].Bins[2]; !IsMissing(bin.Depth) || !IsMissing(bin.Direction) || !IsMissing(bin.Speed) {
+		fmt.Println("Historical all nines sentinels should be parsed as missing bins")
+		t.FailNow()
+	}
+
+	rawBinnedData := `#YY  MM DD hh mm  I   Bin   Depth Dir Speed ErrVl VerVl %Good3 %Good4 %GoodE   EI1   EI2   EI3   EI4   CM1   CM2   CM3   CM4 Flags
+#yr  mo dy hr mn  -     -       m degT  cm/s  cm/s  cm/s      %      %      %     -     -     -     -     -     -     -     -     -
+2017 10 16 18 00  1     1    36.7  87  20.4   0.8   0.3      0    100      0   164   160   161   159   239   238   238   238 393330000
+2017 10 16 18 00  1     2    44.7  85  19.2   1.2  -0.1      0    100      0   158   154   155   153   239   239   238   239 393330000
+2017 10 16 17 00  1     1    36.7  90  18.0   0.6   0.2      0    100      0   163   159   160   158   239   238   238   238 393330000
+`
+
+	parseErr = buoy.ParseRawCurrentData(rawBinnedData, -1)
+	if parseErr != nil || len(buoy.CurrentProfiles) != 2 || len(buoy.CurrentProfiles[0].Bins) != 2 {
+		fmt.Println("Failed to parse the adcp2 current data")
+		t.FailNow()
+	}
+
+	surface, surfaceErr := buoy.CurrentProfiles[0].SurfaceCurrent()
+	if surfaceErr != nil || surface.Depth != 36.7 || surface.Direction != 87 {
+		fmt.Println("The adcp2 bins were parsed incorrectly")
+		t.FailNow()
+	}
+}
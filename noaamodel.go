@@ -1,9 +1,22 @@
 package surfnerd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
+const (
+	// Used for models that do not set their own cycle interval or publication delay
+	defaultModelCycleInterval    = 6 * time.Hour
+	defaultModelPublicationDelay = 5 * time.Hour
+
+	// How many earlier cycles are tried when the latest model run has not been published
+	DefaultModelRunFallbacks = 3
+)
+
 // Represents a NOAA Model and its coverage, timezone, and location.
 type NOAAModel struct {
 	Name               string
@@ -17,19 +30,163 @@ type NOAAModel struct {
 	TimeResolution     float64
 	Units              UnitSystem
 	TimeLocation       string
-	ModelRun           string
+
+	// How often the model is run, and how long after the start of a cycle its run is usually
+	// published on NOMADS
+	CycleInterval    time.Duration
+	PublicationDelay time.Duration
+
+	// The run pinned to fetch the data from. When it is not set the latest run expected to be published
+	// is used, and fetching resolves it against NOMADS first on a copy of the model, so the model keeps
+	// following the latest run. Set it with SetModelRun to pin a run.
+	ModelRun time.Time
+
+	// The path of the OPeNDAP dataset relative to the NOMADS endpoint, with the model name, run date
+	// and run hour as %[1]s, %[2]s and %[3]s. When it is empty the path of the kind of model is used.
+	DatasetPath string `json:",omitempty"`
+
+	// Maps the variables the forecasts are built from, such as htsgwsfc, to the names they have in
+	// the dataset, for datasets that name them differently
+	Variables map[string]string `json:",omitempty"`
+
+	// Decides between models of the same resolution that cover a location, the highest wins
+	Priority int `json:",omitempty"`
+}
+
+// A model published on NOMADS in cycles, whose latest available run can be resolved
+type CycledModel interface {
+	// Creates the url of the OPeNDAP dataset of the given run, which the .das, .info and .ascii
+	// suffixes are added to
+	CreateDatasetURL(endpoints Endpoints, run time.Time) string
+	LatestModelRun(now time.Time) time.Time
+	PreviousModelRun(run time.Time) time.Time
+	SetModelRun(run time.Time)
+}
+
+// Creates and returns the model run of the given day and cycle hour, such as 12 for the 12z run
+func NewModelRun(date time.Time, cycleHour int) time.Time {
+	year, month, day := date.UTC().Date()
+	return time.Date(year, month, day, cycleHour, 0, 0, 0, time.UTC)
+}
+
+func (n NOAAModel) cycleInterval() time.Duration {
+	if n.CycleInterval <= 0 {
+		return defaultModelCycleInterval
+	}
+	return n.CycleInterval
+}
+
+func (n NOAAModel) publicationDelay() time.Duration {
+	if n.PublicationDelay <= 0 {
+		return defaultModelPublicationDelay
+	}
+	return n.PublicationDelay
+}
+
+// Get the latest run of the model that is expected to be published at the given time
+func (n NOAAModel) LatestModelRun(now time.Time) time.Time {
+	return now.UTC().Add(-n.publicationDelay()).Truncate(n.cycleInterval())
+}
+
+// Get the run of the model one cycle before the given run
+func (n NOAAModel) PreviousModelRun(run time.Time) time.Time {
+	return run.UTC().Truncate(n.cycleInterval()).Add(-n.cycleInterval())
+}
+
+// Pins the run the model data is fetched from. The run is rounded down to the start of its cycle.
+func (n *NOAAModel) SetModelRun(run time.Time) {
+	n.ModelRun = run.UTC().Truncate(n.cycleInterval())
+}
+
+// Get the run the data is fetched from, which is the latest expected run unless one is set
+func (n NOAAModel) modelRun() time.Time {
+	if n.ModelRun.IsZero() {
+		return n.LatestModelRun(time.Now())
+	}
+	return n.ModelRun
+}
+
+// Creates the url of the OPeNDAP dataset of the given run, using the dataset path of the model
+// or the given default path
+func (n NOAAModel) datasetURL(endpoints Endpoints, defaultPath string, run time.Time) string {
+	path := n.DatasetPath
+	if path == "" {
+		path = defaultPath
+	}
+	run = run.UTC()
+	return endpoints.nomadsURL(path, n.Name, run.Format("20060102"), fmt.Sprintf("%02dz", run.Hour()))
+}
+
+// Get the name of a forecast variable in the dataset of the model
+func (n NOAAModel) datasetVariable(variable string) string {
+	if name, ok := n.Variables[variable]; ok && name != "" {
+		return name
+	}
+	return variable
+}
+
+// Formats the .ascii request for the forecast variables over the ranges of indices of the time, latitude
+// and longitude axes. With arrayOnly the grids are requested as grid.array, which leaves out their maps.
+func (n NOAAModel) asciiQuery(variables []string, arrayOnly bool, startTimeIndex, endTimeIndex int, latRange, lngRange string) string {
+	timeRange := fmt.Sprintf("[%d:%d]", startTimeIndex, endTimeIndex)
+	constraints := []string{"time" + timeRange}
+	for _, variable := range variables {
+		name := n.datasetVariable(variable)
+		if arrayOnly {
+			name = name + "." + name
+		}
+		constraints = append(constraints, name+timeRange+"["+latRange+"]["+lngRange+"]")
+	}
+	return ".ascii?" + strings.Join(constraints, ",")
+}
+
+// Renames the variables of the dataset back to the forecast variables. Forecast variables the dataset
+// does not have are filled with the fill value, so the forecasts can still be built.
+func (n NOAAModel) forecastData(data ModelDataMap, variables []string) ModelDataMap {
+	count := 0
+	for _, series := range data {
+		if len(series) > count {
+			count = len(series)
+		}
+	}
+
+	for _, variable := range variables {
+		name := n.datasetVariable(variable)
+		if series, ok := data[name]; ok && name != variable {
+			delete(data, name)
+			data[variable] = series
+		}
+		if _, ok := data[variable]; ok {
+			continue
+		}
+
+		series := make([]float64, count)
+		for index, _ := range series {
+			series[index] = modelFillValue
+		}
+		data[variable] = series
+	}
+	return data
 }
 
-// Check if a given model contains a location as part of its coverage
+// Check if a given model contains a location as part of its coverage. The longitude of the location
+// may be given from -180 to 180 or from 0 to 360 degrees.
 func (n NOAAModel) ContainsLocation(loc Location) bool {
 	if loc.Latitude > n.BottomLeftLocation.Latitude && loc.Latitude < n.TopRightLocation.Latitude {
-		if loc.Longitude > n.BottomLeftLocation.Longitude && loc.Longitude < n.TopRightLocation.Longitude {
+		longitude := n.modelLongitude(loc)
+		if longitude > n.BottomLeftLocation.Longitude && longitude < n.TopRightLocation.Longitude {
 			return true
 		}
 	}
 	return false
 }
 
+// Get the longitude of the location in the longitudes of the model grid, as the degrees east of
+// the left edge of the model added to its longitude
+func (n NOAAModel) modelLongitude(loc Location) float64 {
+	return n.BottomLeftLocation.Longitude + NormalizeAbsoluteLongitude(loc.Longitude-n.BottomLeftLocation.Longitude)
+}
+
 // Get the index of a given latitude and longitude for a model coverage area
 // Returns (-1,-1) if the location is not inside of the models coverage area
 func (n NOAAModel) LocationIndices(loc Location) (int, int) {
@@ -37,14 +194,9 @@ func (n NOAAModel) LocationIndices(loc Location) (int, int) {
 		return -1, -1
 	}
 
-	// Find the offsets from the minimum lat and long
-	latOffset := loc.Latitude - n.BottomLeftLocation.Latitude
-	lonOffset := loc.Longitude - n.BottomLeftLocation.Longitude
-
-	// Get the indexes and return them
-	latIndex := int(latOffset / n.LocationResolution)
-	lonIndex := int(lonOffset / n.LocationResolution)
-	return latIndex, lonIndex
+	// Get the indexes of the grid point at or below the location and return them
+	latPosition, lonPosition := n.gridPosition(loc)
+	return int(latPosition), int(lonPosition)
 }
 
 // Get the index of a given altitude in a models coverage area
@@ -69,8 +221,7 @@ func (n NOAAModel) TimeResolutionHours() float64 {
 
 // Get the closest future data index of a given time
 func (n NOAAModel) TimeIndex(desiredTime time.Time) int {
-	latestModelTime, _ := LatestModelDateTime()
-	diff := desiredTime.UTC().Sub(latestModelTime)
+	diff := desiredTime.UTC().Sub(n.modelRun())
 	hoursDiff := int(diff.Hours())
 	if hoursDiff < 1 {
 		return -1
@@ -80,13 +231,54 @@ func (n NOAAModel) TimeIndex(desiredTime time.Time) int {
 	return (hoursDiff + (hoursResolution - (hoursDiff % hoursResolution))) / hoursResolution
 }
 
-// Get the time and hour of the latest NOAA WaveWatch model run
+// Get the time and hour of the latest NOAA WaveWatch model run, assuming a run is published five hours
+// after its cycle starts. Use LatestModelRun of a model for its own publication delay, or ResolveModelRun
+// to check which run is actually published.
 func LatestModelDateTime() (time.Time, int64) {
-	currentTime := time.Now().UTC()
-	currentTime = currentTime.Add(time.Duration(-5 * int64(time.Hour)))
-	lastModelHour := int64(currentTime.Hour() - (currentTime.Hour() % 6))
-	currentTime = currentTime.Add(time.Duration(-(int64(currentTime.Hour()) - lastModelHour) * int64(time.Hour)))
-	return currentTime, lastModelHour
+	latestRun := NOAAModel{}.LatestModelRun(time.Now())
+	return latestRun, int64(latestRun.Hour())
+}
+
+// Finds the latest published run of the model on NOMADS using the DefaultClient, and records it as
+// the run of the model. See Client.ResolveModelRun.
+func ResolveModelRun(model CycledModel, maximumFallbacks int) (time.Time, error) {
+	return ResolveModelRunContext(context.Background(), model, maximumFallbacks)
+}
+
+// Same as ResolveModelRun, but the requests are bound to the given context
+func ResolveModelRunContext(ctx context.Context, model CycledModel, maximumFallbacks int) (time.Time, error) {
+	return DefaultClient.ResolveModelRun(ctx, model, maximumFallbacks)
+}
+
+// Finds the latest published run of the model by probing the .das endpoint of its OPeNDAP dataset,
+// starting from the latest run expected to be published and falling back on up to maximumFallbacks
+// earlier cycles. The run found is recorded as the run of the model. Returns an error wrapping
+// ErrModelRunNotPublished when none of the runs are published.
+func (c *Client) ResolveModelRun(ctx context.Context, model CycledModel, maximumFallbacks int) (time.Time, error) {
+	if model == nil {
+		return time.Time{}, errors.New("No model given to resolve the run of")
+	}
+
+	run := model.LatestModelRun(time.Now())
+	var probeErr error
+	for attempt := 0; attempt <= maximumFallbacks; attempt++ {
+		url := model.CreateDatasetURL(c.endpoints(), run) + ".das"
+		rawData, fetchErr := fetchRawDataFromURL(ctx, c.fetcher(), url)
+		if fetchErr != nil {
+			probeErr = modelFetchError(fetchErr)
+		} else {
+			probeErr = checkModelResponse(rawData, url)
+		}
+
+		if probeErr == nil {
+			model.SetModelRun(run)
+			return run, nil
+		} else if !errors.Is(probeErr, ErrModelRunNotPublished) {
+			return time.Time{}, probeErr
+		}
+		run = model.PreviousModelRun(run)
+	}
+	return time.Time{}, probeErr
 }
 
 // Get the Time location of the model
@@ -1,7 +1,12 @@
 package surfnerd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,3 +20,108 @@ func TestTimeIndex(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestExplicitModelRun(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	model.SetModelRun(NewModelRun(time.Date(2017, time.October, 16, 15, 30, 0, 0, time.UTC), 14))
+	if !model.ModelRun.Equal(time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC)) {
+		fmt.Println("Model run should be rounded down to the start of its cycle")
+		t.FailNow()
+	}
+
+	url := model.CreateURLWithEndpoints(DefaultEndpoints(), NewLocationForLatLong(41.0, 288.5), 0, 1)
+	if !strings.Contains(url, "/dods/wave/mww3/20171016/multi_1.at_10m20171016_12z.ascii?") {
+		fmt.Println("Url was not built for the pinned model run:", url)
+		t.FailNow()
+	}
+
+	now := time.Date(2017, time.October, 16, 9, 0, 0, 0, time.UTC)
+	if !model.LatestModelRun(now).Equal(time.Date(2017, time.October, 16, 0, 0, 0, 0, time.UTC)) {
+		fmt.Println("The 06z run should not be expected until five hours after it starts")
+		t.FailNow()
+	}
+	if !NewGFSWindModel().LatestModelRun(now.Add(time.Hour)).Equal(time.Date(2017, time.October, 16, 6, 0, 0, 0, time.UTC)) {
+		fmt.Println("The GFS run should be expected four hours after it starts")
+		t.FailNow()
+	}
+}
+
+func TestResolveModelRunFallsBack(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	latestRun := model.LatestModelRun(time.Now())
+	publishedRun := model.PreviousModelRun(model.PreviousModelRun(latestRun))
+	publishedURL := model.CreateDatasetURL(Endpoints{NOMADS: "http://test"}, publishedRun)
+	publishedPath := strings.TrimPrefix(publishedURL, "http://test") + ".das"
+
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		if r.URL.Path == publishedPath {
+			fmt.Fprint(w, "Attributes {\n}\n")
+			return
+		}
+		fmt.Fprint(w, "<html><body>Error: is not an available dataset</body></html>")
+	}))
+	defer server.Close()
+
+	client := NewClient(&HTTPFetcher{Client: server.Client()})
+	client.Endpoints = Endpoints{NDBC: server.URL, NOMADS: server.URL}
+
+	run, resolveErr := client.ResolveModelRun(context.Background(), model, DefaultModelRunFallbacks)
+	if resolveErr != nil || !run.Equal(publishedRun) || !model.ModelRun.Equal(publishedRun) || probes != 3 {
+		fmt.Println("Expected the resolver to fall back two cycles to the published run")
+		t.FailNow()
+	}
+
+	_, resolveErr = client.ResolveModelRun(context.Background(), NewEastCoastWaveModel(), 1)
+	if !errors.Is(resolveErr, ErrModelRunNotPublished) {
+		fmt.Println("Expected ErrModelRunNotPublished when no run is published")
+		t.FailNow()
+	}
+}
+
+func TestUnpinnedModelFollowsLatestRun(t *testing.T) {
+	model := NewGFSWindModel()
+	latestRun := model.LatestModelRun(time.Now())
+	publishedRun := model.PreviousModelRun(latestRun)
+	datasetPath := func(run time.Time) string {
+		return strings.TrimPrefix(model.CreateDatasetURL(Endpoints{NOMADS: "http://test"}, run), "http://test")
+	}
+
+	requestedPath := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == datasetPath(publishedRun)+".das" {
+			fmt.Fprint(w, "Attributes {\n}\n")
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".ascii") {
+			requestedPath = r.URL.Path
+		}
+		fmt.Fprint(w, "<html><body>Error: is not an available dataset</body></html>")
+	}))
+	defer server.Close()
+
+	client := NewClient(&HTTPFetcher{Client: server.Client()})
+	client.Endpoints = Endpoints{NDBC: server.URL, NOMADS: server.URL}
+	loc := NewLocationForLatLong(41.0, -71.5)
+
+	client.FetchWindModelDataForModel(context.Background(), loc, model)
+	if requestedPath != datasetPath(publishedRun)+".ascii" || !model.ModelRun.IsZero() {
+		fmt.Println("Expected the data of the published run without pinning the model to it:", requestedPath)
+		t.FailNow()
+	}
+
+	// Once the latest run is published the same model fetches it
+	publishedRun = latestRun
+	client.FetchWindModelDataForModel(context.Background(), loc, model)
+	if requestedPath != datasetPath(latestRun)+".ascii" || !model.ModelRun.IsZero() {
+		fmt.Println("Expected the model to follow the newly published run:", requestedPath)
+		t.FailNow()
+	}
+
+	model.CreateURLWithEndpoints(DefaultEndpoints(), loc, 0, 1)
+	if !model.ModelRun.IsZero() {
+		fmt.Println("Creating a url should not pin the run of the model")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,128 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Holds the oceanographic measurements a buoy reported at one depth for a given time. Stations
+// with sensors at several depths report one item per depth for each time. All measurements are
+// metric, and those the station did not report are missing values. More info is available here
+// http://www.ndbc.noaa.gov/measdes.shtml#ocean
+type OceanDataItem struct {
+	Date time.Time
+
+	// Depth of the sensors in meters
+	Depth float64
+
+	// Water temperature in Celsius
+	WaterTemperature float64
+
+	// Conductivity in milliSiemens per centimeter
+	Conductivity float64
+
+	// Salinity in practical salinity units
+	Salinity float64
+
+	// Dissolved oxygen as a percent of saturation and as a concentration in parts per million
+	OxygenSaturation    float64
+	OxygenConcentration float64
+
+	// Chlorophyll concentration in micrograms per liter
+	Chlorophyll float64
+
+	// Turbidity in Formazin turbidity units
+	Turbidity float64
+
+	// Acidity, on the pH scale
+	PH float64
+
+	// Redox potential in millivolts
+	RedoxPotential float64
+}
+
+// Creates and returns the url for fetching the buoys oceanographic data.
+// The url returns tab delimited ascii data.
+func (b Buoy) CreateOceanDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, oceanDataPostfix)
+}
+
+// Parses the contents of a NDBC oceanographic data (.ocean) file into a time series of OceanDataItem
+// objects. Input a negative integer to parse all available data points. A malformed line is reported as
+// a *ParseError and leaves the OceanData untouched.
+func (b *Buoy) ParseRawOceanData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
+	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
+		dataLineCount = dataCountLimit
+	}
+
+	oceanData := make([]OceanDataItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
+		oceanData[itemIndex] = OceanDataItem{
+			Date:                values.date(),
+			Depth:               values.float("DEPTH", depthSentinel),
+			WaterTemperature:    values.float("OTMP", tempSentinel),
+			Conductivity:        values.float("COND", conductivitySentinel),
+			Salinity:            values.float("SAL", salinitySentinel),
+			OxygenSaturation:    values.float("O2%", oxygenSaturationSentinel),
+			OxygenConcentration: values.float("O2PPM", oxygenConcentrationSentinel),
+			Chlorophyll:         values.float("CLCON", chlorophyllSentinel),
+			Turbidity:           values.float("TURB", turbiditySentinel),
+			PH:                  values.float("PH", phSentinel),
+			RedoxPotential:      values.float("EH", redoxPotentialSentinel),
+		}
+
+		if values.err != nil {
+			return values.err
+		}
+	}
+
+	b.OceanData = oceanData
+	return nil
+}
+
+// Grabs the latest oceanographic data as a time series of OceanDataItem objects. Input a negative
+// integer to download all available data points.
+func (b *Buoy) FetchOceanData(dataCountLimit int) error {
+	return b.FetchOceanDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchOceanData, but the download is bound to the given context
+func (b *Buoy) FetchOceanDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchErr := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateOceanDataURL())
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawOceanData(string(rawData), dataCountLimit)
+}
+
+// Encodes the item as json, leaving out missing measurements
+func (o OceanDataItem) MarshalJSON() ([]byte, error) {
+	type oceanDataItemJSON OceanDataItem
+	return marshalOmittingMissing(oceanDataItemJSON(o))
+}
+
+// Decodes the item from json. Measurements left out of the json are missing.
+func (o *OceanDataItem) UnmarshalJSON(data []byte) error {
+	type oceanDataItemJSON OceanDataItem
+	item := oceanDataItemJSON{}
+	markFloatsMissing(&item)
+
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*o = OceanDataItem(item)
+	return nil
+}
@@ -0,0 +1,351 @@
+package surfnerd
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// A quality control flag, using the values of the QARTOD manuals
+// https://ioos.noaa.gov/project/qartod/
+type QCFlag int
+
+const (
+	QCPass         QCFlag = 1
+	QCNotEvaluated QCFlag = 2
+	QCSuspect      QCFlag = 3
+	QCFail         QCFlag = 4
+	QCMissing      QCFlag = 9
+)
+
+func (q QCFlag) String() string {
+	switch q {
+	case QCPass:
+		return "pass"
+	case QCNotEvaluated:
+		return "not evaluated"
+	case QCSuspect:
+		return "suspect"
+	case QCFail:
+		return "fail"
+	case QCMissing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// Get how bad the flag is, so the results of several tests can be combined into the worst one.
+// Missing values are not tested, so the missing flag has no severity.
+func (q QCFlag) severity() int {
+	switch q {
+	case QCPass:
+		return 1
+	case QCSuspect:
+		return 2
+	case QCFail:
+		return 3
+	}
+	return 0
+}
+
+// An inclusive range of acceptable values
+type QCRange struct {
+	Min float64
+	Max float64
+}
+
+func (r QCRange) contains(value float64) bool {
+	return value >= r.Min && value <= r.Max
+}
+
+// The thresholds of the quality control tests for one variable. A test runs only when its thresholds are
+// set, so the zero value runs no tests. Thresholds are in metric units, and differences of directions are
+// taken around the compass.
+type QCThresholds struct {
+	// Gross range test. Values outside what the sensor can measure fail, and values outside what is
+	// reasonable for the station are suspect.
+	SensorRange   *QCRange
+	OperatorRange *QCRange
+
+	// Climatology test. Values outside the expected range for their month are suspect.
+	Climatology map[time.Month]QCRange
+
+	// Spike test. A value that differs from the average of its neighbors by more than the neighbors
+	// differ from each other is a spike. Spikes larger than the thresholds are suspect or fail.
+	SpikeSuspect float64
+	SpikeFail    float64
+
+	// Rate of change test. Values that changed faster than this many units per hour since the
+	// previous value are suspect.
+	MaxRateOfChange float64
+
+	// Flat line test. Values that have repeated within the tolerance for at least this long are
+	// suspect or fail, which catches a stuck sensor whatever the reporting interval of the station.
+	FlatLineTolerance       float64
+	FlatLineSuspectDuration time.Duration
+	FlatLineFailDuration    time.Duration
+}
+
+// Quality control thresholds for each variable. Variables without thresholds are not tested.
+type QCConfig map[BuoyVariable]QCThresholds
+
+// Creates and returns a quality control configuration with broad thresholds suitable for most
+// NDBC stations. Tighten them per station for better results, especially with climatology ranges.
+// The flat line tolerances are half of the 0.1 resolution NDBC reports pressures and temperatures with.
+func DefaultQCConfig() QCConfig {
+	return QCConfig{
+		WindDirectionVariable: {SensorRange: &QCRange{0, 360}},
+		WindSpeedVariable: {
+			SensorRange:   &QCRange{0, 60},
+			OperatorRange: &QCRange{0, 45},
+			SpikeSuspect:  10,
+			SpikeFail:     20,
+		},
+		WindGustVariable: {
+			SensorRange:   &QCRange{0, 75},
+			OperatorRange: &QCRange{0, 55},
+		},
+		WaveHeightVariable: {
+			SensorRange:     &QCRange{0, 30},
+			OperatorRange:   &QCRange{0, 20},
+			SpikeSuspect:    2,
+			SpikeFail:       4,
+			MaxRateOfChange: 3,
+		},
+		DominantPeriodVariable: {
+			SensorRange:   &QCRange{0, 40},
+			OperatorRange: &QCRange{1, 30},
+		},
+		AveragePeriodVariable: {
+			SensorRange:   &QCRange{0, 40},
+			OperatorRange: &QCRange{1, 25},
+		},
+		WaveDirectionVariable: {SensorRange: &QCRange{0, 360}},
+		PressureVariable: {
+			SensorRange:             &QCRange{850, 1090},
+			OperatorRange:           &QCRange{900, 1070},
+			SpikeSuspect:            4,
+			SpikeFail:               10,
+			MaxRateOfChange:         10,
+			FlatLineTolerance:       0.05,
+			FlatLineSuspectDuration: 6 * time.Hour,
+			FlatLineFailDuration:    12 * time.Hour,
+		},
+		AirTemperatureVariable: {
+			SensorRange:             &QCRange{-60, 60},
+			OperatorRange:           &QCRange{-40, 50},
+			SpikeSuspect:            5,
+			SpikeFail:               10,
+			MaxRateOfChange:         10,
+			FlatLineTolerance:       0.05,
+			FlatLineSuspectDuration: 6 * time.Hour,
+			FlatLineFailDuration:    12 * time.Hour,
+		},
+		WaterTemperatureVariable: {
+			SensorRange:             &QCRange{-5, 40},
+			OperatorRange:           &QCRange{-2, 35},
+			SpikeSuspect:            2,
+			SpikeFail:               5,
+			MaxRateOfChange:         5,
+			FlatLineTolerance:       0.05,
+			FlatLineSuspectDuration: 12 * time.Hour,
+			FlatLineFailDuration:    24 * time.Hour,
+		},
+		DewpointTemperatureVariable: {
+			SensorRange:   &QCRange{-60, 60},
+			OperatorRange: &QCRange{-40, 35},
+		},
+		VisibilityVariable: {SensorRange: &QCRange{0, 30}},
+		PressureTendencyVariable: {
+			SensorRange:   &QCRange{-30, 30},
+			OperatorRange: &QCRange{-15, 15},
+		},
+		WaterLevelVariable: {SensorRange: &QCRange{-10, 10}},
+	}
+}
+
+// Runs the quality control tests over a time series of BuoyDataItem objects and sets the QCFlags of
+// every tested variable of every item to the worst flag of its tests. Missing values are flagged as
+// missing, and values none of the tests could check are flagged not evaluated. Items in English units
+// are compared with the metric thresholds after converting their values, but are left in English units.
+func RunQualityControl(items []BuoyDataItem, config QCConfig) {
+	// The tests compare each value with its neighbors in time, and BuoyData is usually newest first
+	order := make([]int, len(items))
+	for index, _ := range order {
+		order[index] = index
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return items[order[i]].Date.Before(items[order[j]].Date)
+	})
+
+	metricItems := make([]BuoyDataItem, len(order))
+	dates := make([]time.Time, len(order))
+	for orderIndex, itemIndex := range order {
+		metricItems[orderIndex] = items[itemIndex]
+		metricItems[orderIndex].SwellComponents = nil
+		metricItems[orderIndex].ChangeUnits(Metric)
+		dates[orderIndex] = items[itemIndex].Date
+	}
+
+	for _, measurement := range buoyMeasurements {
+		thresholds, ok := config[measurement.Variable]
+		if !ok {
+			continue
+		}
+
+		values := make([]float64, len(metricItems))
+		for index, _ := range metricItems {
+			values[index] = *measurement.Value(&metricItems[index])
+		}
+
+		flags := thresholds.evaluate(dates, values, measurement.Circular)
+		for orderIndex, itemIndex := range order {
+			if items[itemIndex].QCFlags == nil {
+				items[itemIndex].QCFlags = map[BuoyVariable]QCFlag{}
+			}
+			items[itemIndex].QCFlags[measurement.Variable] = flags[orderIndex]
+		}
+	}
+}
+
+// Runs every configured test over the values, which must be ordered oldest first
+func (q QCThresholds) evaluate(dates []time.Time, values []float64, circular bool) []QCFlag {
+	difference := func(a, b float64) float64 {
+		if !circular {
+			return a - b
+		}
+		return angleDifference(b, a)
+	}
+
+	flags := make([]QCFlag, len(values))
+	for index, value := range values {
+		if IsMissing(value) {
+			flags[index] = QCMissing
+			continue
+		}
+
+		flag := QCNotEvaluated
+		combine := func(result QCFlag) {
+			if result.severity() > flag.severity() {
+				flag = result
+			}
+		}
+
+		if q.SensorRange != nil {
+			combine(rangeFlag(q.SensorRange.contains(value), QCFail))
+		}
+		if q.OperatorRange != nil {
+			combine(rangeFlag(q.OperatorRange.contains(value), QCSuspect))
+		}
+		if monthRange, ok := q.Climatology[dates[index].Month()]; ok {
+			combine(rangeFlag(monthRange.contains(value), QCSuspect))
+		}
+
+		hasPrevious := index > 0 && !IsMissing(values[index-1])
+		hasNext := index < len(values)-1 && !IsMissing(values[index+1])
+
+		if (q.SpikeSuspect > 0 || q.SpikeFail > 0) && hasPrevious && hasNext {
+			spike := math.Abs(difference(value, values[index-1])+difference(value, values[index+1]))/2.0 -
+				math.Abs(difference(values[index+1], values[index-1]))/2.0
+			if q.SpikeFail > 0 && spike > q.SpikeFail {
+				combine(QCFail)
+			} else if q.SpikeSuspect > 0 && spike > q.SpikeSuspect {
+				combine(QCSuspect)
+			} else {
+				combine(QCPass)
+			}
+		}
+
+		if q.MaxRateOfChange > 0 && hasPrevious {
+			if hours := dates[index].Sub(dates[index-1]).Hours(); hours > 0 {
+				rate := math.Abs(difference(value, values[index-1])) / hours
+				combine(rangeFlag(rate <= q.MaxRateOfChange, QCSuspect))
+			}
+		}
+
+		if q.FlatLineSuspectDuration > 0 || q.FlatLineFailDuration > 0 {
+			firstRepeat := index
+			for previous := index - 1; previous >= 0 && !IsMissing(values[previous]); previous-- {
+				if math.Abs(difference(value, values[previous])) > q.FlatLineTolerance {
+					break
+				}
+				firstRepeat = previous
+			}
+
+			repeatDuration := dates[index].Sub(dates[firstRepeat])
+			if q.FlatLineFailDuration > 0 && repeatDuration >= q.FlatLineFailDuration {
+				combine(QCFail)
+			} else if q.FlatLineSuspectDuration > 0 && repeatDuration >= q.FlatLineSuspectDuration {
+				combine(QCSuspect)
+			} else {
+				combine(QCPass)
+			}
+		}
+
+		flags[index] = flag
+	}
+	return flags
+}
+
+func rangeFlag(inRange bool, outOfRangeFlag QCFlag) QCFlag {
+	if inRange {
+		return QCPass
+	}
+	return outOfRangeFlag
+}
+
+// Returns if the flag is at least as bad as the threshold flag. Missing and not evaluated
+// flags never are.
+func (q QCFlag) isAtLeast(threshold QCFlag) bool {
+	return q.severity() > 1 && q.severity() >= threshold.severity()
+}
+
+// Replaces every measurement flagged at least as bad as the given flag with a missing value. Pass
+// QCSuspect to mask suspect and failed values, or QCFail to only mask failed values. Run
+// RunQualityControl first.
+func MaskFlaggedValues(items []BuoyDataItem, flag QCFlag) {
+	for index, _ := range items {
+		item := &items[index]
+		for _, measurement := range buoyMeasurements {
+			if itemFlag, ok := item.QCFlags[measurement.Variable]; ok && itemFlag.isAtLeast(flag) {
+				*measurement.Value(item) = MissingValue()
+				if measurement.Variable == WaveDirectionVariable {
+					item.WaveSummary.CompassDirection = ""
+				}
+			}
+		}
+	}
+}
+
+// Returns the items that do not have any measurement flagged at least as bad as the given flag.
+// Run RunQualityControl first.
+func DropFlaggedItems(items []BuoyDataItem, flag QCFlag) []BuoyDataItem {
+	keptItems := []BuoyDataItem{}
+	for _, item := range items {
+		flagged := false
+		for _, itemFlag := range item.QCFlags {
+			flagged = flagged || itemFlag.isAtLeast(flag)
+		}
+		if !flagged {
+			keptItems = append(keptItems, item)
+		}
+	}
+	return keptItems
+}
+
+// Runs the quality control tests over the BuoyData. See RunQualityControl.
+func (b *Buoy) RunQualityControl(config QCConfig) {
+	RunQualityControl(b.BuoyData, config)
+}
+
+// Replaces every measurement of the BuoyData flagged at least as bad as the given flag with
+// a missing value. See MaskFlaggedValues.
+func (b *Buoy) MaskFlaggedValues(flag QCFlag) {
+	MaskFlaggedValues(b.BuoyData, flag)
+}
+
+// Removes the items of the BuoyData with any measurement flagged at least as bad as the given
+// flag. See DropFlaggedItems.
+func (b *Buoy) DropFlaggedItems(flag QCFlag) {
+	b.BuoyData = DropFlaggedItems(b.BuoyData, flag)
+}
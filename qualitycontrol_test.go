@@ -0,0 +1,84 @@
+package surfnerd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQualityControlFlags(t *testing.T) {
+	start := time.Date(2017, 10, 16, 0, 0, 0, 0, time.UTC)
+	waveHeights := []float64{1.0, 1.1, 1.0, 6.5, 1.1, 1.2, 45.0, 1.2}
+
+	// Newest first, like the NDBC files
+	items := make([]BuoyDataItem, len(waveHeights))
+	for index, waveHeight := range waveHeights {
+		item := NewBuoyDataItem(Metric)
+		item.Date = start.Add(time.Duration(index) * time.Hour)
+		item.WaveSummary.WaveHeight = waveHeight
+		item.WaterTemperature = 18.0
+		items[len(items)-1-index] = item
+	}
+	items[0].WaterTemperature = MissingValue()
+
+	config := QCConfig{
+		WaveHeightVariable:       DefaultQCConfig()[WaveHeightVariable],
+		WaterTemperatureVariable: {FlatLineSuspectDuration: 2 * time.Hour, FlatLineFailDuration: 4 * time.Hour},
+	}
+	buoy := Buoy{BuoyData: items}
+	buoy.RunQualityControl(config)
+
+	flagAt := func(hour int, variable BuoyVariable) QCFlag {
+		return buoy.BuoyData[len(items)-1-hour].QCFlags[variable]
+	}
+	if flagAt(0, WaveHeightVariable) != QCPass || flagAt(3, WaveHeightVariable) != QCFail || flagAt(6, WaveHeightVariable) != QCFail {
+		fmt.Println("Spikes and values outside the sensor range should fail")
+		t.FailNow()
+	}
+	if flagAt(2, WaterTemperatureVariable) != QCSuspect || flagAt(5, WaterTemperatureVariable) != QCFail || flagAt(7, WaterTemperatureVariable) != QCMissing {
+		fmt.Println("Repeated values should be flagged by the flat line test")
+		t.FailNow()
+	}
+	if _, ok := buoy.BuoyData[0].QCFlags[PressureVariable]; ok {
+		fmt.Println("Variables without thresholds should not be flagged")
+		t.FailNow()
+	}
+
+	buoy.MaskFlaggedValues(QCFail)
+	if !IsMissing(buoy.BuoyData[len(items)-1-3].WaveSummary.WaveHeight) || IsMissing(buoy.BuoyData[len(items)-1].WaveSummary.WaveHeight) {
+		fmt.Println("Only failed values should be masked")
+		t.FailNow()
+	}
+
+	buoy.DropFlaggedItems(QCSuspect)
+	if len(buoy.BuoyData) != 2 {
+		fmt.Println("Items with suspect values should be dropped")
+		t.FailNow()
+	}
+}
+
+func TestDefaultFlatLineThresholds(t *testing.T) {
+	start := time.Date(2017, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	// A stuck barometer wobbling within its resolution is flagged after the same time whether the
+	// station reports every 10 minutes or every hour
+	for _, interval := range []time.Duration{10 * time.Minute, time.Hour} {
+		items := make([]BuoyDataItem, int(12*time.Hour/interval)+1)
+		for index, _ := range items {
+			item := NewBuoyDataItem(Metric)
+			item.Date = start.Add(time.Duration(index) * interval)
+			item.Pressure = 1013.2 + 0.01*float64(index%2)
+			items[len(items)-1-index] = item
+		}
+
+		buoy := Buoy{BuoyData: items}
+		buoy.RunQualityControl(QCConfig{PressureVariable: DefaultQCConfig()[PressureVariable]})
+		flagAfter := func(elapsed time.Duration) QCFlag {
+			return buoy.BuoyData[len(items)-1-int(elapsed/interval)].QCFlags[PressureVariable]
+		}
+		if flagAfter(6*time.Hour-interval) != QCPass || flagAfter(6*time.Hour) != QCSuspect || flagAfter(12*time.Hour) != QCFail {
+			fmt.Println("A pressure stuck for 6 hours should be suspect and for 12 hours should fail, reporting every", interval)
+			t.FailNow()
+		}
+	}
+}
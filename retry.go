@@ -0,0 +1,136 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryAttempts  = 4
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 10 * time.Second
+)
+
+// Wraps another Fetcher and retries transient failures such as server errors, timeouts
+// and connection resets. The wait between attempts grows exponentially from BaseDelay up to
+// MaxDelay, with random jitter so many clients do not hammer NOAA in lock step.
+type RetryFetcher struct {
+	Fetcher     Fetcher
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Creates a new RetryFetcher around the given fetcher with the default retry policy
+func NewRetryFetcher(fetcher Fetcher) *RetryFetcher {
+	return &RetryFetcher{
+		Fetcher:     fetcher,
+		MaxAttempts: defaultRetryAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+	}
+}
+
+// Fetches the url, retrying transient failures until the attempts run out or the context is done
+func (r *RetryFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	retryErr := r.retry(ctx, func() error {
+		var fetchErr error
+		data, fetchErr = r.Fetcher.Fetch(ctx, url)
+		return fetchErr
+	})
+	return data, retryErr
+}
+
+// Makes a conditional request for the url with the same retry policy as Fetch. If the
+// wrapped fetcher cannot make conditional requests, the data is always fetched in full.
+func (r *RetryFetcher) FetchConditional(ctx context.Context, url string, validators CacheValidators) (*ConditionalResponse, error) {
+	conditionalFetcher, ok := r.Fetcher.(ConditionalFetcher)
+	if !ok {
+		data, fetchErr := r.Fetch(ctx, url)
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return &ConditionalResponse{Data: data}, nil
+	}
+
+	var response *ConditionalResponse
+	retryErr := r.retry(ctx, func() error {
+		var fetchErr error
+		response, fetchErr = conditionalFetcher.FetchConditional(ctx, url, validators)
+		return fetchErr
+	})
+	return response, retryErr
+}
+
+// Runs the given fetch until it succeeds, fails with a permanent error, runs out of
+// attempts, or the context is done
+func (r *RetryFetcher) retry(ctx context.Context, fetch func() error) error {
+	attempts := r.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(r.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		fetchErr := fetch()
+		if fetchErr == nil {
+			return nil
+		}
+
+		lastErr = fetchErr
+		if ctx.Err() != nil || !isTransientError(fetchErr) {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// Get the wait before the given attempt. The delay doubles every attempt and half of
+// it is randomized.
+func (r *RetryFetcher) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay << uint(attempt-1)
+	if delay <= 0 || (r.MaxDelay > 0 && delay > r.MaxDelay) {
+		delay = r.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Returns if a fetch error is worth retrying
+func isTransientError(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
@@ -0,0 +1,92 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryFetcherRecoversFromServerErrors(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	fetcher := NewRetryFetcher(&HTTPFetcher{Client: server.Client()})
+	fetcher.BaseDelay = time.Millisecond
+	fetcher.MaxDelay = 5 * time.Millisecond
+
+	data, fetchErr := fetcher.Fetch(context.Background(), server.URL)
+	if fetchErr != nil || string(data) != "ok" {
+		fmt.Println("Retry fetcher did not recover from transient errors")
+		t.FailNow()
+	}
+	if requestCount != 3 {
+		fmt.Println("Retry fetcher made the wrong number of requests")
+		t.FailNow()
+	}
+}
+
+func TestRetryFetcherDoesNotRetryMissingData(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	fetcher := NewRetryFetcher(&HTTPFetcher{Client: server.Client()})
+	fetcher.BaseDelay = time.Millisecond
+
+	_, fetchErr := fetcher.Fetch(context.Background(), server.URL)
+	var httpErr *HTTPError
+	if !errors.As(fetchErr, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		fmt.Println("Expected a 404 HTTPError from the fetcher")
+		t.FailNow()
+	}
+	if requestCount != 1 {
+		fmt.Println("A missing file should not be retried")
+		t.FailNow()
+	}
+}
+
+func TestMissingStationDataError(t *testing.T) {
+	client, closeServer := newTestNDBCClient()
+	defer closeServer()
+
+	buoy := &Buoy{StationID: "00000", Client: client}
+	fetchErr := buoy.FetchDetailedWaveDataContext(context.Background(), -1)
+	if !errors.Is(fetchErr, ErrStationDataNotFound) {
+		fmt.Println("Expected ErrStationDataNotFound for a missing station file")
+		t.FailNow()
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(fetchErr, &httpErr) {
+		fmt.Println("Expected the HTTPError to be wrapped in the station error")
+		t.FailNow()
+	}
+}
+
+func TestUnpublishedModelResponse(t *testing.T) {
+	body := []byte("<html><body>Error: multi_1.at_10m20171016_12z is not an available dataset</body></html>")
+	if !errors.Is(checkModelResponse(body, "test"), ErrModelRunNotPublished) {
+		fmt.Println("Expected an html response to be treated as an unpublished model run")
+		t.FailNow()
+	}
+
+	if checkModelResponse([]byte("time, [1]\n736619.0\n"), "test") != nil {
+		fmt.Println("A valid model response was rejected")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,170 @@
+package surfnerd
+
+import (
+	"math"
+)
+
+// The spectral moments of a wave spectrum, m_n = integral of f^n * E(f) df, from which the integrated
+// wave parameters are derived. The moments are computed once and every parameter is derived from them
+// following http://www.ndbc.noaa.gov/algor.shtml and http://www.ndbc.noaa.gov/wavecalc.shtml. Parameters
+// that cannot be derived, such as the spread of a spectrum without r1, are missing values.
+type SpectralMoments struct {
+	MinusOne float64
+	Zero     float64
+	One      float64
+	Two      float64
+	Four     float64
+
+	// Integral of f * E(f)^2 df, used for the peakedness
+	peakednessIntegral float64
+
+	// Energy weighted sums of the first order directional coefficients a1 and b1, and the energy
+	// of the frequencies with a known direction
+	directionalCosine float64
+	directionalSine   float64
+	directionalEnergy float64
+	spreadKnown       bool
+}
+
+// Computes the moments from the energy density of each frequency band. The directional cosines and sines
+// are the energy density times r1 cos(alpha1) and r1 sin(alpha1) for each band, or missing values for bands
+// without a direction. Pass nil for either when the spectrum has no directions. spreadKnown tells if the
+// directional sums hold r1, which is needed for the spread.
+func newSpectralMoments(frequencies, bandwidths, densities, directionalCosines, directionalSines []float64, spreadKnown bool) SpectralMoments {
+	moments := SpectralMoments{spreadKnown: spreadKnown}
+	for index, frequency := range frequencies {
+		density := densities[index]
+		if IsMissing(density) || frequency <= 0 {
+			continue
+		}
+
+		energy := density * bandwidths[index]
+		moments.MinusOne += energy / frequency
+		moments.Zero += energy
+		moments.One += energy * frequency
+		moments.Two += energy * math.Pow(frequency, 2)
+		moments.Four += energy * math.Pow(frequency, 4)
+		moments.peakednessIntegral += frequency * math.Pow(density, 2) * bandwidths[index]
+
+		if directionalCosines == nil || directionalSines == nil || IsMissing(directionalCosines[index]) || IsMissing(directionalSines[index]) {
+			continue
+		}
+		moments.directionalCosine += directionalCosines[index] * bandwidths[index]
+		moments.directionalSine += directionalSines[index] * bandwidths[index]
+		moments.directionalEnergy += energy
+	}
+	return moments
+}
+
+// Computes the spectral moments of the whole spectra
+func (b BuoySpectraItem) Moments() SpectralMoments {
+	return b.MomentsInBand(math.Inf(-1), math.Inf(1))
+}
+
+// Computes the spectral moments of the frequencies of the spectra between the minimum and maximum
+// frequency in Hz, inclusive. Each frequency keeps the bandwidth it has in the whole spectra.
+func (b BuoySpectraItem) MomentsInBand(minimumFrequency, maximumFrequency float64) SpectralMoments {
+	if len(b.Energies) != len(b.Frequencies) {
+		return SpectralMoments{}
+	}
+
+	hasDirections := len(b.Angles) == len(b.Frequencies)
+	spreadKnown := hasDirections && len(b.R1) == len(b.Frequencies)
+
+	frequencies := []float64{}
+	bandwidths := []float64{}
+	densities := []float64{}
+	directionalCosines := []float64{}
+	directionalSines := []float64{}
+	for index, frequency := range b.Frequencies {
+		if frequency < minimumFrequency || frequency > maximumFrequency {
+			continue
+		}
+
+		frequencies = append(frequencies, frequency)
+		bandwidths = append(bandwidths, frequencyBandwidth(b.Frequencies, index))
+		densities = append(densities, b.Energies[index])
+		if !hasDirections {
+			continue
+		}
+
+		// Without r1 the direction is still the energy weighted mean of alpha1, but the spread is unknown
+		r1 := 1.0
+		if len(b.R1) == len(b.Frequencies) && !IsMissing(b.R1[index]) {
+			r1 = b.R1[index]
+		} else if !IsMissing(b.Energies[index]) {
+			spreadKnown = false
+		}
+		direction := b.Angles[index] * math.Pi / 180.0
+		directionalCosines = append(directionalCosines, b.Energies[index]*r1*math.Cos(direction))
+		directionalSines = append(directionalSines, b.Energies[index]*r1*math.Sin(direction))
+	}
+
+	if !hasDirections {
+		return newSpectralMoments(frequencies, bandwidths, densities, nil, nil, false)
+	}
+	return newSpectralMoments(frequencies, bandwidths, densities, directionalCosines, directionalSines, spreadKnown)
+}
+
+// Get the significant wave height Hm0 in meters, 4 * sqrt(m0)
+func (s SpectralMoments) SignificantWaveHeight() float64 {
+	return 4.0 * math.Sqrt(s.Zero)
+}
+
+// Get the mean period Tm01 in seconds, m0 / m1
+func (s SpectralMoments) MeanPeriod() float64 {
+	return s.ratio(s.Zero, s.One)
+}
+
+// Get the mean zero crossing period Tm02 in seconds, sqrt(m0 / m2). This is the average
+// period NDBC publishes as APD.
+func (s SpectralMoments) ZeroCrossingPeriod() float64 {
+	return math.Sqrt(s.ratio(s.Zero, s.Two))
+}
+
+// Get the energy period Te in seconds, m-1 / m0, which is used to calculate wave power
+func (s SpectralMoments) EnergyPeriod() float64 {
+	return s.ratio(s.MinusOne, s.Zero)
+}
+
+// Get the Goda peakedness parameter Qp, 2 / m0^2 * integral of f * E(f)^2 df. It is about 2 for a
+// fully developed sea and grows as the spectrum narrows around its peak.
+func (s SpectralMoments) Peakedness() float64 {
+	return s.ratio(2.0*s.peakednessIntegral, math.Pow(s.Zero, 2))
+}
+
+// Get the spectral bandwidth epsilon, sqrt(1 - m2^2 / (m0 * m4)), between 0 for a narrow
+// spectrum and 1 for a broad one
+func (s SpectralMoments) SpectralWidth() float64 {
+	return math.Sqrt(math.Max(1.0-s.ratio(math.Pow(s.Two, 2), s.Zero*s.Four), 0))
+}
+
+// Get the Longuet-Higgins spectral narrowness nu, sqrt(m0 * m2 / m1^2 - 1)
+func (s SpectralMoments) SpectralNarrowness() float64 {
+	return math.Sqrt(math.Max(s.ratio(s.Zero*s.Two, math.Pow(s.One, 2))-1.0, 0))
+}
+
+// Get the energy weighted mean direction in degrees the waves are coming from
+func (s SpectralMoments) MeanDirection() float64 {
+	if s.directionalEnergy <= 0 {
+		return MissingValue()
+	}
+	return normalizeDirection(math.Atan2(s.directionalSine, s.directionalCosine) * 180.0 / math.Pi)
+}
+
+// Get the energy weighted directional spread in degrees, sqrt(2 * (1 - r1)) of the mean r1
+func (s SpectralMoments) DirectionalSpread() float64 {
+	if s.directionalEnergy <= 0 || !s.spreadKnown {
+		return MissingValue()
+	}
+	r1 := math.Hypot(s.directionalCosine, s.directionalSine) / s.directionalEnergy
+	return math.Sqrt(2.0*(1.0-math.Min(r1, 1.0))) * 180.0 / math.Pi
+}
+
+// Divides the moments, returning a missing value for an empty spectrum
+func (s SpectralMoments) ratio(numerator, denominator float64) float64 {
+	if denominator <= 0 {
+		return MissingValue()
+	}
+	return numerator / denominator
+}
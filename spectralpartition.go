@@ -0,0 +1,448 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Options for splitting a wave spectrum into its swell and wind sea partitions
+type PartitionOptions struct {
+	// Partitions with a wave height less than this fraction of the wave height of the whole
+	// spectrum are merged into the neighbor they share the highest boundary with
+	MinimumHeightFraction float64
+
+	// Neighboring partitions whose peaks are closer than this many Hz are merged. In the directional
+	// spectrum their peaks must also be closer than MinimumDirectionSeparation degrees, so crossing
+	// swells of the same period are kept apart.
+	MinimumFrequencySeparation float64
+	MinimumDirectionSeparation float64
+
+	// Neighboring partitions whose peak directions are within this many degrees are merged.
+	// Zero disables merging by direction.
+	MaximumDirectionDifference float64
+
+	// Partition the directional spectrum with a 2D watershed when the spectra has the directional
+	// coefficients. Otherwise, or when they are not available, the energy spectrum is partitioned.
+	UseDirectionalSpectrum bool
+	SpreadingMethod        DirectionalSpreadingMethod
+	DirectionStep          float64
+}
+
+// Creates and returns the partition options used by FindSwellComponents
+func DefaultPartitionOptions() PartitionOptions {
+	return PartitionOptions{
+		MinimumHeightFraction:      0.15,
+		MinimumFrequencySeparation: 0.01,
+		MinimumDirectionSeparation: 45.0,
+		UseDirectionalSpectrum:     true,
+		SpreadingMethod:            MaximumEntropyMethod,
+		DirectionStep:              10.0,
+	}
+}
+
+// A part of a wave spectrum belonging to one swell or wind sea. The Swell holds the wave height of
+// the partition, with the period and direction at its peak.
+type SpectralPartition struct {
+	Swell Swell
+
+	// The frequencies in Hz the partition covers, and the frequency of its peak
+	MinimumFrequency float64
+	MaximumFrequency float64
+	PeakFrequency    float64
+
+	// The zero spectral moment of the partition in m^2, and its share of the whole spectrum
+	Energy         float64
+	EnergyFraction float64
+
+	// The spectral moments of the partition, for its integrated wave parameters
+	Moments SpectralMoments
+}
+
+// The spectrum to partition as a graph of cells, each a frequency band or a frequency and direction bin
+type spectralGraph struct {
+	// The energy density of each cell, and the area of the cell so density * area is its energy
+	densities []float64
+	areas     []float64
+
+	frequencyIndices []int
+	directions       []float64
+	neighbors        func(cell int) []int
+
+	// The first order directional coefficients of each cell, r1 cos(alpha1) and r1 sin(alpha1), and
+	// if they hold r1 so the spread of a partition is known
+	directionalCosines []float64
+	directionalSines   []float64
+	spreadKnown        bool
+
+	// If the cells are frequency and direction bins of a directional spectrum
+	directional bool
+}
+
+// Splits the spectra into partitions with a watershed: every cell belongs to the peak reached by
+// climbing the spectrum from it. Partitions are then merged following the options. Partitions are
+// returned with the most energetic first.
+func (b BuoySpectraItem) Partition(options PartitionOptions) ([]SpectralPartition, error) {
+	if len(b.Frequencies) == 0 || len(b.Energies) != len(b.Frequencies) {
+		return nil, errors.New("Spectra does not have matching frequencies and energies, could not partition")
+	}
+
+	var graph *spectralGraph
+	if options.UseDirectionalSpectrum && b.HasDirectionalCoefficients() {
+		spectrum, spectrumErr := b.DirectionalSpectrum(options.SpreadingMethod, options.DirectionStep)
+		if spectrumErr != nil {
+			return nil, spectrumErr
+		}
+		graph = newDirectionalSpectralGraph(spectrum)
+	} else {
+		graph = b.newSpectralGraph()
+	}
+
+	return graph.partition(b, options), nil
+}
+
+// Creates the graph of the energy spectrum, where each frequency band neighbors the bands on either side
+func (b BuoySpectraItem) newSpectralGraph() *spectralGraph {
+	frequencyCount := len(b.Frequencies)
+	graph := &spectralGraph{
+		densities:          make([]float64, frequencyCount),
+		areas:              make([]float64, frequencyCount),
+		frequencyIndices:   make([]int, frequencyCount),
+		directions:         make([]float64, frequencyCount),
+		directionalCosines: make([]float64, frequencyCount),
+		directionalSines:   make([]float64, frequencyCount),
+		spreadKnown:        len(b.R1) == frequencyCount,
+	}
+	for index, _ := range b.Frequencies {
+		graph.densities[index] = b.Energies[index]
+		graph.areas[index] = frequencyBandwidth(b.Frequencies, index)
+		graph.frequencyIndices[index] = index
+		graph.directions[index] = MissingValue()
+		if len(b.Angles) == frequencyCount {
+			graph.directions[index] = b.Angles[index]
+		}
+
+		r1 := 1.0
+		if graph.spreadKnown && !IsMissing(b.R1[index]) {
+			r1 = b.R1[index]
+		} else if !IsMissing(b.Energies[index]) {
+			graph.spreadKnown = false
+		}
+		graph.directionalCosines[index] = r1 * math.Cos(graph.directions[index]*math.Pi/180.0)
+		graph.directionalSines[index] = r1 * math.Sin(graph.directions[index]*math.Pi/180.0)
+	}
+
+	graph.neighbors = func(cell int) []int {
+		neighbors := []int{}
+		if cell > 0 {
+			neighbors = append(neighbors, cell-1)
+		}
+		if cell < frequencyCount-1 {
+			neighbors = append(neighbors, cell+1)
+		}
+		return neighbors
+	}
+	return graph
+}
+
+// Creates the graph of a directional spectrum, where each bin neighbors the eight bins around it and
+// directions wrap around the compass
+func newDirectionalSpectralGraph(spectrum *DirectionalSpectrum) *spectralGraph {
+	frequencyCount := len(spectrum.Frequencies)
+	directionCount := len(spectrum.Directions)
+	directionStep := spectrum.directionStep()
+
+	graph := &spectralGraph{spreadKnown: true, directional: true}
+	for frequencyIndex, row := range spectrum.Energy {
+		bandwidth := frequencyBandwidth(spectrum.Frequencies, frequencyIndex)
+		for directionIndex, density := range row {
+			graph.densities = append(graph.densities, density)
+			graph.areas = append(graph.areas, bandwidth*directionStep)
+			graph.frequencyIndices = append(graph.frequencyIndices, frequencyIndex)
+			graph.directions = append(graph.directions, spectrum.Directions[directionIndex])
+			graph.directionalCosines = append(graph.directionalCosines, math.Cos(spectrum.Directions[directionIndex]*math.Pi/180.0))
+			graph.directionalSines = append(graph.directionalSines, math.Sin(spectrum.Directions[directionIndex]*math.Pi/180.0))
+		}
+	}
+
+	graph.neighbors = func(cell int) []int {
+		frequencyIndex, directionIndex := cell/directionCount, cell%directionCount
+		neighbors := []int{}
+		for frequencyOffset := -1; frequencyOffset <= 1; frequencyOffset++ {
+			neighborFrequency := frequencyIndex + frequencyOffset
+			if neighborFrequency < 0 || neighborFrequency >= frequencyCount {
+				continue
+			}
+			for directionOffset := -1; directionOffset <= 1; directionOffset++ {
+				if frequencyOffset == 0 && directionOffset == 0 {
+					continue
+				}
+				neighborDirection := (directionIndex + directionOffset + directionCount) % directionCount
+				neighbors = append(neighbors, neighborFrequency*directionCount+neighborDirection)
+			}
+		}
+		return neighbors
+	}
+	return graph
+}
+
+// Get the density of a cell, treating missing values as no energy
+func (g *spectralGraph) density(cell int) float64 {
+	if IsMissing(g.densities[cell]) {
+		return 0
+	}
+	return g.densities[cell]
+}
+
+// Returns if the cell is further up the spectrum than the other cell. Equal cells are ordered by
+// index so plateaus are climbed in one direction and never loop.
+func (g *spectralGraph) isAbove(cell, other int) bool {
+	return g.density(cell) > g.density(other) || (g.density(cell) == g.density(other) && cell > other)
+}
+
+// Labels every cell with energy by the peak it climbs to, and cells with no energy with -1
+func (g *spectralGraph) watershed() []int {
+	labels := make([]int, len(g.densities))
+	for cell, _ := range labels {
+		labels[cell] = -2
+	}
+
+	for cell, _ := range labels {
+		path := []int{}
+		current := cell
+		for labels[current] == -2 {
+			if g.density(current) <= 0 {
+				labels[current] = -1
+				break
+			}
+
+			path = append(path, current)
+			next := current
+			for _, neighbor := range g.neighbors(current) {
+				if g.isAbove(neighbor, next) {
+					next = neighbor
+				}
+			}
+			if next == current {
+				labels[current] = current
+				break
+			}
+			current = next
+		}
+
+		for _, pathCell := range path {
+			labels[pathCell] = labels[current]
+		}
+	}
+	return labels
+}
+
+// The running totals of a partition while it is being merged
+type partitionTotals struct {
+	peak             int
+	energy           float64
+	minimumFrequency int
+	maximumFrequency int
+}
+
+func (g *spectralGraph) partition(b BuoySpectraItem, options PartitionOptions) []SpectralPartition {
+	labels := g.watershed()
+
+	// Union find over the peaks, so merged partitions point at the peak that absorbed them
+	parents := map[int]int{}
+	var root func(label int) int
+	root = func(label int) int {
+		if parents[label] != label {
+			parents[label] = root(parents[label])
+		}
+		return parents[label]
+	}
+
+	totalEnergy := 0.0
+	for cell, label := range labels {
+		if label >= 0 {
+			parents[label] = label
+			totalEnergy += g.density(cell) * g.areas[cell]
+		}
+	}
+	if totalEnergy <= 0 {
+		return []SpectralPartition{}
+	}
+
+	for {
+		totals := map[int]*partitionTotals{}
+		saddles := map[[2]int]float64{}
+		for cell, label := range labels {
+			if label < 0 {
+				continue
+			}
+
+			label = root(label)
+			partition, ok := totals[label]
+			if !ok {
+				partition = &partitionTotals{peak: cell, minimumFrequency: g.frequencyIndices[cell], maximumFrequency: g.frequencyIndices[cell]}
+				totals[label] = partition
+			}
+			partition.energy += g.density(cell) * g.areas[cell]
+			if g.isAbove(cell, partition.peak) {
+				partition.peak = cell
+			}
+			if g.frequencyIndices[cell] < partition.minimumFrequency {
+				partition.minimumFrequency = g.frequencyIndices[cell]
+			}
+			if g.frequencyIndices[cell] > partition.maximumFrequency {
+				partition.maximumFrequency = g.frequencyIndices[cell]
+			}
+
+			// The saddle between two partitions is the highest point of their shared boundary
+			for _, neighbor := range g.neighbors(cell) {
+				if labels[neighbor] < 0 || root(labels[neighbor]) == label {
+					continue
+				}
+				pair := [2]int{label, root(labels[neighbor])}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				saddles[pair] = math.Max(saddles[pair], math.Min(g.density(cell), g.density(neighbor)))
+			}
+		}
+
+		if !g.mergeOnce(b, options, totals, saddles, totalEnergy, parents) {
+			return g.partitionsFromTotals(b, labels, root, totals, totalEnergy)
+		}
+	}
+}
+
+// Merges the first pair of partitions the options call for. Returns false when nothing was merged.
+func (g *spectralGraph) mergeOnce(b BuoySpectraItem, options PartitionOptions, totals map[int]*partitionTotals, saddles map[[2]int]float64, totalEnergy float64, parents map[int]int) bool {
+	merge := func(first, second int) {
+		if g.isAbove(totals[first].peak, totals[second].peak) {
+			parents[second] = first
+		} else {
+			parents[first] = second
+		}
+	}
+
+	// Check the pairs in a fixed order so the result does not depend on map iteration
+	pairs := make([][2]int, 0, len(saddles))
+	for pair, _ := range saddles {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0] || (pairs[i][0] == pairs[j][0] && pairs[i][1] < pairs[j][1])
+	})
+
+	for _, pair := range pairs {
+		firstPeak, secondPeak := totals[pair[0]].peak, totals[pair[1]].peak
+		firstDirection, secondDirection := g.directions[firstPeak], g.directions[secondPeak]
+		directionDifference := math.Abs(angleDifference(secondDirection, firstDirection))
+		knownDirections := !IsMissing(firstDirection) && !IsMissing(secondDirection)
+
+		frequencySeparation := math.Abs(b.Frequencies[g.frequencyIndices[firstPeak]] - b.Frequencies[g.frequencyIndices[secondPeak]])
+		if frequencySeparation < options.MinimumFrequencySeparation && (!g.directional || directionDifference < options.MinimumDirectionSeparation) {
+			merge(pair[0], pair[1])
+			return true
+		}
+
+		if options.MaximumDirectionDifference > 0 && knownDirections && directionDifference < options.MaximumDirectionDifference {
+			merge(pair[0], pair[1])
+			return true
+		}
+	}
+
+	// Merge the smallest partition that is too small into the neighbor it has the highest saddle with.
+	// Wave height scales with the square root of energy, so compare the energy with the fraction squared.
+	hasNeighbor := map[int]bool{}
+	for _, pair := range pairs {
+		hasNeighbor[pair[0]] = true
+		hasNeighbor[pair[1]] = true
+	}
+
+	smallest := -1
+	minimumEnergy := math.Pow(options.MinimumHeightFraction, 2) * totalEnergy
+	for label, partition := range totals {
+		if !hasNeighbor[label] || partition.energy >= minimumEnergy {
+			continue
+		} else if smallest < 0 || partition.energy < totals[smallest].energy || (partition.energy == totals[smallest].energy && label < smallest) {
+			smallest = label
+		}
+	}
+	if smallest < 0 {
+		return false
+	}
+
+	neighbor := -1
+	highestSaddle := -1.0
+	for _, pair := range pairs {
+		other := -1
+		if pair[0] == smallest {
+			other = pair[1]
+		} else if pair[1] == smallest {
+			other = pair[0]
+		}
+		if other >= 0 && saddles[pair] > highestSaddle {
+			neighbor = other
+			highestSaddle = saddles[pair]
+		}
+	}
+	parents[smallest] = neighbor
+	return true
+}
+
+// Creates the partitions from their totals, most energetic first
+func (g *spectralGraph) partitionsFromTotals(b BuoySpectraItem, labels []int, root func(label int) int, totals map[int]*partitionTotals, totalEnergy float64) []SpectralPartition {
+	partitions := make([]SpectralPartition, 0, len(totals))
+	for label, partition := range totals {
+		peakFrequencyIndex := g.frequencyIndices[partition.peak]
+		swell := Swell{Units: Metric}
+		swell.WaveHeight = 4.0 * math.Sqrt(partition.energy)
+		swell.Period = 1.0 / b.Frequencies[peakFrequencyIndex]
+		swell.Direction = g.directions[partition.peak]
+		swell.CompassDirection = DegreeToDirection(swell.Direction)
+		swell.MaxEnergy = b.Energies[peakFrequencyIndex]
+		swell.FrequencyIndex = peakFrequencyIndex
+
+		partitions = append(partitions, SpectralPartition{
+			Swell:            swell,
+			MinimumFrequency: b.Frequencies[partition.minimumFrequency],
+			MaximumFrequency: b.Frequencies[partition.maximumFrequency],
+			PeakFrequency:    b.Frequencies[peakFrequencyIndex],
+			Energy:           partition.energy,
+			EnergyFraction:   partition.energy / totalEnergy,
+			Moments:          g.partitionMoments(b, labels, root, label),
+		})
+	}
+
+	sort.SliceStable(partitions, func(i, j int) bool {
+		if partitions[i].Energy == partitions[j].Energy {
+			return partitions[i].PeakFrequency < partitions[j].PeakFrequency
+		}
+		return partitions[i].Energy > partitions[j].Energy
+	})
+	return partitions
+}
+
+// Computes the spectral moments of a partition by summing the energy density of its cells into a
+// spectrum of its own
+func (g *spectralGraph) partitionMoments(b BuoySpectraItem, labels []int, root func(label int) int, label int) SpectralMoments {
+	bandwidths := make([]float64, len(b.Frequencies))
+	densities := make([]float64, len(b.Frequencies))
+	directionalCosines := make([]float64, len(b.Frequencies))
+	directionalSines := make([]float64, len(b.Frequencies))
+	for index, _ := range b.Frequencies {
+		bandwidths[index] = frequencyBandwidth(b.Frequencies, index)
+	}
+
+	for cell, cellLabel := range labels {
+		if cellLabel < 0 || root(cellLabel) != label {
+			continue
+		}
+
+		frequencyIndex := g.frequencyIndices[cell]
+		density := g.density(cell) * g.areas[cell] / bandwidths[frequencyIndex]
+		densities[frequencyIndex] += density
+		directionalCosines[frequencyIndex] += density * g.directionalCosines[cell]
+		directionalSines[frequencyIndex] += density * g.directionalSines[cell]
+	}
+	return newSpectralMoments(b.Frequencies, bandwidths, densities, directionalCosines, directionalSines, g.spreadKnown)
+}
@@ -0,0 +1,254 @@
+package surfnerd
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Decides if a station should be returned by a StationIndex query
+type StationFilter func(buoy *Buoy) bool
+
+// Keeps the stations that have reported meteorological data recently
+func ActiveFilter() StationFilter {
+	return func(buoy *Buoy) bool {
+		return buoy.IsBuoyActive()
+	}
+}
+
+// Keeps the stations of any of the given types, such as buoy, fixed, dart or other
+func TypeFilter(types ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyValue(buoy.Type, types)
+	}
+}
+
+// Keeps the stations owned by any of the given owners
+func OwnerFilter(owners ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyValue(buoy.Owner, owners)
+	}
+}
+
+// Keeps the stations of any of the given programs, such as NDBC Meteorological/Ocean or IOOS Partners
+func PGMFilter(programs ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyValue(buoy.PGM, programs)
+	}
+}
+
+// Keeps the stations that measure water currents
+func CurrentsFilter() StationFilter {
+	return func(buoy *Buoy) bool {
+		return buoy.DoesBuoyHaveWaterCurrentData()
+	}
+}
+
+// Keeps the stations that measure water quality
+func WaterQualityFilter() StationFilter {
+	return func(buoy *Buoy) bool {
+		return buoy.DoesBuoyHaveWaterQualityData()
+	}
+}
+
+// Keeps the stations that report wave spectra
+func SpectralDataFilter() StationFilter {
+	return func(buoy *Buoy) bool {
+		return buoy.DoesBuoyHaveSpectralData()
+	}
+}
+
+// Keeps the stations that pass any of the given filters
+func AnyFilter(filters ...StationFilter) StationFilter {
+	return func(buoy *Buoy) bool {
+		for _, filter := range filters {
+			if filter(buoy) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Keeps the stations that do not pass the given filter
+func NotFilter(filter StationFilter) StationFilter {
+	return func(buoy *Buoy) bool {
+		return !filter(buoy)
+	}
+}
+
+func matchesAnyValue(value string, values []string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// A station found by a StationIndex query, with its great circle distance from the queried location
+// in kilometers
+type StationDistance struct {
+	Buoy     *Buoy
+	Distance float64
+}
+
+// A spatial index of stations for finding the stations near a location. Stations are stored in a k-d tree
+// of points on the unit sphere, so distances are great circle distances that hold across the poles and the
+// antimeridian. The index does not change when the stations it was built from do.
+type StationIndex struct {
+	root  *stationNode
+	count int
+}
+
+type stationNode struct {
+	buoy  *Buoy
+	point [3]float64
+	axis  int
+	left  *stationNode
+	right *stationNode
+}
+
+// Creates and returns a StationIndex of the given stations. Stations without a location are left out.
+func NewStationIndex(stations []*Buoy) *StationIndex {
+	nodes := []*stationNode{}
+	for _, buoy := range stations {
+		if buoy == nil || buoy.Location == nil {
+			continue
+		}
+		nodes = append(nodes, &stationNode{buoy: buoy, point: unitVector(*buoy.Location)})
+	}
+	return &StationIndex{root: buildStationTree(nodes, 0), count: len(nodes)}
+}
+
+// Builds the tree by splitting the nodes at the median along each axis in turn
+func buildStationTree(nodes []*stationNode, depth int) *stationNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	axis := depth % 3
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].point[axis] < nodes[j].point[axis]
+	})
+
+	median := len(nodes) / 2
+	node := nodes[median]
+	node.axis = axis
+	node.left = buildStationTree(nodes[:median], depth+1)
+	node.right = buildStationTree(nodes[median+1:], depth+1)
+	return node
+}
+
+// Get the number of stations in the index
+func (s *StationIndex) Len() int {
+	return s.count
+}
+
+// Finds the n stations closest to the location that pass every filter, closest first
+func (s *StationIndex) Nearest(loc Location, n int, filters ...StationFilter) []StationDistance {
+	if n <= 0 {
+		return []StationDistance{}
+	}
+
+	target := unitVector(loc)
+	nearest := []StationDistance{}
+	chords := []float64{}
+
+	var search func(node *stationNode)
+	search = func(node *stationNode) {
+		if node == nil {
+			return
+		}
+
+		if passesFilters(node.buoy, filters) {
+			chord := chordLength(target, node.point)
+			if len(chords) < n || chord < chords[len(chords)-1] {
+				position := sort.SearchFloat64s(chords, chord)
+				for position < len(chords) && chords[position] == chord {
+					position++
+				}
+				chords = append(chords[:position], append([]float64{chord}, chords[position:]...)...)
+				nearest = append(nearest[:position], append([]StationDistance{{Buoy: node.buoy, Distance: loc.DistanceTo(*node.buoy.Location)}}, nearest[position:]...)...)
+				if len(chords) > n {
+					chords = chords[:n]
+					nearest = nearest[:n]
+				}
+			}
+		}
+
+		offset := target[node.axis] - node.point[node.axis]
+		near, far := node.left, node.right
+		if offset > 0 {
+			near, far = node.right, node.left
+		}
+		search(near)
+		if len(chords) < n || math.Abs(offset) < chords[len(chords)-1] {
+			search(far)
+		}
+	}
+	search(s.root)
+	return nearest
+}
+
+// Finds every station within the radius in kilometers of the location that passes every filter,
+// closest first
+func (s *StationIndex) WithinRadius(loc Location, radius float64, filters ...StationFilter) []StationDistance {
+	target := unitVector(loc)
+	maximumChord := 2.0
+	if radius < math.Pi*earthRadius {
+		maximumChord = 2.0 * math.Sin(radius/(2.0*earthRadius))
+	}
+
+	found := []StationDistance{}
+	var search func(node *stationNode)
+	search = func(node *stationNode) {
+		if node == nil {
+			return
+		}
+
+		chord := chordLength(target, node.point)
+		if chord <= maximumChord && passesFilters(node.buoy, filters) {
+			found = append(found, StationDistance{Buoy: node.buoy, Distance: loc.DistanceTo(*node.buoy.Location)})
+		}
+
+		offset := target[node.axis] - node.point[node.axis]
+		if offset >= -maximumChord {
+			search(node.right)
+		}
+		if offset <= maximumChord {
+			search(node.left)
+		}
+	}
+	search(s.root)
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Distance < found[j].Distance
+	})
+	return found
+}
+
+func passesFilters(buoy *Buoy, filters []StationFilter) bool {
+	for _, filter := range filters {
+		if !filter(buoy) {
+			return false
+		}
+	}
+	return true
+}
+
+// Get the point of the location on the unit sphere
+func unitVector(loc Location) [3]float64 {
+	latitude := loc.Latitude * math.Pi / 180.0
+	longitude := loc.Longitude * math.Pi / 180.0
+	return [3]float64{
+		math.Cos(latitude) * math.Cos(longitude),
+		math.Cos(latitude) * math.Sin(longitude),
+		math.Sin(latitude),
+	}
+}
+
+// Get the straight line distance between two points on the unit sphere
+func chordLength(first, second [3]float64) float64 {
+	return math.Sqrt(math.Pow(first[0]-second[0], 2) + math.Pow(first[1]-second[1], 2) + math.Pow(first[2]-second[2], 2))
+}
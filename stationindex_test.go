@@ -0,0 +1,126 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func newTestStations() *BuoyStations {
+	random := rand.New(rand.NewSource(42))
+	stations := &BuoyStations{}
+	for index := 0; index < 500; index++ {
+		location := NewLocationForLatLong(random.Float64()*180.0-90.0, random.Float64()*360.0-180.0)
+		buoy := &Buoy{Location: &location, StationID: strconv.Itoa(index), Type: "buoy", Active: "y", Owner: "NDBC"}
+		if index%3 == 0 {
+			buoy.Type = "fixed"
+		}
+		if index%5 == 0 {
+			buoy.Active = "n"
+		}
+		if index%7 == 0 {
+			buoy.Currents = "y"
+		}
+		buoy.SpectralData = buoy.Type == "buoy" && buoy.IsBuoyActive()
+		stations.Stations = append(stations.Stations, buoy)
+	}
+	return stations
+}
+
+// Great circle distance with the spherical law of cosines, to check the index against
+func testGreatCircleDistance(first, second Location) float64 {
+	firstLatitude, secondLatitude := first.Latitude*math.Pi/180.0, second.Latitude*math.Pi/180.0
+	longitudeDifference := (second.Longitude - first.Longitude) * math.Pi / 180.0
+	cosine := math.Sin(firstLatitude)*math.Sin(secondLatitude) + math.Cos(firstLatitude)*math.Cos(secondLatitude)*math.Cos(longitudeDifference)
+	return earthRadius * math.Acos(math.Max(-1, math.Min(1, cosine)))
+}
+
+func TestStationIndexQueries(t *testing.T) {
+	stations := newTestStations()
+	filters := []StationFilter{SpectralDataFilter(), NotFilter(CurrentsFilter())}
+	query := NewLocationForLatLong(41.0, 289.0)
+
+	expected := []float64{}
+	for _, buoy := range stations.Stations {
+		if passesFilters(buoy, filters) {
+			expected = append(expected, testGreatCircleDistance(query, *buoy.Location))
+		}
+	}
+	sortedExpected := append([]float64{}, expected...)
+	sort.Float64s(sortedExpected)
+
+	nearest := stations.Nearest(query, 10, filters...)
+	if len(nearest) != 10 {
+		fmt.Println("Expected 10 nearest stations")
+		t.FailNow()
+	}
+	for index, station := range nearest {
+		if math.Abs(station.Distance-sortedExpected[index]) > 1e-3 || !passesFilters(station.Buoy, filters) {
+			fmt.Println("Nearest stations do not match a linear search")
+			t.FailNow()
+		}
+	}
+
+	radius := sortedExpected[25] + 1.0
+	within := stations.WithinRadius(query, radius, filters...)
+	if len(within) != 26 || within[25].Distance > radius {
+		fmt.Println("Expected 26 stations within the radius, found", len(within))
+		t.FailNow()
+	}
+
+	closestBuoys := stations.FindClosestActiveWaveBuoys(query)
+	if len(closestBuoys) != 3 || closestBuoys[0] == nil || closestBuoys[2] == nil {
+		fmt.Println("Expected the three closest wave buoys")
+		t.FailNow()
+	}
+	for index, _ := range closestBuoys[1:] {
+		if testGreatCircleDistance(query, *closestBuoys[index].Location) > testGreatCircleDistance(query, *closestBuoys[index+1].Location) {
+			fmt.Println("Closest wave buoys are out of order")
+			t.FailNow()
+		}
+	}
+
+	if len(stations.Nearest(query, 5, OwnerFilter("nobody"))) != 0 {
+		fmt.Println("Expected no stations to pass the owner filter")
+		t.FailNow()
+	}
+}
+
+func TestStationIndexAcrossAntimeridian(t *testing.T) {
+	west := NewLocationForLatLong(60.0, 179.5)
+	east := NewLocationForLatLong(60.0, -170.0)
+	index := NewStationIndex([]*Buoy{{StationID: "west", Location: &west}, {StationID: "east", Location: &east}})
+
+	nearest := index.Nearest(NewLocationForLatLong(60.0, -179.5), 1)
+	if len(nearest) != 1 || nearest[0].Buoy.StationID != "west" || math.Abs(nearest[0].Distance-testGreatCircleDistance(west, NewLocationForLatLong(60.0, 180.5))) > 1e-6 {
+		fmt.Println("Expected the station across the antimeridian to be closest")
+		t.FailNow()
+	}
+}
+
+func TestStationIndexConcurrentQueries(t *testing.T) {
+	stations := newTestStations()
+	query := NewLocationForLatLong(41.0, 289.0)
+
+	results := make([][]StationDistance, 8)
+	group := sync.WaitGroup{}
+	for index, _ := range results {
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			results[index] = stations.Nearest(query, 5)
+		}(index)
+	}
+	group.Wait()
+
+	for _, result := range results {
+		if len(result) != 5 || result[0].Buoy != results[0][0].Buoy {
+			fmt.Println("Concurrent queries should share the same index")
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Holds the extremes a buoy measured over the hour ending at Date. Pressure is in hectopascals,
+// speeds are in meters per second and directions are in degrees the wind is coming from. Times
+// are zero when the station did not report them. More info is available here
+// http://www.ndbc.noaa.gov/measdes.shtml#supl
+type SupplementalDataItem struct {
+	Date time.Time
+
+	// The lowest one minute pressure of the hour
+	LowestPressure     float64
+	LowestPressureTime time.Time
+
+	// The highest one minute wind speed of the hour and its direction
+	HighestWindSpeed     float64
+	HighestWindDirection float64
+	HighestWindTime      time.Time
+}
+
+// Creates and returns the url for fetching the buoys supplemental measurements.
+// The url returns tab delimited ascii data.
+func (b Buoy) CreateSupplementalDataURL() string {
+	return b.client().endpoints().ndbcURL(baseDataURL, b.StationID, supplementalDataPostfix)
+}
+
+// Parses the contents of a NDBC supplemental measurements (.supl) file into a time series of
+// SupplementalDataItem objects. Input a negative integer to parse all available data points. A
+// malformed line is reported as a *ParseError and leaves the SupplementalData untouched.
+func (b *Buoy) ParseRawSupplementalData(rawData string, dataCountLimit int) error {
+	table, tableErr := parseNDBCTable(rawData)
+	if tableErr != nil {
+		return tableErr
+	} else if columnErr := table.requireColumns("YY", "MM", "DD", "hh"); columnErr != nil {
+		return columnErr
+	}
+
+	dataLineCount := len(table.Rows)
+	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
+		dataLineCount = dataCountLimit
+	}
+
+	supplementalData := make([]SupplementalDataItem, dataLineCount)
+	for itemIndex, row := range table.Rows[:dataLineCount] {
+		values := table.reader(row)
+		item := SupplementalDataItem{Date: values.date()}
+		item.LowestPressure = values.float("PRES", pressureSentinel)
+		item.LowestPressureTime = values.clockTime("PTIME", item.Date)
+		item.HighestWindSpeed = values.float("WSPD", speedSentinel)
+		item.HighestWindDirection = values.float("WDIR", directionSentinel)
+		item.HighestWindTime = values.clockTime("WTIME", item.Date)
+
+		if values.err != nil {
+			return values.err
+		}
+		supplementalData[itemIndex] = item
+	}
+
+	b.SupplementalData = supplementalData
+	return nil
+}
+
+// Grabs the latest supplemental measurements as a time series of SupplementalDataItem objects. Input
+// a negative integer to download all available data points.
+func (b *Buoy) FetchSupplementalData(dataCountLimit int) error {
+	return b.FetchSupplementalDataContext(context.Background(), dataCountLimit)
+}
+
+// Same as FetchSupplementalData, but the download is bound to the given context
+func (b *Buoy) FetchSupplementalDataContext(ctx context.Context, dataCountLimit int) error {
+	rawData, fetchErr := fetchRawDataFromURL(ctx, b.client().fetcher(), b.CreateSupplementalDataURL())
+	if fetchErr != nil {
+		return stationFetchError(fetchErr)
+	}
+
+	return b.ParseRawSupplementalData(string(rawData), dataCountLimit)
+}
+
+// Encodes the item as json, leaving out missing measurements
+func (s SupplementalDataItem) MarshalJSON() ([]byte, error) {
+	type supplementalDataItemJSON SupplementalDataItem
+	return marshalOmittingMissing(supplementalDataItemJSON(s))
+}
+
+// Decodes the item from json. Measurements left out of the json are missing.
+func (s *SupplementalDataItem) UnmarshalJSON(data []byte) error {
+	type supplementalDataItemJSON SupplementalDataItem
+	item := supplementalDataItemJSON{}
+	markFloatsMissing(&item)
+
+	jsonErr := json.Unmarshal(data, &item)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*s = SupplementalDataItem(item)
+	return nil
+}
@@ -3,6 +3,8 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
+	"sort"
+	"time"
 )
 
 // A human readable abstracted representation of a surfing forecast for a given location.
@@ -51,6 +53,11 @@ func (s *SurfForecast) ExportAsJSON(filename string) error {
 }
 
 func NewSurfForecast(loc Location, beachAngle, beachSlope float64, waveForecast *WaveForecast, windForecast *WindForecast) *SurfForecast {
+	// Require that there is wave data
+	if waveForecast == nil || len(waveForecast.ForecastData) < 1 {
+		return nil
+	}
+
 	surfForecast := &SurfForecast{}
 	surfForecast.Location = loc
 	surfForecast.BeachAngle = beachAngle
@@ -61,49 +68,61 @@ func NewSurfForecast(loc Location, beachAngle, beachSlope float64, waveForecast
 	if waveForecast.Model.Units != Metric {
 		waveForecast.ChangeUnits(Metric)
 	}
-	if windForecast.Model.Units != Metric {
-		windForecast.ChangeUnits(Metric)
-	}
 
 	// Save the model metadata
 	surfForecast.WaveModel = waveForecast.Model
 	surfForecast.WaveModelLocation = waveForecast.Location
-	surfForecast.WindModel = windForecast.Model
-	surfForecast.WindModelLocation = windForecast.Location
 
 	// Initialize the surf forecast data slice
 	surfForecast.ForecastData = make([]SurfForecastItem, len(waveForecast.ForecastData))
 
-	// Require that there is wave data
-	if waveForecast.ForecastData == nil {
-		return nil
-	} else if len(waveForecast.ForecastData) < 1 {
-		return nil
-	}
-
-	noWindData := false
-	if windForecast.ForecastData == nil {
-		noWindData = true
-	} else if len(windForecast.ForecastData) < 1 {
-		noWindData = true
+	// The wind model may start at a different time or step at a different rate than the wave model.
+	// Without a wind forecast every step uses the wind of the wave model.
+	windData := []WindForecastItem{}
+	var maximumWindGap time.Duration
+	if windForecast != nil {
+		if windForecast.Model.Units != Metric {
+			windForecast.ChangeUnits(Metric)
+		}
+		surfForecast.WindModel = windForecast.Model
+		surfForecast.WindModelLocation = windForecast.Location
+
+		windData = append(windData, windForecast.ForecastData...)
+		sort.SliceStable(windData, func(i, j int) bool {
+			return windData[i].Date.Before(windData[j].Date)
+		})
+		for index, _ := range windData {
+			if isModelFillValue(windData[index].WindGustSpeed) {
+				windData[index].WindGustSpeed = MissingValue()
+			}
+		}
+		maximumWindGap = 2 * windStep(windForecast.Model, windData)
 	}
 
 	// Get the wind and wave data from the two model runs
 	for i, _ := range waveForecast.ForecastData {
 		surfForecastItem := SurfForecastItem{}
 		surfForecastItem.Date = waveForecast.ForecastData[i].Date
-		surfForecastItem.Time = waveForecast.ForecastData[i].Time
 
-		if !noWindData {
-			surfForecastItem.WindSpeed = windForecast.ForecastData[i].WindSpeed
-			surfForecastItem.WindGustSpeed = windForecast.ForecastData[i].WindGustSpeed
-			surfForecastItem.WindDirection = windForecast.ForecastData[i].WindDirection
-			surfForecastItem.WindCompassDirection = DegreeToDirection(windForecast.ForecastData[i].WindDirection)
+		if wind, source, ok := windAtTime(windData, surfForecastItem.Date, maximumWindGap); ok {
+			surfForecastItem.WindSpeed = wind.WindSpeed
+			surfForecastItem.WindGustSpeed = wind.WindGustSpeed
+			surfForecastItem.WindDirection = wind.WindDirection
+			surfForecastItem.WindCompassDirection = DegreeToDirection(wind.WindDirection)
+			surfForecastItem.WindSource = source
+		} else if waveWind := waveForecast.ForecastData[i]; !isModelFillValue(waveWind.SurfaceWindSpeed) && !isModelFillValue(waveWind.SurfaceWindDirection) {
+			// The wave model has no gusts
+			surfForecastItem.WindSpeed = waveWind.SurfaceWindSpeed
+			surfForecastItem.WindGustSpeed = MissingValue()
+			surfForecastItem.WindDirection = waveWind.SurfaceWindDirection
+			surfForecastItem.WindCompassDirection = DegreeToDirection(waveWind.SurfaceWindDirection)
+			surfForecastItem.WindSource = WaveModelWindSource
 		} else {
-			surfForecastItem.WindSpeed = waveForecast.ForecastData[i].SurfaceWindSpeed
-			surfForecastItem.WindGustSpeed = -1
-			surfForecastItem.WindDirection = waveForecast.ForecastData[i].SurfaceWindDirection
-			surfForecastItem.WindCompassDirection = DegreeToDirection(waveForecast.ForecastData[i].SurfaceWindDirection)
+			surfForecastItem.WindSpeed = MissingValue()
+			surfForecastItem.WindGustSpeed = MissingValue()
+			surfForecastItem.WindDirection = MissingValue()
+			surfForecastItem.WindCompassDirection = DegreeToDirection(MissingValue())
+			surfForecastItem.WindSource = NoWindSource
 		}
 
 		swellOne := Swell{}
@@ -173,3 +192,61 @@ func NewSurfForecast(loc Location, beachAngle, beachSlope float64, waveForecast
 	}
 	return surfForecast
 }
+
+// Get the time between the steps of the wind model, or the shortest time between the wind data
+// sorted by time when the model does not give it
+func windStep(model NOAAModel, windData []WindForecastItem) time.Duration {
+	if model.TimeResolution > 0 {
+		return time.Duration(model.TimeResolutionHours() * float64(time.Hour))
+	}
+
+	var step time.Duration
+	for index := 1; index < len(windData); index++ {
+		if gap := windData[index].Date.Sub(windData[index-1].Date); gap > 0 && (step == 0 || gap < step) {
+			step = gap
+		}
+	}
+	return step
+}
+
+// Finds the wind at the given time in wind data sorted by time. The wind is taken from the step at
+// the time, or interpolated between the steps on either side of it, with directions interpolated
+// the short way around the compass. Returns false when the time is outside of the wind data, the
+// steps around it are more than maximumGap apart, or the wind around it is missing.
+func windAtTime(windData []WindForecastItem, date time.Time, maximumGap time.Duration) (WindForecastItem, WindSource, bool) {
+	index := sort.Search(len(windData), func(i int) bool {
+		return !windData[i].Date.Before(date)
+	})
+	if index == len(windData) {
+		return WindForecastItem{}, "", false
+	}
+
+	after := windData[index]
+	if after.Date.Equal(date) {
+		return after, ModelWindSource, isValidWind(after)
+	} else if index == 0 {
+		return WindForecastItem{}, "", false
+	}
+
+	before := windData[index-1]
+	if after.Date.Sub(before.Date) > maximumGap || !isValidWind(before) || !isValidWind(after) {
+		return WindForecastItem{}, "", false
+	}
+
+	fraction := date.Sub(before.Date).Seconds() / after.Date.Sub(before.Date).Seconds()
+	interpolate := func(first, second float64) float64 {
+		return first + fraction*(second-first)
+	}
+
+	wind := before
+	wind.Date = date
+	wind.WindSpeed = interpolate(before.WindSpeed, after.WindSpeed)
+	wind.WindGustSpeed = interpolate(before.WindGustSpeed, after.WindGustSpeed)
+	wind.WindDirection = normalizeDirection(before.WindDirection + fraction*angleDifference(before.WindDirection, after.WindDirection))
+	return wind, InterpolatedWindSource, true
+}
+
+// Checks that the wind speed and direction are not missing or filled in by the model
+func isValidWind(wind WindForecastItem) bool {
+	return !isModelFillValue(wind.WindSpeed) && !isModelFillValue(wind.WindDirection)
+}
@@ -1,7 +1,12 @@
 package surfnerd
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSurfForecastFetch(t *testing.T) {
@@ -32,3 +37,129 @@ func TestSurfForecastFetch(t *testing.T) {
 	surfForecast.ChangeUnits(English)
 	surfForecast.ExportAsJSON("test_forecast.json")
 }
+
+func TestModelTimeDecoding(t *testing.T) {
+	decoded := DecodeModelTime(736619.5)
+	if !decoded.Equal(time.Date(2017, time.October, 16, 12, 0, 0, 0, time.UTC)) {
+		fmt.Println("Model time was decoded to the wrong date:", decoded)
+		t.FailNow()
+	}
+	if math.Abs(EncodeModelTime(decoded)-736619.5) > 1e-9 {
+		fmt.Println("Model time did not survive a round trip")
+		t.FailNow()
+	}
+
+	model := NewEastCoastWaveModel()
+	model.SetModelRun(NewModelRun(decoded, 0))
+	rawData := []byte("time, [3]\n736619.5, 736619.625, 736619.75\n")
+	for _, variable := range []string{"dirpwsfc", "htsgwsfc", "perpwsfc", "swell_1", "swdir_1", "swper_1", "swell_2", "swdir_2", "swper_2", "wvhgtsfc", "wvdirsfc", "wvpersfc", "windsfc", "wdirsfc"} {
+		rawData = append(rawData, []byte(variable+", [3][1][1]\n[0][0], 1.0\n[1][0], 2.0\n[2][0], 3.0\n")...)
+	}
+	forecast := WaveForecastFromModelData(WaveModelDataFromRaw(NewLocationForLatLong(41.0, 288.5), model.NOAAModel, rawData))
+	if len(forecast.ForecastData) != 3 || !forecast.ForecastData[2].Date.Equal(decoded.Add(6*time.Hour)) {
+		fmt.Println("Forecast times should come from the model time axis")
+		t.FailNow()
+	}
+
+	jsonData, _ := json.Marshal(forecast.ForecastData[0])
+	if !strings.Contains(string(jsonData), `"Date":"2017-10-16T12:00:00Z"`) {
+		fmt.Println("Forecast times should be encoded as RFC3339:", string(jsonData))
+		t.FailNow()
+	}
+}
+
+func TestSurfForecastAlignsWindByTime(t *testing.T) {
+	start := time.Date(2017, time.October, 16, 0, 0, 0, 0, time.UTC)
+	waveForecast := &WaveForecast{Model: NOAAModel{Units: Metric}}
+	for step := 0; step < 6; step++ {
+		waveForecast.ForecastData = append(waveForecast.ForecastData, WaveForecastItem{
+			Date:                 start.Add(time.Duration(3*step) * time.Hour),
+			PrimarySwellPeriod:   10.0,
+			SurfaceWindSpeed:     1.0,
+			SurfaceWindDirection: 90.0,
+			Units:                Metric,
+		})
+	}
+
+	// The wind model starts three hours later, steps every six hours and ends early
+	windForecast := &WindForecast{Model: NOAAModel{Units: Metric}, ForecastData: []WindForecastItem{
+		{Date: start.Add(9 * time.Hour), WindSpeed: 8.0, WindGustSpeed: 10.0, WindDirection: 10.0, Units: Metric},
+		{Date: start.Add(3 * time.Hour), WindSpeed: 4.0, WindGustSpeed: 6.0, WindDirection: 350.0, Units: Metric},
+	}}
+
+	surfForecast := NewSurfForecast(NewLocationForLatLong(41.0, -71.0), 145.0, 0.02, waveForecast, windForecast)
+	expectedSources := []WindSource{WaveModelWindSource, ModelWindSource, InterpolatedWindSource, ModelWindSource, WaveModelWindSource, WaveModelWindSource}
+	for index, item := range surfForecast.ForecastData {
+		if item.WindSource != expectedSources[index] {
+			fmt.Println("Wrong wind source at step", index, item.WindSource)
+			t.FailNow()
+		}
+	}
+
+	interpolated := surfForecast.ForecastData[2]
+	if math.Abs(interpolated.WindSpeed-6.0) > 1e-9 || math.Abs(interpolated.WindGustSpeed-8.0) > 1e-9 || math.Abs(interpolated.WindDirection) > 1e-9 {
+		fmt.Println("Wind was not interpolated across north:", interpolated.WindSpeed, interpolated.WindDirection)
+		t.FailNow()
+	}
+	if surfForecast.ForecastData[5].WindSpeed != 1.0 || surfForecast.ForecastData[5].WindDirection != 90.0 || !IsMissing(surfForecast.ForecastData[5].WindGustSpeed) {
+		fmt.Println("Steps past the wind model should use the wave model wind without gusts")
+		t.FailNow()
+	}
+
+	// For a model stepping every 90 minutes the steps are too far apart to interpolate between, and
+	// without wave model wind there is no wind at all
+	windForecast.Model.TimeResolution = 1.5 / 24.0
+	waveForecast.ForecastData[2].SurfaceWindSpeed = MissingValue()
+	surfForecast = NewSurfForecast(NewLocationForLatLong(41.0, -71.0), 145.0, 0.02, waveForecast, windForecast)
+	if surfForecast.ForecastData[2].WindSource != NoWindSource || !IsMissing(surfForecast.ForecastData[2].WindSpeed) {
+		fmt.Println("Expected no wind where the wind model steps are too far apart:", surfForecast.ForecastData[2].WindSource)
+		t.FailNow()
+	}
+}
+
+func TestSurfForecastWindFillValues(t *testing.T) {
+	start := time.Date(2017, time.October, 16, 0, 0, 0, 0, time.UTC)
+	waveForecast := &WaveForecast{Model: NOAAModel{Units: Metric}}
+	for step := 0; step < 3; step++ {
+		waveForecast.ForecastData = append(waveForecast.ForecastData, WaveForecastItem{
+			Date:                 start.Add(time.Duration(3*step) * time.Hour),
+			PrimarySwellPeriod:   10.0,
+			SurfaceWindSpeed:     1.0,
+			SurfaceWindDirection: 90.0,
+			Units:                Metric,
+		})
+	}
+
+	// The wind model fills the first step, and only the gust of the second step
+	windForecast := &WindForecast{Model: NOAAModel{Units: Metric, TimeResolution: 0.125}, ForecastData: []WindForecastItem{
+		{Date: start, WindSpeed: modelFillValue, WindGustSpeed: modelFillValue, WindDirection: modelFillValue, Units: Metric},
+		{Date: start.Add(3 * time.Hour), WindSpeed: 4.0, WindGustSpeed: modelFillValue, WindDirection: 350.0, Units: Metric},
+		{Date: start.Add(6 * time.Hour), WindSpeed: 6.0, WindGustSpeed: 8.0, WindDirection: 10.0, Units: Metric},
+	}}
+
+	surfForecast := NewSurfForecast(NewLocationForLatLong(41.0, -71.0), 145.0, 0.02, waveForecast, windForecast)
+	if surfForecast.ForecastData[0].WindSource != WaveModelWindSource || surfForecast.ForecastData[0].WindSpeed != 1.0 {
+		fmt.Println("A filled wind step should fall back to the wave model wind:", surfForecast.ForecastData[0].WindSource)
+		t.FailNow()
+	}
+	if surfForecast.ForecastData[1].WindSource != ModelWindSource || surfForecast.ForecastData[1].WindSpeed != 4.0 || !IsMissing(surfForecast.ForecastData[1].WindGustSpeed) {
+		fmt.Println("A filled gust should be missing:", surfForecast.ForecastData[1].WindGustSpeed)
+		t.FailNow()
+	}
+
+	// Without a wind forecast every step uses the wave model wind
+	for _, windForecast := range []*WindForecast{nil, {}} {
+		surfForecast = NewSurfForecast(NewLocationForLatLong(41.0, -71.0), 145.0, 0.02, waveForecast, windForecast)
+		for index, item := range surfForecast.ForecastData {
+			if item.WindSource != WaveModelWindSource || item.WindSpeed != 1.0 {
+				fmt.Println("Expected the wave model wind without a wind forecast at step", index, item.WindSource)
+				t.FailNow()
+			}
+		}
+	}
+
+	if NewSurfForecast(NewLocationForLatLong(41.0, -71.0), 145.0, 0.02, nil, windForecast) != nil {
+		fmt.Println("Expected no surf forecast without a wave forecast")
+		t.FailNow()
+	}
+}
@@ -1,15 +1,36 @@
 package surfnerd
 
+import (
+	"time"
+)
+
+// Where the wind of a surf forecast item came from
+type WindSource string
+
+const (
+	// The wind model had a step at the time of the item
+	ModelWindSource WindSource = "model"
+
+	// The wind was interpolated between the wind model steps around the time of the item
+	InterpolatedWindSource WindSource = "interpolated"
+
+	// The wind model did not cover the time of the item, so the surface wind of the wave model was used
+	WaveModelWindSource WindSource = "wavemodel"
+
+	// Neither the wind model nor the wave model had wind at the time of the item, so it is missing
+	NoWindSource WindSource = "none"
+)
+
 // A single timestep in a surf forecast.
 type SurfForecastItem struct {
-	Date                    string
-	Time                    string
+	Date                    time.Time
 	MinimumBreakingHeight   float64
 	MaximumBreakingHeight   float64
 	WindSpeed               float64
 	WindGustSpeed           float64
 	WindDirection           float64
 	WindCompassDirection    string
+	WindSource              WindSource
 	PrimarySwellComponent   Swell
 	SecondarySwellComponent Swell
 	TertiarySwellComponent  Swell
@@ -1,6 +1,7 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
 )
 
@@ -37,7 +38,9 @@ func (s *Swell) ChangeUnits(newUnits UnitSystem) {
 
 // Tests if the swell has valid numbers or if it is just maxed out to show null
 func (s *Swell) IsValid() bool {
-	if s.WaveHeight > 1000 {
+	if IsMissing(s.WaveHeight) {
+		return false
+	} else if s.WaveHeight > 1000 {
 		return false
 	}
 	return true
@@ -69,6 +72,16 @@ func (s *Swell) BreakingWaveHeights(beachAngle, depth, beachSlope float64) (mini
 	return
 }
 
+// Creates a swell in the given unit system with a missing height, period and direction
+func NewMissingSwell(units UnitSystem) Swell {
+	return Swell{
+		WaveHeight: MissingValue(),
+		Period:     MissingValue(),
+		Direction:  MissingValue(),
+		Units:      units,
+	}
+}
+
 func NewSwellWithDirection(waveHeight, period, direction float64) Swell {
 	swell := Swell{
 		WaveHeight:       waveHeight,
@@ -89,6 +102,27 @@ func NewSwellWithCompassDirection(waveHeight, period float64, direction string)
 	return swell
 }
 
+// Encodes the swell as json, leaving out missing values
+func (s Swell) MarshalJSON() ([]byte, error) {
+	type swellJSON Swell
+	return marshalOmittingMissing(swellJSON(s))
+}
+
+// Decodes the swell from json. Values left out of the json are missing.
+func (s *Swell) UnmarshalJSON(data []byte) error {
+	type swellJSON Swell
+	swell := swellJSON{}
+	markFloatsMissing(&swell)
+
+	jsonErr := json.Unmarshal(data, &swell)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	*s = Swell(swell)
+	return nil
+}
+
 type ByMaxEnergy []Swell
 
 func (b ByMaxEnergy) Len() int {
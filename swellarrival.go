@@ -0,0 +1,234 @@
+package surfnerd
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Options for detecting the arrival of groundswells in a time series of spectra
+type SwellArrivalOptions struct {
+	// Only partitions peaking below this frequency in Hz are followed as arriving groundswell
+	MaximumFrequency float64
+
+	// How much the peak frequency may drop between readings in Hz before an arrival is over. Spectra
+	// have a coarse frequency resolution, so the peak jumps around a little even as it drifts up.
+	FrequencyTolerance float64
+
+	// The shortest arrival that is reported, in readings and in time, and the longest gap allowed
+	// between two readings of the same arrival
+	MinimumObservations int
+	MinimumDuration     time.Duration
+	MaximumGap          time.Duration
+
+	// The correlation the fitted drift of the peak frequency must have with time
+	MinimumCorrelation float64
+
+	// How the spectra are partitioned to find the groundswell
+	PartitionOptions PartitionOptions
+}
+
+// Creates and returns the options used to detect swell arrivals at NDBC buoys, which report spectra
+// every hour
+func DefaultSwellArrivalOptions() SwellArrivalOptions {
+	return SwellArrivalOptions{
+		MaximumFrequency:    0.1,
+		FrequencyTolerance:  0.005,
+		MinimumObservations: 6,
+		MinimumDuration:     6 * time.Hour,
+		MaximumGap:          3 * time.Hour,
+		MinimumCorrelation:  0.8,
+		PartitionOptions:    DefaultPartitionOptions(),
+	}
+}
+
+// The arrival of a groundswell from a distant storm. Long period waves travel faster than short
+// period waves in deep water, so the peak frequency of an arriving swell rises steadily over time.
+// The rate it rises gives the distance to the storm that generated it, and extending it back to
+// zero frequency gives when the storm generated it.
+type SwellArrival struct {
+	// The first and last readings of the arrival, with the peak frequency in Hz at each
+	Start          time.Time
+	End            time.Time
+	StartFrequency float64
+	EndFrequency   float64
+
+	// The fitted rate the peak frequency rose in Hz per hour, and its correlation with time
+	FrequencySlope float64
+	Correlation    float64
+
+	// The distance to the storm in kilometers with its standard error, and the time the storm
+	// generated the swell
+	Distance         float64
+	DistanceError    float64
+	GenerationTime   time.Time
+	PeakWaveHeight   float64
+	MeanDirection    float64
+	CompassDirection string
+
+	// The location the swell was generated, found by following the mean direction back along a great
+	// circle from the buoy. Nil when the location of the buoy or the direction of the swell is unknown.
+	SourceLocation *Location `json:",omitempty"`
+}
+
+// The groundswell partition of a single reading
+type swellArrivalReading struct {
+	date      time.Time
+	partition SpectralPartition
+}
+
+// Detects groundswell arrivals in a time series of BuoyDataItem objects with wave spectra, in any order.
+// The location of the buoy is used to find the source of each swell and may be nil. Arrivals are
+// returned oldest first.
+func DetectSwellArrivals(items []BuoyDataItem, location *Location, options SwellArrivalOptions) []SwellArrival {
+	readings := []swellArrivalReading{}
+	for _, item := range items {
+		if len(item.WaveSpectra.Frequencies) == 0 {
+			continue
+		}
+
+		partitions, partitionErr := item.WaveSpectra.Partition(options.PartitionOptions)
+		if partitionErr != nil {
+			continue
+		}
+
+		// Partitions are sorted by energy, so the first low frequency one is the dominant groundswell
+		for _, partition := range partitions {
+			if partition.PeakFrequency < options.MaximumFrequency {
+				readings = append(readings, swellArrivalReading{date: item.Date, partition: partition})
+				break
+			}
+		}
+	}
+	sort.SliceStable(readings, func(i, j int) bool {
+		return readings[i].date.Before(readings[j].date)
+	})
+
+	arrivals := []SwellArrival{}
+	start := 0
+	for index := 1; index <= len(readings); index++ {
+		if index < len(readings) {
+			gap := readings[index].date.Sub(readings[index-1].date)
+			drop := readings[index-1].partition.PeakFrequency - readings[index].partition.PeakFrequency
+			if gap <= options.MaximumGap && drop <= options.FrequencyTolerance {
+				continue
+			}
+		}
+
+		if arrival, ok := newSwellArrival(readings[start:index], location, options); ok {
+			arrivals = append(arrivals, arrival)
+		}
+		start = index
+	}
+	return arrivals
+}
+
+// Fits the drift of the peak frequency of a run of readings, and returns the arrival when the run
+// looks like a dispersive arrival
+func newSwellArrival(readings []swellArrivalReading, location *Location, options SwellArrivalOptions) (SwellArrival, bool) {
+	if len(readings) < options.MinimumObservations || len(readings) < 3 {
+		return SwellArrival{}, false
+	}
+
+	first, last := readings[0], readings[len(readings)-1]
+	if last.date.Sub(first.date) < options.MinimumDuration {
+		return SwellArrival{}, false
+	}
+
+	hours := make([]float64, len(readings))
+	frequencies := make([]float64, len(readings))
+	for index, reading := range readings {
+		hours[index] = reading.date.Sub(first.date).Hours()
+		frequencies[index] = reading.partition.PeakFrequency
+	}
+
+	slope, intercept, correlation, slopeError := fitLine(hours, frequencies)
+	if slope <= 0 || correlation < options.MinimumCorrelation {
+		return SwellArrival{}, false
+	}
+
+	// Deep water waves travel at the group velocity g / (4 pi f), so a swell generated at t0 a distance
+	// D away arrives at frequency f = g (t - t0) / (4 pi D). The slope of the drift is g / (4 pi D).
+	slopePerSecond := slope / 3600.0
+	distance := 9.81 / (4.0 * math.Pi * slopePerSecond) / 1000.0
+	if distance > math.Pi*earthRadius {
+		return SwellArrival{}, false
+	}
+
+	arrival := SwellArrival{
+		Start:          first.date,
+		End:            last.date,
+		StartFrequency: first.partition.PeakFrequency,
+		EndFrequency:   last.partition.PeakFrequency,
+		FrequencySlope: slope,
+		Correlation:    correlation,
+		Distance:       distance,
+		DistanceError:  distance * slopeError / slope,
+		GenerationTime: first.date.Add(time.Duration(-intercept / slope * float64(time.Hour))),
+		PeakWaveHeight: MissingValue(),
+	}
+
+	// Average the direction as a vector weighted by the energy of each reading
+	directionalCosine, directionalSine := 0.0, 0.0
+	for _, reading := range readings {
+		if IsMissing(arrival.PeakWaveHeight) || reading.partition.Swell.WaveHeight > arrival.PeakWaveHeight {
+			arrival.PeakWaveHeight = reading.partition.Swell.WaveHeight
+		}
+
+		direction := reading.partition.Moments.MeanDirection()
+		if IsMissing(direction) {
+			continue
+		}
+		directionalCosine += reading.partition.Energy * math.Cos(direction*math.Pi/180.0)
+		directionalSine += reading.partition.Energy * math.Sin(direction*math.Pi/180.0)
+	}
+
+	arrival.MeanDirection = MissingValue()
+	if directionalCosine != 0 || directionalSine != 0 {
+		arrival.MeanDirection = normalizeDirection(math.Atan2(directionalSine, directionalCosine) * 180.0 / math.Pi)
+		arrival.CompassDirection = DegreeToDirection(arrival.MeanDirection)
+		if location != nil {
+			source := location.DestinationPoint(arrival.MeanDirection, arrival.Distance)
+			arrival.SourceLocation = &source
+		}
+	}
+	return arrival, true
+}
+
+// Fits a line to the points with least squares. Returns the slope and intercept, the correlation
+// of the points, and the standard error of the slope.
+func fitLine(xs, ys []float64) (slope, intercept, correlation, slopeError float64) {
+	count := float64(len(xs))
+	meanX, meanY := 0.0, 0.0
+	for index, _ := range xs {
+		meanX += xs[index] / count
+		meanY += ys[index] / count
+	}
+
+	sumXX, sumYY, sumXY := 0.0, 0.0, 0.0
+	for index, _ := range xs {
+		sumXX += math.Pow(xs[index]-meanX, 2)
+		sumYY += math.Pow(ys[index]-meanY, 2)
+		sumXY += (xs[index] - meanX) * (ys[index] - meanY)
+	}
+	if sumXX == 0 || sumYY == 0 {
+		return 0, meanY, 0, MissingValue()
+	}
+
+	slope = sumXY / sumXX
+	intercept = meanY - slope*meanX
+	correlation = sumXY / math.Sqrt(sumXX*sumYY)
+
+	residuals := 0.0
+	for index, _ := range xs {
+		residuals += math.Pow(ys[index]-(intercept+slope*xs[index]), 2)
+	}
+	slopeError = math.Sqrt(residuals / math.Max(count-2.0, 1.0) / sumXX)
+	return
+}
+
+// Detects groundswell arrivals in the spectra of the BuoyData. Fetch the wave spectra for at least
+// a day first. See DetectSwellArrivals.
+func (b Buoy) DetectSwellArrivals(options SwellArrivalOptions) []SwellArrival {
+	return DetectSwellArrivals(b.BuoyData, b.Location, options)
+}
@@ -0,0 +1,60 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSwellArrivalDetection(t *testing.T) {
+	// A storm 4000 km to the south west of the buoy generates a swell that arrives a day and a half later
+	distance := 4000.0
+	generated := time.Date(2016, time.January, 10, 0, 0, 0, 0, time.UTC)
+	slope := 9.81 / (4.0 * math.Pi * distance * 1000.0)
+
+	items := []BuoyDataItem{}
+	for hour := 36; hour <= 84; hour++ {
+		date := generated.Add(time.Duration(hour) * time.Hour)
+		peakFrequency := slope * date.Sub(generated).Seconds()
+
+		item := NewBuoyDataItem(Metric)
+		item.Date = date
+		item.WaveSpectra.SeperationFrequency = MissingValue()
+		for frequency := 0.03; frequency < 0.35; frequency += 0.005 {
+			swell := 2.0 * math.Exp(-math.Pow((frequency-peakFrequency)/0.008, 2))
+			windSea := 0.5 * math.Exp(-math.Pow((frequency-0.2)/0.02, 2))
+			direction := 225.0
+			if frequency > 0.14 {
+				direction = 90.0
+			}
+
+			item.WaveSpectra.Frequencies = append(item.WaveSpectra.Frequencies, frequency)
+			item.WaveSpectra.Energies = append(item.WaveSpectra.Energies, swell+windSea)
+			item.WaveSpectra.Angles = append(item.WaveSpectra.Angles, direction)
+			item.WaveSpectra.R1 = append(item.WaveSpectra.R1, 0.9)
+		}
+
+		// Newest first, like the NDBC data
+		items = append([]BuoyDataItem{item}, items...)
+	}
+
+	buoyLocation := NewLocationForLatLong(40.0, -70.0)
+	options := DefaultSwellArrivalOptions()
+	options.PartitionOptions.UseDirectionalSpectrum = false
+	arrivals := DetectSwellArrivals(items, &buoyLocation, options)
+	if len(arrivals) != 1 {
+		fmt.Println("Expected a single swell arrival")
+		t.FailNow()
+	}
+
+	arrival := arrivals[0]
+	if math.Abs(arrival.Distance-distance)/distance > 0.1 || math.Abs(arrival.GenerationTime.Sub(generated).Hours()) > 6 {
+		fmt.Println("Swell arrival was fit to the wrong storm:", arrival.Distance, arrival.GenerationTime)
+		t.FailNow()
+	}
+	if math.Abs(arrival.MeanDirection-225.0) > 1e-6 || arrival.SourceLocation == nil || arrival.SourceLocation.Latitude >= buoyLocation.Latitude || arrival.SourceLocation.Longitude >= buoyLocation.Longitude {
+		fmt.Println("Swell source should be to the south west of the buoy")
+		t.FailNow()
+	}
+}
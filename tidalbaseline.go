@@ -0,0 +1,219 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// A harmonic constituent of the tide, with the amplitude in meters and the phase in radians
+// relative to the reference time of its baseline
+type TidalConstituent struct {
+	Name      string
+	Period    time.Duration
+	Amplitude float64
+	Phase     float64
+}
+
+// The principal lunar and solar constituents, which make up most of the tide almost everywhere. They
+// are ordered by how much of the tide they usually make up, which is the order they are fitted in.
+var principalTidalConstituents = []TidalConstituent{
+	{Name: "M2", Period: time.Duration(12.4206012 * float64(time.Hour))},
+	{Name: "K1", Period: time.Duration(23.9344697 * float64(time.Hour))},
+	{Name: "S2", Period: 12 * time.Hour},
+	{Name: "O1", Period: time.Duration(25.8193417 * float64(time.Hour))},
+}
+
+// Chooses the constituents that can be told apart in a series of the given length. By the Rayleigh
+// criterion two constituents are only separated by a series longer than their synodic period, which is
+// about 26 hours for M2 and K1, 13.7 days for K1 and O1, and 14.8 days for M2 and S2. Fitting both of a
+// pair on a shorter series makes the least squares problem ill conditioned.
+func resolvableTidalConstituents(span time.Duration) []TidalConstituent {
+	constituents := []TidalConstituent{}
+	for _, candidate := range principalTidalConstituents {
+		resolvable := true
+		for _, constituent := range constituents {
+			synodicHours := 1.0 / math.Abs(1.0/candidate.Period.Hours()-1.0/constituent.Period.Hours())
+			if span.Hours() < synodicHours {
+				resolvable = false
+				break
+			}
+		}
+		if resolvable {
+			constituents = append(constituents, candidate)
+		}
+	}
+	return constituents
+}
+
+// A tide fitted to a series of water heights, used to predict the height at other times
+type TidalBaseline struct {
+	Reference    time.Time
+	Mean         float64
+	Constituents []TidalConstituent
+}
+
+// A DART reading that differs from the predicted tide by more than the detection threshold
+type DartAnomaly struct {
+	Reading DartDataItem
+
+	// The height predicted by the tidal baseline, and the observed height minus the prediction
+	PredictedHeight float64
+	Residual        float64
+}
+
+// Fits the tidal constituents and the mean height to the given water heights with least squares. Missing
+// heights are skipped. M2, K1, S2 and O1 are all fitted for series of at least about 15 days. Shorter series
+// cannot tell S2 from M2 or O1 from K1, so only the mean, M2 and K1 are fitted, and O1 is added from 13.7
+// days. An error is returned for series shorter than two days.
+func FitTidalBaseline(dates []time.Time, heights []float64) (*TidalBaseline, error) {
+	if len(dates) != len(heights) {
+		return nil, errors.New("Dates and heights do not match, could not fit the tide")
+	}
+
+	var first, last time.Time
+	sampleCount := 0
+	for index, date := range dates {
+		if IsMissing(heights[index]) {
+			continue
+		}
+		if sampleCount == 0 || date.Before(first) {
+			first = date
+		}
+		if sampleCount == 0 || date.After(last) {
+			last = date
+		}
+		sampleCount++
+	}
+
+	baseline := &TidalBaseline{Reference: first, Constituents: resolvableTidalConstituents(last.Sub(first))}
+	parameterCount := 1 + 2*len(baseline.Constituents)
+	if sampleCount <= parameterCount || last.Sub(first) < 48*time.Hour {
+		return nil, errors.New("At least two days of heights are needed to fit the tide")
+	}
+
+	// Accumulate the normal equations of the least squares problem
+	normalMatrix := make([][]float64, parameterCount)
+	for index, _ := range normalMatrix {
+		normalMatrix[index] = make([]float64, parameterCount+1)
+	}
+
+	for index, date := range dates {
+		if IsMissing(heights[index]) {
+			continue
+		}
+
+		basis := baseline.basis(date)
+		for row := 0; row < parameterCount; row++ {
+			for column := 0; column < parameterCount; column++ {
+				normalMatrix[row][column] += basis[row] * basis[column]
+			}
+			normalMatrix[row][parameterCount] += basis[row] * heights[index]
+		}
+	}
+
+	coefficients, solveErr := solveLinearSystem(normalMatrix)
+	if solveErr != nil {
+		return nil, solveErr
+	}
+
+	baseline.Mean = coefficients[0]
+	for index, _ := range baseline.Constituents {
+		cosine, sine := coefficients[1+2*index], coefficients[2+2*index]
+		baseline.Constituents[index].Amplitude = math.Hypot(cosine, sine)
+		baseline.Constituents[index].Phase = math.Atan2(sine, cosine)
+	}
+	return baseline, nil
+}
+
+// Get the least squares basis functions at the given time: a constant followed by the cosine and
+// sine of each constituent
+func (t TidalBaseline) basis(date time.Time) []float64 {
+	hours := date.Sub(t.Reference).Hours()
+	basis := make([]float64, 1, 1+2*len(t.Constituents))
+	basis[0] = 1.0
+	for _, constituent := range t.Constituents {
+		angle := 2.0 * math.Pi * hours / constituent.Period.Hours()
+		basis = append(basis, math.Cos(angle), math.Sin(angle))
+	}
+	return basis
+}
+
+// Predicts the water height at the given time
+func (t TidalBaseline) Height(date time.Time) float64 {
+	hours := date.Sub(t.Reference).Hours()
+	height := t.Mean
+	for _, constituent := range t.Constituents {
+		angle := 2.0 * math.Pi * hours / constituent.Period.Hours()
+		height += constituent.Amplitude * math.Cos(angle-constituent.Phase)
+	}
+	return height
+}
+
+// Solves a linear system given as an augmented matrix with gaussian elimination and partial pivoting
+func solveLinearSystem(augmented [][]float64) ([]float64, error) {
+	size := len(augmented)
+	for pivot := 0; pivot < size; pivot++ {
+		maxRow := pivot
+		for row := pivot + 1; row < size; row++ {
+			if math.Abs(augmented[row][pivot]) > math.Abs(augmented[maxRow][pivot]) {
+				maxRow = row
+			}
+		}
+		if math.Abs(augmented[maxRow][pivot]) < 1e-12 {
+			return nil, errors.New("Linear system is singular, could not solve")
+		}
+		augmented[pivot], augmented[maxRow] = augmented[maxRow], augmented[pivot]
+
+		for row := pivot + 1; row < size; row++ {
+			factor := augmented[row][pivot] / augmented[pivot][pivot]
+			for column := pivot; column <= size; column++ {
+				augmented[row][column] -= factor * augmented[pivot][column]
+			}
+		}
+	}
+
+	solution := make([]float64, size)
+	for row := size - 1; row >= 0; row-- {
+		sum := augmented[row][size]
+		for column := row + 1; column < size; column++ {
+			sum -= augmented[row][column] * solution[column]
+		}
+		solution[row] = sum / augmented[row][row]
+	}
+	return solution, nil
+}
+
+// Fits a tidal baseline to the standard mode readings of a DART station, then returns the event mode
+// readings whose height differs from the predicted tide by more than the threshold in meters. Standard
+// readings spanning at least two days are needed to fit the tide, and about 15 days to fit all of the
+// principal constituents.
+func DetectDartAnomalies(items []DartDataItem, threshold float64) ([]DartAnomaly, error) {
+	dates := []time.Time{}
+	heights := []float64{}
+	for _, item := range items {
+		if item.MeasurementType == DartStandardMeasurement {
+			dates = append(dates, item.Date)
+			heights = append(heights, item.WaterColumnHeight)
+		}
+	}
+
+	baseline, fitErr := FitTidalBaseline(dates, heights)
+	if fitErr != nil {
+		return nil, fitErr
+	}
+
+	anomalies := []DartAnomaly{}
+	for _, item := range items {
+		if !item.MeasurementType.IsEventMode() || IsMissing(item.WaterColumnHeight) {
+			continue
+		}
+
+		predicted := baseline.Height(item.Date)
+		residual := item.WaterColumnHeight - predicted
+		if math.Abs(residual) > threshold {
+			anomalies = append(anomalies, DartAnomaly{Reading: item, PredictedHeight: predicted, Residual: residual})
+		}
+	}
+	return anomalies, nil
+}
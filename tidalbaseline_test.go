@@ -0,0 +1,118 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDartAnomalyDetection(t *testing.T) {
+	start := time.Date(2017, 10, 10, 0, 0, 0, 0, time.UTC)
+	tide := func(date time.Time) float64 {
+		hours := date.Sub(start).Hours()
+		return 5807.0 + 0.8*math.Cos(2*math.Pi*hours/12.4206012-0.3) + 0.2*math.Cos(2*math.Pi*hours/23.9344697+1.1)
+	}
+
+	// Four days of standard readings, newest first like the NDBC files, then an event with a 30cm wave
+	lines := []string{
+		"#YY  MM DD hh mm ss T   HEIGHT",
+		"#yr  mo dy hr mn  s -        m",
+	}
+	eventStart := start.Add(96 * time.Hour)
+	for minute := 10; minute >= 0; minute-- {
+		date := eventStart.Add(time.Duration(minute) * time.Minute)
+		height := tide(date)
+		if minute == 4 {
+			height += 0.3
+		}
+		lines = append(lines, fmt.Sprintf("%s 2 %.3f", date.Format("2006 01 02 15 04 05"), height))
+	}
+	for date := eventStart.Add(-15 * time.Minute); !date.Before(start); date = date.Add(-15 * time.Minute) {
+		lines = append(lines, fmt.Sprintf("%s 1 %.3f", date.Format("2006 01 02 15 04 05"), tide(date)))
+	}
+	lines = append(lines, fmt.Sprintf("%s 1 9999.000", start.Add(-15*time.Minute).Format("2006 01 02 15 04 05")))
+
+	buoy := Buoy{}
+	parseErr := buoy.ParseRawDartData(strings.Join(lines, "\n"), -1)
+	if parseErr != nil || buoy.DartData[0].MeasurementType != DartEventMeasurement || !IsMissing(buoy.DartData[len(buoy.DartData)-1].WaterColumnHeight) {
+		fmt.Println("Failed to parse the DART data")
+		t.FailNow()
+	}
+
+	anomalies, detectErr := buoy.DetectDartAnomalies(0.1)
+	if detectErr != nil || len(anomalies) != 1 {
+		fmt.Println("Expected exactly one anomaly to be detected")
+		t.FailNow()
+	}
+	if anomalies[0].Reading.Date.Minute() != 4 || math.Abs(anomalies[0].Residual-0.3) > 0.01 {
+		fmt.Println("The anomaly residual was calculated incorrectly")
+		t.FailNow()
+	}
+
+	if _, detectErr = DetectDartAnomalies(buoy.DartData[:20], 0.1); detectErr == nil {
+		fmt.Println("Expected a short series to be rejected")
+		t.FailNow()
+	}
+}
+
+func TestTidalBaselineFit(t *testing.T) {
+	start := time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+	amplitudes := map[string]float64{"M2": 0.8, "K1": 0.2, "S2": 0.3, "O1": 0.15}
+	random := rand.New(rand.NewSource(42))
+
+	series := func(days int, constituents []string) ([]time.Time, []float64) {
+		dates := []time.Time{}
+		heights := []float64{}
+		for date := start; date.Before(start.AddDate(0, 0, days)); date = date.Add(30 * time.Minute) {
+			height := 5807.0 + 0.05*random.NormFloat64()
+			for _, constituent := range principalTidalConstituents {
+				for _, name := range constituents {
+					if constituent.Name == name {
+						height += amplitudes[name] * math.Cos(2*math.Pi*date.Sub(start).Hours()/constituent.Period.Hours()-0.5)
+					}
+				}
+			}
+			dates = append(dates, date)
+			heights = append(heights, height)
+		}
+		return dates, heights
+	}
+
+	checkAmplitudes := func(baseline *TidalBaseline, names []string) {
+		if len(baseline.Constituents) != len(names) {
+			fmt.Println("Expected the constituents", names, "to be fitted, got", baseline.Constituents)
+			t.FailNow()
+		}
+		for index, name := range names {
+			constituent := baseline.Constituents[index]
+			if constituent.Name != name || math.Abs(constituent.Amplitude-amplitudes[name]) > 0.02 || math.Abs(constituent.Phase-0.5) > 0.1 {
+				fmt.Println("Recovered the wrong amplitude or phase for", name, constituent)
+				t.FailNow()
+			}
+		}
+		if math.Abs(baseline.Mean-5807.0) > 0.01 {
+			fmt.Println("Recovered the wrong mean height", baseline.Mean)
+			t.FailNow()
+		}
+	}
+
+	dates, heights := series(30, []string{"M2", "K1", "S2", "O1"})
+	baseline, fitErr := FitTidalBaseline(dates, heights)
+	if fitErr != nil {
+		fmt.Println(fitErr)
+		t.FailNow()
+	}
+	checkAmplitudes(baseline, []string{"M2", "K1", "S2", "O1"})
+
+	// Four days cannot separate S2 from M2 or O1 from K1, so only M2 and K1 are fitted
+	dates, heights = series(4, []string{"M2", "K1"})
+	baseline, fitErr = FitTidalBaseline(dates, heights)
+	if fitErr != nil {
+		fmt.Println(fitErr)
+		t.FailNow()
+	}
+	checkAmplitudes(baseline, []string{"M2", "K1"})
+}
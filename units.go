@@ -31,7 +31,7 @@ func DegreeToDirection(degree float64) string {
 	degree = math.Abs(degree)
 
 	// Make sure its in the range
-	if degree > 361 {
+	if IsMissing(degree) || degree > 361 {
 		return "NULL"
 	}
 
@@ -82,6 +82,25 @@ func DirectionToDegree(direction string) float64 {
 	}
 }
 
+// Normalizes a compass direction in degrees to the range 0 up to 360
+func normalizeDirection(direction float64) float64 {
+	normalized := math.Mod(direction, 360.0)
+	if normalized < 0 {
+		normalized += 360.0
+	}
+	return normalized
+}
+
+// Get the signed difference in degrees to turn from one compass direction to another the short
+// way around, in the range -180 up to 180. Clockwise turns are positive.
+func angleDifference(from, to float64) float64 {
+	difference := normalizeDirection(to - from)
+	if difference >= 180.0 {
+		difference -= 360.0
+	}
+	return difference
+}
+
 // Converts a given input from meters to feet
 func MetersToFeet(meterValue float64) float64 {
 	return meterValue * 3.28
@@ -110,6 +129,16 @@ func MilesPerHourToKnots(mphValue float64) float64 {
 	return mphValue / 1.15
 }
 
+// Converts from kilometers to nautical miles
+func KilometersToNauticalMiles(kilometerValue float64) float64 {
+	return kilometerValue / 1.852
+}
+
+// Converts from nautical miles to kilometers
+func NauticalMilesToKilometers(nauticalMileValue float64) float64 {
+	return nauticalMileValue * 1.852
+}
+
 // Converts from Celsius to Fahrenheit
 func CelsiusToFahrenheit(celsiusValue float64) float64 {
 	return (celsiusValue * (9.0 / 5.0)) + 32.0
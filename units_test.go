@@ -40,3 +40,13 @@ func TestDegreeConversion(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestAngleDifference(t *testing.T) {
+	if angleDifference(350, 10) != 20 || angleDifference(10, 350) != -20 || angleDifference(90, 270) != -180 {
+		t.Fail()
+	}
+
+	if normalizeDirection(-10) != 350 || normalizeDirection(720) != 0 {
+		t.Fail()
+	}
+}
@@ -2,50 +2,45 @@
 package surfnerd
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"io/ioutil"
-	"net/http"
 	"strings"
 )
 
-func fetchSpaceDelimitedString(url string) ([]string, error) {
+func fetchLineDelimitedString(ctx context.Context, fetcher Fetcher, url string) ([]string, error) {
 	// Get the response from the website and find if it can retreive the data
-	response, httpError := http.Get(url)
-	defer response.Body.Close()
-
-	if httpError != nil {
-		return []string{}, httpError
+	rawData, fetchErr := fetcher.Fetch(ctx, url)
+	if fetchErr != nil {
+		return []string{}, fetchErr
 	}
 
-	rawData, readError := ioutil.ReadAll(response.Body)
 	rawString := string(rawData)
-
-	return strings.Fields(rawString), readError
+	return strings.Split(rawString, "\n"), nil
 }
 
-func fetchLineDelimitedString(url string) ([]string, error) {
-	// Get the response from the website and find if it can retreive the data
-	response, httpError := http.Get(url)
-	defer response.Body.Close()
+func fetchRawDataFromURL(ctx context.Context, fetcher Fetcher, url string) ([]byte, error) {
+	// Fetch the data
+	return fetcher.Fetch(ctx, url)
+}
 
-	if httpError != nil {
-		return []string{}, httpError
+func fetchArchiveFromURL(ctx context.Context, fetcher Fetcher, url string) ([]byte, error) {
+	rawData, fetchErr := fetcher.Fetch(ctx, url)
+	if fetchErr != nil {
+		return nil, fetchErr
 	}
 
-	rawData, readError := ioutil.ReadAll(response.Body)
-	rawString := string(rawData)
-
-	return strings.Split(rawString, "\n"), readError
-}
+	// Some servers decompress gzip files on the way, so only inflate data that is still compressed
+	if !bytes.HasPrefix(rawData, []byte{0x1f, 0x8b}) {
+		return rawData, nil
+	}
 
-func fetchRawDataFromURL(url string) ([]byte, error) {
-	// Fetch the data
-	resp, httpErr := http.Get(url)
-	if httpErr != nil {
-		return nil, httpErr
+	reader, gzipErr := gzip.NewReader(bytes.NewReader(rawData))
+	if gzipErr != nil {
+		return nil, gzipErr
 	}
-	defer resp.Body.Close()
+	defer reader.Close()
 
-	// Read all of the raw data
-	contents, readErr := ioutil.ReadAll(resp.Body)
-	return contents, readErr
+	return ioutil.ReadAll(reader)
 }
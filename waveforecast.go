@@ -3,7 +3,6 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
-	"time"
 )
 
 // Container holding a complete WaveWatch forecast with the location, model description, run time, and
@@ -65,6 +64,7 @@ func (w *WaveForecast) ToModelData() *ModelData {
 	dataMap["wvpersfc"] = make([]float64, dataCount)
 	dataMap["windsfc"] = make([]float64, dataCount)
 	dataMap["wdirsfc"] = make([]float64, dataCount)
+	dataMap["time"] = make([]float64, dataCount)
 
 	for forcIndex, forecast := range w.ForecastData {
 		dataMap["htsgwsfc"][forcIndex] = forecast.SignificantWaveHeight
@@ -81,6 +81,7 @@ func (w *WaveForecast) ToModelData() *ModelData {
 		dataMap["wvpersfc"][forcIndex] = forecast.WindSwellPeriod
 		dataMap["windsfc"][forcIndex] = forecast.SurfaceWindSpeed
 		dataMap["wdirsfc"][forcIndex] = forecast.SurfaceWindDirection
+		dataMap["time"][forcIndex] = EncodeModelTime(forecast.Date)
 	}
 
 	modelData := &ModelData{
@@ -101,14 +102,12 @@ func WaveForecastFromModelData(modelData *ModelData) *WaveForecast {
 	itemCount := len(modelData.Data["dirpwsfc"])
 	forecastItems := make([]WaveForecastItem, itemCount)
 
-	modelTime, _ := LatestModelDateTime()
+	forecastTimes := modelData.forecastTimes(itemCount)
 
 	for i := 0; i < itemCount; i++ {
 		thisForecastItem := WaveForecastItem{}
 
-		forecastTime := modelTime.Add(time.Duration(3 * int64(i) * int64(time.Hour)))
-		thisForecastItem.Date = forecastTime.In(modelData.Model.TimezoneLocation()).Format("Monday January 02, 2006")
-		thisForecastItem.Time = forecastTime.In(modelData.Model.TimezoneLocation()).Format("03 PM")
+		thisForecastItem.Date = forecastTimes[i]
 		thisForecastItem.SignificantWaveHeight = modelData.Data["htsgwsfc"][i]
 		thisForecastItem.DominantWaveDirection = modelData.Data["dirpwsfc"][i]
 		thisForecastItem.MeanWavePeriod = modelData.Data["perpwsfc"][i]
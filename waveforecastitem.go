@@ -1,9 +1,12 @@
 package surfnerd
 
+import (
+	"time"
+)
+
 // Data container for WaveWatch data at a specific timestep and location.
 type WaveForecastItem struct {
-	Date                     string
-	Time                     string
+	Date                     time.Time
 	SignificantWaveHeight    float64
 	DominantWaveDirection    float64
 	MeanWavePeriod           float64
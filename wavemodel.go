@@ -1,14 +1,22 @@
 package surfnerd
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 )
 
+// Paths are relative to the NOMADS endpoint of the client, with the model name, run date and run hour
 const (
-	baseMultigridUrl = "http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/%[1]s/%[2]s%[1]s_%[3]s.ascii?time[%[6]d:%[7]d],dirpwsfc.dirpwsfc[%[6]d:%[7]d][%[4]d][%[5]d],htsgwsfc.htsgwsfc[%[6]d:%[7]d][%[4]d][%[5]d],perpwsfc.perpwsfc[%[6]d:%[7]d][%[4]d][%[5]d],swdir_1.swdir_1[%[6]d:%[7]d][%[4]d][%[5]d],swdir_2.swdir_2[%[6]d:%[7]d][%[4]d][%[5]d],swell_1.swell_1[%[6]d:%[7]d][%[4]d][%[5]d],swell_2.swell_2[%[6]d:%[7]d][%[4]d][%[5]d],swper_1.swper_1[%[6]d:%[7]d][%[4]d][%[5]d],swper_2.swper_2[%[6]d:%[7]d][%[4]d][%[5]d],ugrdsfc.ugrdsfc[%[6]d:%[7]d][%[4]d][%[5]d],vgrdsfc.vgrdsfc[%[6]d:%[7]d][%[4]d][%[5]d],wdirsfc.wdirsfc[%[6]d:%[7]d][%[4]d][%[5]d],windsfc.windsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvdirsfc.wvdirsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvhgtsfc.wvhgtsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvpersfc.wvpersfc[%[6]d:%[7]d][%[4]d][%[5]d]"
+	multigridDatasetPath = "/dods/wave/mww3/%[2]s/%[1]s%[2]s_%[3]s"
 )
 
+// The variables wave forecasts are built from, named as in the multigrid datasets
+var multigridVariables = []string{
+	"dirpwsfc", "htsgwsfc", "perpwsfc", "swdir_1", "swdir_2", "swell_1", "swell_2", "swper_1", "swper_2",
+	"ugrdsfc", "vgrdsfc", "wdirsfc", "windsfc", "wvdirsfc", "wvhgtsfc", "wvpersfc",
+}
+
 // A container representing a NOAA WaveWatch III MultiGrid Wave Model. This type has everything needed to construct a url
 // to get the data needed for a correct location.
 type WaveModel struct {
@@ -19,19 +27,35 @@ type WaveModel struct {
 // The time indices can be calculated assuming every index expands to the TimeResolution in terms of
 // Days. So if model.TimeResolution return 0.167, that means each index is equal to 0.167 days.
 func (w *WaveModel) CreateURL(loc Location, startTimeIndex, endTimeIndex int) string {
-	// Get the times
-	timestamp, _ := LatestModelDateTime()
-	w.ModelRun = FormatViewingTime(timestamp)
-	dateString := timestamp.Format("20060102")
-	lastModelTime := timestamp.Hour()
-	hourString := fmt.Sprintf("%02dz", lastModelTime)
+	return w.CreateURLWithEndpoints(DefaultClient.endpoints(), loc, startTimeIndex, endTimeIndex)
+}
 
-	// Get the location
+// Same as CreateURL, but the url is built on the NOMADS server of the given endpoints
+func (w *WaveModel) CreateURLWithEndpoints(endpoints Endpoints, loc Location, startTimeIndex, endTimeIndex int) string {
 	latIndex, lngIndex := w.LocationIndices(loc)
+	return w.createURL(endpoints, indexRange(latIndex, latIndex), indexRange(lngIndex, lngIndex), startTimeIndex, endTimeIndex)
+}
+
+// Same as CreateURLWithEndpoints, but the url fetches the block of grid points around the location that
+// is needed to interpolate onto it with the given grid options. Parse the data with InterpolateRawModelData.
+func (w *WaveModel) CreateStencilURLWithEndpoints(endpoints Endpoints, loc Location, options GridOptions, startTimeIndex, endTimeIndex int) string {
+	window := w.gridWindow(loc, options.SearchRadius)
+	return w.createURL(endpoints, indexRange(window.latitudeStart, window.latitudeEnd), indexRange(window.longitudeStart, window.longitudeEnd), startTimeIndex, endTimeIndex)
+}
+
+// Creates the url for the given latitude and longitude index ranges
+func (w *WaveModel) createURL(endpoints Endpoints, latRange, lngRange string, startTimeIndex, endTimeIndex int) string {
+	// Get the times
+	timestamp := w.modelRun()
 
 	// Format the url and return
-	url := fmt.Sprintf(baseMultigridUrl, dateString, w.Name, hourString, latIndex, lngIndex, startTimeIndex, endTimeIndex)
-	return url
+	url := w.datasetURL(endpoints, multigridDatasetPath, timestamp)
+	return url + w.asciiQuery(multigridVariables, true, startTimeIndex, endTimeIndex, latRange, lngRange)
+}
+
+// Creates the url of the OPeNDAP dataset of the given model run on the NOMADS server of the endpoints
+func (w *WaveModel) CreateDatasetURL(endpoints Endpoints, run time.Time) string {
+	return w.datasetURL(endpoints, multigridDatasetPath, run)
 }
 
 // Create a URL for downloading data from the NOAA GRADS servers
@@ -55,11 +79,13 @@ func NewEastCoastWaveModel() *WaveModel {
 		NOAAModel{
 			Name:               "multi_1.at_10m",
 			Description:        "Multi-grid wave model: US East Coast 10 arc-min grid",
-			BottomLeftLocation: NewLocationForLatLong(0.00, 260.00),
-			TopRightLocation:   NewLocationForLatLong(55.00011, 310.00011),
-			LocationResolution: 0.167,
+			BottomLeftLocation: NewLocationForLatLong(0.0, 260.0),
+			TopRightLocation:   NewLocationForLatLong(55.0, 310.0),
+			LocationResolution: 1.0 / 6.0,
 			TimeResolution:     0.125,
 			Units:              Metric,
+			CycleInterval:      6 * time.Hour,
+			PublicationDelay:   5 * time.Hour,
 			TimeLocation:       "America/New_York",
 		},
 	}
@@ -71,12 +97,16 @@ func NewWestCoastWaveModel() *WaveModel {
 		NOAAModel{
 			Name:               "multi_1.wc_10m",
 			Description:        "Multi-grid wave model: US West Coast 10 arc-min grid",
-			BottomLeftLocation: NewLocationForLatLong(25.00, 210.00),
-			TopRightLocation:   NewLocationForLatLong(50.00005, 250.00008),
-			LocationResolution: 0.167,
+			BottomLeftLocation: NewLocationForLatLong(25.0, 210.0),
+			TopRightLocation:   NewLocationForLatLong(50.0, 250.0),
+			LocationResolution: 1.0 / 6.0,
 			TimeResolution:     0.125,
 			Units:              Metric,
+			CycleInterval:      6 * time.Hour,
+			PublicationDelay:   5 * time.Hour,
 			TimeLocation:       "America/Los_Angeles",
+			// Preferred over the Pacific Islands model where they overlap
+			Priority: 1,
 		},
 	}
 }
@@ -87,83 +117,113 @@ func NewPacificIslandsWaveModel() *WaveModel {
 		NOAAModel{
 			Name:               "multi_1.ep_10m",
 			Description:        "Multi-grid wave model: Pacific Islands (including Hawaii) 10 arc-min grid",
-			BottomLeftLocation: NewLocationForLatLong(-20.00, 130.00),
-			TopRightLocation:   NewLocationForLatLong(30.0001, 215.00017),
-			LocationResolution: 0.167,
+			BottomLeftLocation: NewLocationForLatLong(-20.0, 130.0),
+			TopRightLocation:   NewLocationForLatLong(30.0, 215.0),
+			LocationResolution: 1.0 / 6.0,
 			TimeResolution:     0.125,
 			Units:              Metric,
+			CycleInterval:      6 * time.Hour,
+			PublicationDelay:   5 * time.Hour,
 			TimeLocation:       "Pacific/Honolulu",
 		},
 	}
 }
 
-// Get a slice containing pointers to all the available wave models.
+// Get a slice containing pointers to copies of all the wave models of the DefaultModelRegistry.
 func GetAllAvailableWaveModels() []*WaveModel {
-	eastCoastModel := NewEastCoastWaveModel()
-	westCoastModel := NewWestCoastWaveModel()
-	pacificIslandsModel := NewPacificIslandsWaveModel()
-	return []*WaveModel{
-		eastCoastModel,
-		westCoastModel,
-		pacificIslandsModel,
-	}
+	return DefaultModelRegistry.WaveModels()
 }
 
-// Returns the WaveModel for a given Location
+// Returns the WaveModel of the DefaultModelRegistry with the finest resolution that covers a given Location
 // If no model is matched then it returns nil
 func GetWaveModelForLocation(loc Location) *WaveModel {
-	models := GetAllAvailableWaveModels()
-
-	// Check all of the models to see if they contain the lat and long
-	for _, model := range models {
-		if model.ContainsLocation(loc) {
-			return model
-		}
-	}
-
-	return nil
+	return DefaultModelRegistry.WaveModelForLocation(loc)
 }
 
 // Grabs the latest wave data from NOAA GRADS servers for a given location
 // Data is returned as a Forecast object
 func FetchWaveForecast(loc Location) *WaveForecast {
-	modelData := FetchWaveModelData(loc)
-	forecast := WaveForecastFromModelData(modelData)
+	forecast, _ := FetchWaveForecastContext(context.Background(), loc)
 	return forecast
 }
 
+// Grabs the latest wave data from NOAA GRADS servers for a given location using the DefaultClient.
+// The context controls cancellation of the download.
+func FetchWaveForecastContext(ctx context.Context, loc Location) (*WaveForecast, error) {
+	return DefaultClient.FetchWaveForecast(ctx, loc)
+}
+
 // Grabs the latest WaveWatch data from NOAA GRADS servers for a given Location
 // Data is returned as a WaveModelData object which contains a map of raw values.
 func FetchWaveModelData(loc Location) *ModelData {
-	model := GetWaveModelForLocation(loc)
+	modelData, _ := FetchWaveModelDataContext(context.Background(), loc)
+	return modelData
+}
+
+// Grabs the latest WaveWatch data from NOAA GRADS servers for a given Location using the
+// DefaultClient. The context controls cancellation of the download.
+func FetchWaveModelDataContext(ctx context.Context, loc Location) (*ModelData, error) {
+	return DefaultClient.FetchWaveModelData(ctx, loc)
+}
+
+// Grabs the latest wave data from NOAA GRADS servers for a given location using this client
+// Data is returned as a Forecast object
+func (c *Client) FetchWaveForecast(ctx context.Context, loc Location) (*WaveForecast, error) {
+	modelData, fetchErr := c.FetchWaveModelData(ctx, loc)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return WaveForecastFromModelData(modelData), nil
+}
+
+// Grabs the latest WaveWatch data from NOAA GRADS servers for a given Location using this client
+// Data is returned as a WaveModelData object which contains a map of raw values. The data is
+// interpolated onto the location with the DefaultGridOptions.
+func (c *Client) FetchWaveModelData(ctx context.Context, loc Location) (*ModelData, error) {
+	return c.FetchWaveModelDataWithGridOptions(ctx, loc, DefaultGridOptions())
+}
+
+// Same as FetchWaveModelData, but the data is taken from the grid points around the location
+// following the given grid options
+func (c *Client) FetchWaveModelDataWithGridOptions(ctx context.Context, loc Location, options GridOptions) (*ModelData, error) {
+	model := c.models().WaveModelForLocation(loc)
 	if model == nil {
-		return nil
+		return nil, errors.New("No wave model covers the given location")
+	}
+
+	// Make sure the run is published before downloading it, unless one was pinned. The model is a copy
+	// from the registry, so resolving the run does not pin it for later fetches.
+	if model.ModelRun.IsZero() {
+		if _, resolveErr := c.ResolveModelRun(ctx, model, DefaultModelRunFallbacks); resolveErr != nil {
+			return nil, resolveErr
+		}
 	}
 
 	// Create the url
-	url := model.CreateURL(loc, 0, 60)
+	url := model.CreateStencilURLWithEndpoints(c.endpoints(), loc, options, 0, 60)
 
 	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
+	rawData, err := fetchRawDataFromURL(ctx, c.fetcher(), url)
 	if err != nil {
-		return nil
+		return nil, modelFetchError(err)
+	} else if err = checkModelResponse(rawData, url); err != nil {
+		return nil, err
 	}
 
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+	modelData, interpolateErr := InterpolateRawModelData(loc, model.NOAAModel, rawData, options)
+	if interpolateErr != nil {
+		return nil, interpolateErr
 	}
-	return modelData
+	modelData.Data = model.forecastData(modelData.Data, multigridVariables)
+	return modelData, nil
 }
 
 // Takes in raw data and parses it into a ModelData object. Useful for
 // implementing your own network fetching.
 func WaveModelDataFromRaw(loc Location, model NOAAModel, rawData []byte) *ModelData {
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
+	modelDataContainer := model.forecastData(parseRawModelData(rawData), multigridVariables)
 	modelData := &ModelData{
 		Location: loc,
 		Model:    model,
@@ -3,7 +3,6 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
-	"time"
 )
 
 type WindForecast struct {
@@ -51,11 +50,13 @@ func (w *WindForecast) ToModelData() *ModelData {
 	dataMap["windSpeed"] = make([]float64, dataCount)
 	dataMap["windDirection"] = make([]float64, dataCount)
 	dataMap["windGustSpeed"] = make([]float64, dataCount)
+	dataMap["time"] = make([]float64, dataCount)
 
 	for forcIndex, forecast := range w.ForecastData {
 		dataMap["windSpeed"][forcIndex] = forecast.WindSpeed
 		dataMap["windDirection"][forcIndex] = forecast.WindDirection
 		dataMap["windGustSpeed"][forcIndex] = forecast.WindGustSpeed
+		dataMap["time"][forcIndex] = EncodeModelTime(forecast.Date)
 	}
 
 	modelData := &ModelData{
@@ -76,14 +77,12 @@ func WindForecastFromModelData(modelData *ModelData) *WindForecast {
 	itemCount := len(modelData.Data["ugrd10m"])
 	forecastItems := make([]WindForecastItem, itemCount)
 
-	modelTime, _ := LatestModelDateTime()
+	forecastTimes := modelData.forecastTimes(itemCount)
 
 	for i := 0; i < itemCount; i++ {
 		thisForecastItem := WindForecastItem{}
 
-		forecastTime := modelTime.Add(time.Duration(3 * int64(i) * int64(time.Hour)))
-		thisForecastItem.Date = forecastTime.In(modelData.Model.TimezoneLocation()).Format("Monday January 02, 2006")
-		thisForecastItem.Time = forecastTime.In(modelData.Model.TimezoneLocation()).Format("03 PM")
+		thisForecastItem.Date = forecastTimes[i]
 
 		speed, direction := ScalarFromUV(modelData.Data["ugrd10m"][i], modelData.Data["vgrd10m"][i])
 		thisForecastItem.WindSpeed = speed
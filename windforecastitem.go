@@ -1,9 +1,12 @@
 package surfnerd
 
+import (
+	"time"
+)
+
 // A single timestep in a wind forecast
 type WindForecastItem struct {
-	Date          string
-	Time          string
+	Date          time.Time
 	WindSpeed     float64
 	WindGustSpeed float64
 	WindDirection float64
@@ -1,7 +1,8 @@
 package surfnerd
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 )
 
@@ -12,11 +13,15 @@ const (
 	NAM
 )
 
+// Paths are relative to the NOMADS endpoint of the client, with the model name, run date and run hour
 const (
-	gfsURL = "http://nomads.ncep.noaa.gov:9090/dods/%[1]s/gfs%[2]s/%[1]s_%[3]s.ascii?time[%[7]d:%[8]d],ugrd10m[%[7]d:%[8]d][%[5]d][%[6]d],vgrd10m[%[7]d:%[8]d][%[5]d][%[6]d],gustsfc[%[7]d:%[8]d][%[5]d][%[6]d]"
-	namURL = "http://nomads.ncep.noaa.gov:9090/dods/nam/nam%[2]s/%[1]s_%[3]s.ascii?time[%[7]d:%[8]d],ugrd10m[%[7]d:%[8]d][%[5]d][%[6]d],vgrd10m[%[7]d:%[8]d][%[5]d][%[6]d],gustsfc[%[7]d:%[8]d][%[5]d][%[6]d]"
+	gfsDatasetPath = "/dods/%[1]s/gfs%[2]s/%[1]s_%[3]s"
+	namDatasetPath = "/dods/nam/nam%[2]s/%[1]s_%[3]s"
 )
 
+// The variables wind forecasts are built from, named as in the GFS and NAM datasets
+var windVariables = []string{"ugrd10m", "vgrd10m", "gustsfc"}
+
 // Represents a NOAA Wind Model
 type WindModel struct {
 	NOAAModel
@@ -26,28 +31,43 @@ type WindModel struct {
 
 // Create the URL for fetching the data from the wind model
 func (w *WindModel) CreateURL(loc Location, startTimeIndex, endTimeIndex int) string {
-	// Get the times
-	timestamp, _ := LatestModelDateTime()
-	w.ModelRun = FormatViewingTime(timestamp)
-	dateString := timestamp.Format("20060102")
-	lastModelTime := timestamp.Hour()
-	hourString := fmt.Sprintf("%02dz", lastModelTime)
+	return w.CreateURLWithEndpoints(DefaultClient.endpoints(), loc, startTimeIndex, endTimeIndex)
+}
 
-	// Get the location
+// Same as CreateURL, but the url is built on the NOMADS server of the given endpoints
+func (w *WindModel) CreateURLWithEndpoints(endpoints Endpoints, loc Location, startTimeIndex, endTimeIndex int) string {
 	latIndex, lngIndex := w.LocationIndices(loc)
+	return w.createURL(endpoints, indexRange(latIndex, latIndex), indexRange(lngIndex, lngIndex), startTimeIndex, endTimeIndex)
+}
 
-	// Get the altitude
-	altIndex := w.MinimumAltitudeIndex
+// Same as CreateURLWithEndpoints, but the url fetches the block of grid points around the location that
+// is needed to interpolate onto it with the given grid options. Parse the data with InterpolateRawModelData.
+func (w *WindModel) CreateStencilURLWithEndpoints(endpoints Endpoints, loc Location, options GridOptions, startTimeIndex, endTimeIndex int) string {
+	window := w.gridWindow(loc, options.SearchRadius)
+	return w.createURL(endpoints, indexRange(window.latitudeStart, window.latitudeEnd), indexRange(window.longitudeStart, window.longitudeEnd), startTimeIndex, endTimeIndex)
+}
+
+// Creates the url for the given latitude and longitude index ranges
+func (w *WindModel) createURL(endpoints Endpoints, latRange, lngRange string, startTimeIndex, endTimeIndex int) string {
+	// Get the times
+	timestamp := w.modelRun()
 
 	// Format the url and return
-	var baseURL string
-	if w.ModelType == GFS {
-		baseURL = gfsURL
-	} else if w.ModelType == NAM {
-		baseURL = namURL
+	url := w.datasetURL(endpoints, w.defaultDatasetPath(), timestamp)
+	return url + w.asciiQuery(windVariables, false, startTimeIndex, endTimeIndex, latRange, lngRange)
+}
+
+// Creates the url of the OPeNDAP dataset of the given model run on the NOMADS server of the endpoints
+func (w *WindModel) CreateDatasetURL(endpoints Endpoints, run time.Time) string {
+	return w.datasetURL(endpoints, w.defaultDatasetPath(), run)
+}
+
+// Get the dataset path of the type of the model
+func (w *WindModel) defaultDatasetPath() string {
+	if w.ModelType == NAM {
+		return namDatasetPath
 	}
-	url := fmt.Sprintf(baseURL, w.Name, dateString, hourString, altIndex, latIndex, lngIndex, startTimeIndex, endTimeIndex)
-	return url
+	return gfsDatasetPath
 }
 
 // Create a URL for downloading data from the NOAA GRADS servers
@@ -79,6 +99,8 @@ func NewGFSWindModel() *WindModel {
 			LocationResolution: 0.5,
 			TimeResolution:     0.125,
 			Units:              Metric,
+			CycleInterval:      6 * time.Hour,
+			PublicationDelay:   4 * time.Hour,
 			TimeLocation:       "GMT",
 		},
 		46,
@@ -107,101 +129,127 @@ func NewGFSWindModel() *WindModel {
 // 	}
 // }
 
-// Get a slice containing pointers to all the available wind models.
+// Get a slice containing pointers to copies of all the wind models of the DefaultModelRegistry.
 func GetAllAvailableWindModels() []*WindModel {
-	gfsModel := NewGFSWindModel()
-	//namConusModel := NewNAMCONUSNestWindModel()
-	return []*WindModel{
-		gfsModel,
-	}
+	return DefaultModelRegistry.WindModels()
 }
 
-// Returns the WindModel for a given Location
+// Returns the WindModel of the DefaultModelRegistry with the finest resolution that covers a given Location
 // If no model is matched then it returns nil
 func GetWindModelForLocation(loc Location) *WindModel {
-	models := GetAllAvailableWindModels()
-
-	// Check all of the models to see if they contain the lat and long
-	for _, model := range models {
-		if model.ContainsLocation(loc) {
-			return model
-		}
-	}
-
-	return nil
+	return DefaultModelRegistry.WindModelForLocation(loc)
 }
 
-// Returns the WindModel for a given Location
-// If no model is matched then it returns nil
+// Returns the WindModel of the given type of the DefaultModelRegistry with the finest resolution that
+// covers a given Location. If no model is matched then it returns nil
 func GetWindModelForLocationAndType(loc Location, modelType WindModelType) *WindModel {
-	models := GetAllAvailableWindModels()
-
-	// Check all of the models to see if they contain the lat and long
-	for _, model := range models {
-		if model.ContainsLocation(loc) {
-			if model.ModelType == modelType {
-				return model
-			}
-		}
-	}
-
-	return nil
+	return DefaultModelRegistry.WindModelForLocationAndType(loc, modelType)
 }
 
 // Grabs the latest wind data from NOAA GRADS servers for a given location
 // Data is returned as a Forecast object
 func FetchWindForecast(loc Location) *WindForecast {
-	modelData := FetchWindModelData(loc)
-	forecast := WindForecastFromModelData(modelData)
+	forecast, _ := FetchWindForecastContext(context.Background(), loc)
 	return forecast
 }
 
+// Grabs the latest wind data from NOAA GRADS servers for a given location using the DefaultClient.
+// The context controls cancellation of the download.
+func FetchWindForecastContext(ctx context.Context, loc Location) (*WindForecast, error) {
+	return DefaultClient.FetchWindForecast(ctx, loc)
+}
+
 // Grabs the latest wind data from NOAA GRADS servers for a given location and model
 // Data is returned as a Forecast object
 func FetchWindForecastForModel(loc Location, model *WindModel) *WindForecast {
-	modelData := FetchWindModelDataForModel(loc, model)
-	forecast := WindForecastFromModelData(modelData)
+	forecast, _ := FetchWindForecastForModelContext(context.Background(), loc, model)
 	return forecast
 }
 
+// Grabs the latest wind data from NOAA GRADS servers for a given location and model using the
+// DefaultClient. The context controls cancellation of the download.
+func FetchWindForecastForModelContext(ctx context.Context, loc Location, model *WindModel) (*WindForecast, error) {
+	return DefaultClient.FetchWindForecastForModel(ctx, loc, model)
+}
+
 // Grabs the latest Wave Model data from NOAA GRADS servers for a given Location
 // Data is returned as a WaveModelData object which contains a map of raw values.
 func FetchWindModelData(loc Location) *ModelData {
-	model := GetWindModelForLocation(loc)
-	if model == nil {
-		return nil
-	}
+	modelData, _ := FetchWindModelDataContext(context.Background(), loc)
+	return modelData
+}
 
-	// Create the url
-	var timeStepCount int = 0
-	if model.ModelType == GFS {
-		timeStepCount = 60
-	} else if model.ModelType == NAM {
-		timeStepCount = 20
-	}
-	url := model.CreateURL(loc, 0, timeStepCount)
+// Grabs the latest wind model data from NOAA GRADS servers for a given Location using the
+// DefaultClient. The context controls cancellation of the download.
+func FetchWindModelDataContext(ctx context.Context, loc Location) (*ModelData, error) {
+	return DefaultClient.FetchWindModelData(ctx, loc)
+}
 
-	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
-	if err != nil {
-		return nil
+// Grabs the latest Wave Model data from NOAA GRADS servers for a given Location and Model
+// Data is returned as a WaveModelData object which contains a map of raw values.
+func FetchWindModelDataForModel(loc Location, model *WindModel) *ModelData {
+	modelData, _ := FetchWindModelDataForModelContext(context.Background(), loc, model)
+	return modelData
+}
+
+// Grabs the latest wind model data from NOAA GRADS servers for a given Location and Model using
+// the DefaultClient. The context controls cancellation of the download.
+func FetchWindModelDataForModelContext(ctx context.Context, loc Location, model *WindModel) (*ModelData, error) {
+	return DefaultClient.FetchWindModelDataForModel(ctx, loc, model)
+}
+
+// Grabs the latest wind data from NOAA GRADS servers for a given location using this client
+// Data is returned as a Forecast object
+func (c *Client) FetchWindForecast(ctx context.Context, loc Location) (*WindForecast, error) {
+	modelData, fetchErr := c.FetchWindModelData(ctx, loc)
+	if fetchErr != nil {
+		return nil, fetchErr
 	}
+	return WindForecastFromModelData(modelData), nil
+}
 
-	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+// Grabs the latest wind data from NOAA GRADS servers for a given location and model using this client
+// Data is returned as a Forecast object
+func (c *Client) FetchWindForecastForModel(ctx context.Context, loc Location, model *WindModel) (*WindForecast, error) {
+	modelData, fetchErr := c.FetchWindModelDataForModel(ctx, loc, model)
+	if fetchErr != nil {
+		return nil, fetchErr
 	}
-	return modelData
+	return WindForecastFromModelData(modelData), nil
 }
 
-// Grabs the latest Wave Model data from NOAA GRADS servers for a given Location and Model
+// Grabs the latest wind model data from NOAA GRADS servers for a given Location using this client
 // Data is returned as a WaveModelData object which contains a map of raw values.
-func FetchWindModelDataForModel(loc Location, model *WindModel) *ModelData {
+func (c *Client) FetchWindModelData(ctx context.Context, loc Location) (*ModelData, error) {
+	model := c.models().WindModelForLocation(loc)
 	if model == nil {
-		return nil
+		return nil, errors.New("No wind model covers the given location")
+	}
+	return c.FetchWindModelDataForModel(ctx, loc, model)
+}
+
+// Grabs the latest wind model data from NOAA GRADS servers for a given Location and Model using
+// this client. Data is returned as a WaveModelData object which contains a map of raw values. The
+// data is interpolated onto the location with the DefaultGridOptions.
+func (c *Client) FetchWindModelDataForModel(ctx context.Context, loc Location, model *WindModel) (*ModelData, error) {
+	return c.FetchWindModelDataForModelWithGridOptions(ctx, loc, model, DefaultGridOptions())
+}
+
+// Same as FetchWindModelDataForModel, but the data is taken from the grid points around the location
+// following the given grid options
+func (c *Client) FetchWindModelDataForModelWithGridOptions(ctx context.Context, loc Location, model *WindModel, options GridOptions) (*ModelData, error) {
+	if model == nil {
+		return nil, errors.New("No wind model given to fetch data from")
+	}
+
+	// Make sure the run is published before downloading it, unless one was pinned. The run is resolved
+	// on a copy so the given model keeps following the latest run.
+	if model.ModelRun.IsZero() {
+		resolved := *model
+		model = &resolved
+		if _, resolveErr := c.ResolveModelRun(ctx, model, DefaultModelRunFallbacks); resolveErr != nil {
+			return nil, resolveErr
+		}
 	}
 
 	// Create the url
@@ -211,29 +259,30 @@ func FetchWindModelDataForModel(loc Location, model *WindModel) *ModelData {
 	} else if model.ModelType == NAM {
 		timeStepCount = 20
 	}
-	url := model.CreateURL(loc, 0, timeStepCount)
+	url := model.CreateStencilURLWithEndpoints(c.endpoints(), loc, options, 0, timeStepCount)
 
 	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
+	rawData, err := fetchRawDataFromURL(ctx, c.fetcher(), url)
 	if err != nil {
-		return nil
+		return nil, modelFetchError(err)
+	} else if err = checkModelResponse(rawData, url); err != nil {
+		return nil, err
 	}
 
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+	modelData, interpolateErr := InterpolateRawModelData(loc, model.NOAAModel, rawData, options)
+	if interpolateErr != nil {
+		return nil, interpolateErr
 	}
-	return modelData
+	modelData.Data = model.forecastData(modelData.Data, windVariables)
+	return modelData, nil
 }
 
 // Takes in raw data and parses it into a ModelData object. Useful for
 // implementing your own network fetching.
 func WindModelDataFromRaw(loc Location, model NOAAModel, rawData []byte) *ModelData {
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
+	modelDataContainer := model.forecastData(parseRawModelData(rawData), windVariables)
 	modelData := &ModelData{
 		Location: loc,
 		Model:    model,
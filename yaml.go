@@ -0,0 +1,333 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A line of a yaml document with its comment stripped
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// Parses the block yaml that configuration files are written in into v, going through json so the
+// json tags of v are used for the keys. Mappings, sequences, flow collections such as
+// {latitude: 15.0, longitude: 260.0} and plain or quoted scalars are supported. Anchors, tags,
+// multiline strings and multiple documents are not.
+func unmarshalYAML(data []byte, v interface{}) error {
+	lines, lineErr := readYAMLLines(string(data))
+	if lineErr != nil {
+		return lineErr
+	}
+
+	parser := &yamlParser{lines: lines}
+	var document interface{}
+	if len(lines) > 0 {
+		var parseErr error
+		document, parseErr = parser.parseBlock(lines[0].indent)
+		if parseErr != nil {
+			return parseErr
+		}
+		if parser.position < len(lines) {
+			return fmt.Errorf("Invalid yaml indentation on line %d", lines[parser.position].number)
+		}
+	}
+
+	jsonData, jsonErr := json.Marshal(document)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// Splits the document into lines, skipping blank lines, comments and document markers
+func readYAMLLines(document string) ([]yamlLine, error) {
+	lines := []yamlLine{}
+	for index, rawLine := range strings.Split(document, "\n") {
+		rawLine = strings.TrimRight(stripYAMLComment(rawLine), " \t\r")
+		text := strings.TrimLeft(rawLine, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("Invalid yaml indentation with tabs on line %d", index+1)
+		}
+		lines = append(lines, yamlLine{number: index + 1, indent: len(rawLine) - len(text), text: text})
+	}
+	return lines, nil
+}
+
+// Removes a comment, which starts with a # at the start of the line or after a space, outside of quotes
+func stripYAMLComment(line string) string {
+	var quote rune
+	for index, character := range line {
+		switch {
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+		case character == '"' || character == '\'':
+			quote = character
+		case character == '#' && (index == 0 || line[index-1] == ' ' || line[index-1] == '\t'):
+			return line[:index]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines    []yamlLine
+	position int
+}
+
+// Parses the mapping or sequence starting at the current line, which is indented by indent
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLSequenceItem(p.lines[p.position].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	mapping := map[string]interface{}{}
+	for p.position < len(p.lines) {
+		line := p.lines[p.position]
+		if line.indent < indent {
+			break
+		} else if line.indent > indent || isYAMLSequenceItem(line.text) {
+			return nil, fmt.Errorf("Invalid yaml indentation on line %d", line.number)
+		}
+
+		key, rawValue, isPair := splitYAMLPair(line.text)
+		if !isPair {
+			return nil, fmt.Errorf("Expected a yaml key on line %d", line.number)
+		}
+		p.position++
+
+		var value interface{}
+		var valueErr error
+		if rawValue != "" {
+			value, valueErr = parseYAMLValue(rawValue, line.number)
+		} else if p.position < len(p.lines) && p.lines[p.position].indent > indent {
+			value, valueErr = p.parseBlock(p.lines[p.position].indent)
+		} else if p.position < len(p.lines) && p.lines[p.position].indent == indent && isYAMLSequenceItem(p.lines[p.position].text) {
+			// Sequences may be written at the indent of their key
+			value, valueErr = p.parseSequence(indent)
+		}
+		if valueErr != nil {
+			return nil, valueErr
+		}
+		mapping[key] = value
+	}
+	return mapping, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	sequence := []interface{}{}
+	for p.position < len(p.lines) {
+		line := p.lines[p.position]
+		if line.indent != indent || !isYAMLSequenceItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("Invalid yaml indentation on line %d", line.number)
+			}
+			break
+		}
+
+		itemText := strings.TrimLeft(line.text[1:], " ")
+		var item interface{}
+		var itemErr error
+		if itemText == "" {
+			p.position++
+			if p.position < len(p.lines) && p.lines[p.position].indent > indent {
+				item, itemErr = p.parseBlock(p.lines[p.position].indent)
+			}
+		} else if _, _, isPair := splitYAMLPair(itemText); isPair || isYAMLSequenceItem(itemText) {
+			// The item is a collection starting on the line of the dash, so it is parsed as if the
+			// dash were indentation
+			p.lines[p.position] = yamlLine{number: line.number, indent: line.indent + len(line.text) - len(itemText), text: itemText}
+			item, itemErr = p.parseBlock(p.lines[p.position].indent)
+		} else {
+			p.position++
+			item, itemErr = parseYAMLValue(itemText, line.number)
+		}
+		if itemErr != nil {
+			return nil, itemErr
+		}
+		sequence = append(sequence, item)
+	}
+	return sequence, nil
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Splits a key: value line at the first colon followed by a space or the end of the line that is
+// outside of quotes and flow collections
+func splitYAMLPair(text string) (string, string, bool) {
+	var quote rune
+	depth := 0
+	for index, character := range text {
+		switch {
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+		case character == '"' || character == '\'':
+			quote = character
+		case character == '{' || character == '[':
+			depth++
+		case character == '}' || character == ']':
+			depth--
+		case character == ':' && depth == 0 && (index == len(text)-1 || text[index+1] == ' '):
+			key := strings.TrimSpace(text[:index])
+			if unquoted, isQuoted := unquoteYAML(key); isQuoted {
+				key = unquoted
+			}
+			return key, strings.TrimSpace(text[index+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+// Parses a scalar or a flow collection
+func parseYAMLValue(text string, lineNumber int) (interface{}, error) {
+	value, rest, parseErr := parseYAMLFlowValue(text, false)
+	if parseErr != nil || strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("Invalid yaml value on line %d", lineNumber)
+	}
+	return value, nil
+}
+
+// Parses the value at the start of the text and returns the text after it. Plain scalars inside of
+// flow collections end at a comma or the end of the collection.
+func parseYAMLFlowValue(text string, inFlow bool) (interface{}, string, error) {
+	text = strings.TrimLeft(text, " ")
+	switch {
+	case strings.HasPrefix(text, "["):
+		return parseYAMLFlowCollection(text[1:], ']', false)
+	case strings.HasPrefix(text, "{"):
+		return parseYAMLFlowCollection(text[1:], '}', true)
+	case strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'"):
+		end := closingYAMLQuote(text)
+		if end < 0 {
+			return nil, "", fmt.Errorf("Unterminated yaml string")
+		}
+		value, _ := unquoteYAML(text[:end+1])
+		return value, text[end+1:], nil
+	}
+
+	end := len(text)
+	if inFlow {
+		if stop := strings.IndexAny(text, ",]}"); stop >= 0 {
+			end = stop
+		}
+	}
+	return parseYAMLScalar(strings.TrimSpace(text[:end])), text[end:], nil
+}
+
+// Parses the entries of a flow sequence or mapping up to the closing character
+func parseYAMLFlowCollection(text string, closing byte, isMapping bool) (interface{}, string, error) {
+	sequence := []interface{}{}
+	mapping := map[string]interface{}{}
+	for {
+		text = strings.TrimLeft(text, " ")
+		if text == "" {
+			return nil, "", fmt.Errorf("Unterminated yaml collection")
+		}
+		if text[0] == closing {
+			text = text[1:]
+			break
+		}
+
+		if isMapping {
+			separator := strings.Index(text, ":")
+			if separator < 0 {
+				return nil, "", fmt.Errorf("Expected a yaml key")
+			}
+			key := strings.TrimSpace(text[:separator])
+			if unquoted, isQuoted := unquoteYAML(key); isQuoted {
+				key = unquoted
+			}
+			value, rest, valueErr := parseYAMLFlowValue(text[separator+1:], true)
+			if valueErr != nil {
+				return nil, "", valueErr
+			}
+			mapping[key] = value
+			text = rest
+		} else {
+			value, rest, valueErr := parseYAMLFlowValue(text, true)
+			if valueErr != nil {
+				return nil, "", valueErr
+			}
+			sequence = append(sequence, value)
+			text = rest
+		}
+
+		text = strings.TrimLeft(text, " ")
+		if strings.HasPrefix(text, ",") {
+			text = text[1:]
+		} else if text == "" || text[0] != closing {
+			return nil, "", fmt.Errorf("Expected a comma between yaml entries")
+		}
+	}
+
+	if isMapping {
+		return mapping, text, nil
+	}
+	return sequence, text, nil
+}
+
+// Finds the index of the quote closing the string at the start of the text, or -1
+func closingYAMLQuote(text string) int {
+	quote := text[0]
+	for index := 1; index < len(text); index++ {
+		switch {
+		case quote == '"' && text[index] == '\\':
+			index++
+		case quote == '\'' && text[index] == '\'' && index+1 < len(text) && text[index+1] == '\'':
+			index++
+		case text[index] == quote:
+			return index
+		}
+	}
+	return -1
+}
+
+// Removes the quotes of a quoted string. Returns false when the text is not quoted.
+func unquoteYAML(text string) (string, bool) {
+	if len(text) < 2 || text[0] != text[len(text)-1] {
+		return text, false
+	}
+	switch text[0] {
+	case '"':
+		if unquoted, unquoteErr := strconv.Unquote(text); unquoteErr == nil {
+			return unquoted, true
+		}
+		return text[1 : len(text)-1], true
+	case '\'':
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), true
+	}
+	return text, false
+}
+
+// Converts a plain scalar to null, a boolean, a number or a string
+func parseYAMLScalar(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if number, parseErr := strconv.ParseFloat(text, 64); parseErr == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+		return number
+	}
+	return text
+}